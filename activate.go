@@ -0,0 +1,46 @@
+package stuber
+
+// group identifies the stubs sharing a hit counter.
+type group struct {
+	service string
+	method  string
+}
+
+// hit counts a match of the stub in the hit counter of its service and method.
+//
+// Internal requests are not counted.
+//
+// Parameters:
+// - query: The Query used to find the Stub value.
+// - stub: The matched Stub value.
+func (s *searcher) hit(query Query, stub *Stub) {
+	if query.RequestInternal() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hits[group{service: stub.Service, method: stub.Method}]++
+}
+
+// activated checks if the stub may take part in the search.
+//
+// A stub with a positive ActivateAfter is skipped until the stubs of its
+// service and method have been matched at least that many times.
+//
+// Parameters:
+// - stub: The Stub value to check.
+//
+// Returns:
+// - bool: Whether the stub is active.
+func (s *searcher) activated(stub *Stub) bool {
+	if stub.ActivateAfter <= 0 {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.hits[group{service: stub.Service, method: stub.Method}] >= stub.ActivateAfter
+}
@@ -0,0 +1,58 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ActivateAfter(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	input := stuber.InputData{Equals: map[string]interface{}{"name": "bob"}}
+	stubs := []*stuber.Stub{
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  "SayHello",
+			Input:   input,
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello bob"}},
+		},
+		&stuber.Stub{
+			ID:            uuid.New(),
+			Service:       "Greeter",
+			Method:        "SayHello",
+			Input:         input,
+			Output:        stuber.Output{Error: "throttled"},
+			ActivateAfter: 3,
+		},
+	}
+
+	s.PutMany(stubs...)
+
+	query := stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "bob"}}
+
+	for range 3 {
+		r, err := s.FindByQuery(query)
+		require.NoError(t, err)
+		require.NotNil(t, r.Found())
+		require.Zero(t, r.Found().ActivateAfter)
+	}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+	require.Equal(t, 3, r.Found().ActivateAfter)
+
+	s.Clear()
+	s.PutMany(stubs...)
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+	require.Zero(t, r.Found().ActivateAfter)
+}
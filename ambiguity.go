@@ -0,0 +1,103 @@
+package stuber
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// AmbiguityPair is a pair of stubs of the same service and method that tie
+// for a query, so which one is found depends on their order.
+type AmbiguityPair struct {
+	First  uuid.UUID `json:"first"`  // The ID of the stub sorted first.
+	Second uuid.UUID `json:"second"` // The ID of the stub sorted second.
+	Query  Query     `json:"query"`  // A query both stubs match with the same rank.
+}
+
+// ambiguityReport returns the pairs of stubs that tie for some query, e.g. to
+// keep a stub set unambiguous in CI.
+//
+// Two enabled stubs of the same service and method with the same activation
+// threshold are compared on the smallest query of each of them and on the
+// union of both. The pair is reported with the first of these queries that
+// both stubs match with the same rank. For stubs with only exact and partial
+// inputs this finds every tie. For other matchers it is best-effort: a pair
+// is only reported with a query proving the tie, but ties needing other
+// queries are missed. Method aliases are not compared.
+//
+// Returns:
+// - []AmbiguityPair: The tying pairs, sorted by service, method and IDs.
+func (s *searcher) ambiguityReport() []AmbiguityPair {
+	stubs := slices.DeleteFunc(s.all(), func(stub *Stub) bool {
+		return stub.Disabled
+	})
+	slices.SortFunc(stubs, compareStubs)
+
+	results := make([]AmbiguityPair, 0)
+
+	for i, first := range stubs {
+		for _, second := range stubs[i+1:] {
+			if second.Service != first.Service || second.Method != first.Method {
+				break
+			}
+
+			if first.ActivateAfter != second.ActivateAfter {
+				continue
+			}
+
+			if query, ok := s.tie(first, second); ok {
+				results = append(results, AmbiguityPair{First: first.ID, Second: second.ID, Query: query})
+			}
+		}
+	}
+
+	return results
+}
+
+// tie looks for a query both stubs match with the same rank.
+func (s *searcher) tie(first, second *Stub) (Query, bool) {
+	a, b := smallestQuery(first), smallestQuery(second)
+
+	union := Query{
+		Service: first.Service,
+		Method:  first.Method,
+		Headers: unionData(a.Headers, b.Headers),
+		Data:    unionData(a.Data, b.Data),
+	}
+
+	for _, query := range []Query{a, b, union} {
+		if query.Data == nil {
+			query.Data = map[string]any{}
+		}
+
+		if query.Headers == nil {
+			query.Headers = map[string]any{}
+		}
+
+		normalized := s.normalization.query(query)
+		mode := s.serviceDefault(query.Service)
+
+		matchedFirst, rankFirst := s.runMatch(normalized, withMatchMode(s.normalization.stub(first), mode))
+		if !matchedFirst {
+			continue
+		}
+
+		matchedSecond, rankSecond := s.runMatch(normalized, withMatchMode(s.normalization.stub(second), mode))
+		if matchedSecond && rankFirst == rankSecond {
+			return query, true
+		}
+	}
+
+	return Query{}, false
+}
+
+// unionData returns the fields of both maps, the ones of a win on conflicts.
+func unionData(a, b map[string]any) map[string]any {
+	union := make(map[string]any, len(a)+len(b))
+
+	maps.Copy(union, b)
+	maps.Copy(union, a)
+
+	return union
+}
@@ -0,0 +1,48 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_AmbiguityReport(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(method string, input stuber.InputData) *stuber.Stub {
+		return &stuber.Stub{ID: uuid.New(), Service: "Users", Method: method, Input: input, Output: stuber.Output{Error: "boom"}}
+	}
+
+	// Clearly ambiguous: the same partial input.
+	bob := newStub("Get", stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}})
+	bobAgain := newStub("Get", stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}})
+
+	// Clearly disjoint: different exact inputs.
+	alice := newStub("Find", stuber.InputData{Equals: map[string]interface{}{"name": "Alice"}})
+	carol := newStub("Find", stuber.InputData{Equals: map[string]interface{}{"name": "Carol"}})
+
+	// Identical, but only one of them is active at a time.
+	first := newStub("Delete", stuber.InputData{Contains: map[string]interface{}{"id": "1"}})
+	later := newStub("Delete", stuber.InputData{Contains: map[string]interface{}{"id": "1"}})
+	later.ActivateAfter = 1
+
+	s.PutMany(bob, bobAgain, alice, carol, first, later)
+
+	report := s.AmbiguityReport()
+	require.Len(t, report, 1)
+	require.ElementsMatch(t, []uuid.UUID{bob.ID, bobAgain.ID}, []uuid.UUID{report[0].First, report[0].Second})
+
+	// The reported query is indeed a tie.
+	eval, err := s.Evaluate(report[0].Query)
+	require.NoError(t, err)
+	require.True(t, eval.Exact)
+	require.InDelta(t, eval.Score, eval.SimilarScore, 0)
+
+	// Disabled stubs do not take part in searches.
+	s.SetEnabledWhere(func(stub *stuber.Stub) bool { return stub.ID == bobAgain.ID }, false)
+	require.Empty(t, s.AmbiguityReport())
+}
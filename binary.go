@@ -0,0 +1,827 @@
+package stuber
+
+import (
+	"bytes"
+	"cmp"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnsupportedVersion is returned when a binary payload has a layout
+// version this package cannot read.
+var ErrUnsupportedVersion = errors.New("unsupported version")
+
+// binaryVersion is the version of the binary payload layout.
+const binaryVersion = 2
+
+// The tags of the dynamic values, e.g. the values of Output.Data.
+const (
+	dynamicNil byte = iota
+	dynamicFalse
+	dynamicTrue
+	dynamicString
+	dynamicNumber
+	dynamicFloat
+	dynamicInt
+	dynamicInt64
+	dynamicMap
+	dynamicNilMap
+	dynamicSlice
+	dynamicNilSlice
+)
+
+// binaryCodec writes and reads the values of one type.
+type binaryCodec struct {
+	encode func(w *binaryWriter, v reflect.Value) error
+	decode func(r *binaryReader, v reflect.Value) error
+}
+
+// stubCodec is the codec of the Stub type, built once.
+var stubCodec = sync.OnceValues(func() (*binaryCodec, error) { //nolint:gochecknoglobals
+	return newBinaryCodec(reflect.TypeFor[Stub](), make(map[reflect.Type]*binaryCodec))
+})
+
+// stubLayout fingerprints the fields of the Stub type, so a payload written
+// by a version of the package with other fields is refused, not misread.
+var stubLayout = sync.OnceValue(func() uint64 { //nolint:gochecknoglobals
+	h := fnv.New64a()
+
+	describeLayout(h, reflect.TypeFor[Stub](), make(map[reflect.Type]bool))
+
+	return h.Sum64()
+})
+
+// describeLayout writes the names and types of the serialized fields of the
+// type and the types it holds.
+func describeLayout(w io.Writer, typ reflect.Type, seen map[reflect.Type]bool) {
+	fmt.Fprintf(w, "%s;", typ)
+
+	if seen[typ] || isBinaryMarshaler(typ) {
+		return
+	}
+
+	seen[typ] = true
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Struct:
+		for _, field := range binaryFields(typ) {
+			fmt.Fprintf(w, "%s:", field.Name)
+			describeLayout(w, field.Type, seen)
+		}
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		describeLayout(w, typ.Elem(), seen)
+	case reflect.Map:
+		describeLayout(w, typ.Key(), seen)
+		describeLayout(w, typ.Elem(), seen)
+	}
+}
+
+// binaryFields returns the serialized fields of the struct type: the
+// exported ones not excluded from JSON, like custom matchers.
+func binaryFields(typ reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, typ.NumField())
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.IsExported() && field.Tag.Get("json") != "-" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// isBinaryMarshaler checks if the type serializes itself, e.g. time.Time.
+func isBinaryMarshaler(typ reflect.Type) bool {
+	return typ.Implements(reflect.TypeFor[encoding.BinaryMarshaler]()) &&
+		reflect.PointerTo(typ).Implements(reflect.TypeFor[encoding.BinaryUnmarshaler]())
+}
+
+// newBinaryCodec builds the codec of the type.
+//
+// Maps and slices are written with their length plus one, so nil ones are
+// told from empty ones. Dynamic values are tagged with their type.
+//
+// Parameters:
+// - typ: The type to build the codec of.
+// - codecs: The codecs already built, to reuse them and end recursive types.
+//
+// Returns:
+// - *binaryCodec: The codec of the type.
+// - error: An error if the type holds values that cannot be serialized.
+//
+//nolint:cyclop,funlen
+func newBinaryCodec(typ reflect.Type, codecs map[reflect.Type]*binaryCodec) (*binaryCodec, error) {
+	if c, ok := codecs[typ]; ok {
+		return c, nil
+	}
+
+	c := &binaryCodec{}
+	codecs[typ] = c
+
+	switch {
+	case isBinaryMarshaler(typ):
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary() //nolint:forcetypeassert
+			if err != nil {
+				return err
+			}
+
+			w.bytes(data)
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			data, err := r.bytes()
+			if err != nil {
+				return err
+			}
+
+			return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data) //nolint:forcetypeassert
+		}
+
+		return c, nil
+	case typ == reflect.TypeFor[any](), typ == reflect.TypeFor[map[string]any](), typ == reflect.TypeFor[[]any]():
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			return w.dynamic(v.Interface())
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			value, err := r.dynamic()
+			if err != nil || value == nil {
+				return err
+			}
+
+			if reflect.TypeOf(value) != typ && typ.Kind() != reflect.Interface {
+				return fmt.Errorf("binary payload: %T is not %s", value, typ)
+			}
+
+			v.Set(reflect.ValueOf(value))
+
+			return nil
+		}
+
+		return c, nil
+	case typ == reflect.TypeFor[uuid.UUID]():
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			id := v.Interface().(uuid.UUID) //nolint:forcetypeassert
+			w.buf = append(w.buf, id[:]...)
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			data, err := r.take(len(uuid.UUID{}))
+			if err != nil {
+				return err
+			}
+
+			v.Set(reflect.ValueOf(uuid.UUID(data)))
+
+			return nil
+		}
+
+		return c, nil
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.bool(v.Bool())
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			b, err := r.bool()
+			v.SetBool(b)
+
+			return err
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.varint(v.Int())
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			n, err := r.varint()
+			v.SetInt(n)
+
+			return err
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.uvarint(v.Uint())
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			n, err := r.uvarint()
+			v.SetUint(n)
+
+			return err
+		}
+	case reflect.Float32, reflect.Float64:
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.float(v.Float())
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			f, err := r.float()
+			v.SetFloat(f)
+
+			return err
+		}
+	case reflect.String:
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.string(v.String())
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			s, err := r.string()
+			v.SetString(s)
+
+			return err
+		}
+	case reflect.Pointer:
+		elem, err := newBinaryCodec(typ.Elem(), codecs)
+		if err != nil {
+			return nil, err
+		}
+
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.bool(!v.IsNil())
+
+			if v.IsNil() {
+				return nil
+			}
+
+			return elem.encode(w, v.Elem())
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			set, err := r.bool()
+			if err != nil || !set {
+				return err
+			}
+
+			p := reflect.New(typ.Elem())
+			v.Set(p)
+
+			return elem.decode(r, p.Elem())
+		}
+	case reflect.Slice:
+		elem, err := newBinaryCodec(typ.Elem(), codecs)
+		if err != nil {
+			return nil, err
+		}
+
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.length(v.Len(), v.IsNil())
+
+			for i := range v.Len() {
+				if err := elem.encode(w, v.Index(i)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			n, isNil, err := r.length()
+			if err != nil || isNil {
+				return err
+			}
+
+			v.Set(reflect.MakeSlice(typ, n, n))
+
+			for i := range n {
+				if err := elem.decode(r, v.Index(i)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("binary payload: unsupported map key of %s", typ)
+		}
+
+		elem, err := newBinaryCodec(typ.Elem(), codecs)
+		if err != nil {
+			return nil, err
+		}
+
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			w.length(v.Len(), v.IsNil())
+
+			// Sort the keys to make the payload stable.
+			keys := v.MapKeys()
+			slices.SortFunc(keys, func(a, b reflect.Value) int { return cmp.Compare(a.String(), b.String()) })
+
+			for _, key := range keys {
+				w.string(key.String())
+
+				if err := elem.encode(w, v.MapIndex(key)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			n, isNil, err := r.length()
+			if err != nil || isNil {
+				return err
+			}
+
+			m := reflect.MakeMapWithSize(typ, n)
+			item := reflect.New(typ.Elem()).Elem()
+
+			for range n {
+				key, err := r.string()
+				if err != nil {
+					return err
+				}
+
+				item.SetZero()
+
+				if err := elem.decode(r, item); err != nil {
+					return err
+				}
+
+				m.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), item)
+			}
+
+			v.Set(m)
+
+			return nil
+		}
+	case reflect.Struct:
+		fields := binaryFields(typ)
+		fieldCodecs := make([]*binaryCodec, len(fields))
+
+		for i, field := range fields {
+			fc, err := newBinaryCodec(field.Type, codecs)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", typ, field.Name, err)
+			}
+
+			fieldCodecs[i] = fc
+		}
+
+		c.encode = func(w *binaryWriter, v reflect.Value) error {
+			for i, field := range fields {
+				if err := fieldCodecs[i].encode(w, v.Field(field.Index[0])); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+		c.decode = func(r *binaryReader, v reflect.Value) error {
+			for i, field := range fields {
+				if err := fieldCodecs[i].decode(r, v.Field(field.Index[0])); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	default:
+		return nil, fmt.Errorf("binary payload: unsupported type %s", typ)
+	}
+
+	return c, nil
+}
+
+// binaryWriter appends the values of a binary payload to a buffer.
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) bool(b bool) {
+	if b {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *binaryWriter) varint(n int64) {
+	w.buf = binary.AppendVarint(w.buf, n)
+}
+
+func (w *binaryWriter) uvarint(n uint64) {
+	w.buf = binary.AppendUvarint(w.buf, n)
+}
+
+func (w *binaryWriter) float(f float64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(f))
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *binaryWriter) bytes(data []byte) {
+	w.uvarint(uint64(len(data)))
+	w.buf = append(w.buf, data...)
+}
+
+// length writes the length of a map or slice, zero if it is nil.
+func (w *binaryWriter) length(n int, isNil bool) {
+	if isNil {
+		w.uvarint(0)
+	} else {
+		w.uvarint(uint64(n) + 1)
+	}
+}
+
+// dynamic writes a dynamic value with its type tag.
+//
+// Values of other types than the ones JSON decodes into, e.g. a []string
+// set through the Go API, are normalized through JSON first, so they are
+// read back the way Import would read them.
+func (w *binaryWriter) dynamic(value any) error {
+	switch v := value.(type) {
+	case nil:
+		w.buf = append(w.buf, dynamicNil)
+	case bool:
+		if v {
+			w.buf = append(w.buf, dynamicTrue)
+		} else {
+			w.buf = append(w.buf, dynamicFalse)
+		}
+	case string:
+		w.buf = append(w.buf, dynamicString)
+		w.string(v)
+	case json.Number:
+		w.buf = append(w.buf, dynamicNumber)
+		w.string(string(v))
+	case float64:
+		w.buf = append(w.buf, dynamicFloat)
+		w.float(v)
+	case int:
+		w.buf = append(w.buf, dynamicInt)
+		w.varint(int64(v))
+	case int64:
+		w.buf = append(w.buf, dynamicInt64)
+		w.varint(v)
+	case map[string]any:
+		if v == nil {
+			w.buf = append(w.buf, dynamicNilMap)
+
+			return nil
+		}
+
+		w.buf = append(w.buf, dynamicMap)
+		w.uvarint(uint64(len(v)))
+
+		// Sort the keys to make the payload stable.
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			w.string(key)
+
+			if err := w.dynamic(v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if v == nil {
+			w.buf = append(w.buf, dynamicNilSlice)
+
+			return nil
+		}
+
+		w.buf = append(w.buf, dynamicSlice)
+		w.uvarint(uint64(len(v)))
+
+		for _, item := range v {
+			if err := w.dynamic(item); err != nil {
+				return err
+			}
+		}
+	default:
+		normalized, err := normalizeJSON(v)
+		if err != nil {
+			return fmt.Errorf("binary payload: %w", err)
+		}
+
+		return w.dynamic(normalized)
+	}
+
+	return nil
+}
+
+// normalizeJSON converts the value into the types JSON decodes into, with
+// numbers as json.Number.
+func normalizeJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var normalized any
+
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// binaryReader reads the values of a binary payload.
+type binaryReader struct {
+	data []byte
+	pos  int
+}
+
+// take returns the next n bytes of the payload.
+func (r *binaryReader) take(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, fmt.Errorf("binary payload: %w", io.ErrUnexpectedEOF)
+	}
+
+	data := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return data, nil
+}
+
+func (r *binaryReader) byte() (byte, error) {
+	data, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return data[0], nil
+}
+
+func (r *binaryReader) bool() (bool, error) {
+	b, err := r.byte()
+
+	return b != 0, err
+}
+
+func (r *binaryReader) varint() (int64, error) {
+	n, size := binary.Varint(r.data[r.pos:])
+	if size <= 0 {
+		return 0, fmt.Errorf("binary payload: %w", io.ErrUnexpectedEOF)
+	}
+
+	r.pos += size
+
+	return n, nil
+}
+
+func (r *binaryReader) uvarint() (uint64, error) {
+	n, size := binary.Uvarint(r.data[r.pos:])
+	if size <= 0 {
+		return 0, fmt.Errorf("binary payload: %w", io.ErrUnexpectedEOF)
+	}
+
+	r.pos += size
+
+	return n, nil
+}
+
+func (r *binaryReader) float() (float64, error) {
+	data, err := r.take(8) //nolint:mnd
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+}
+
+// count reads the number of the following items, each taking at least a
+// byte, so a corrupt count does not allocate more than the payload holds.
+func (r *binaryReader) count() (int, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if n > uint64(len(r.data)-r.pos) {
+		return 0, fmt.Errorf("binary payload: %w", io.ErrUnexpectedEOF)
+	}
+
+	return int(n), nil
+}
+
+func (r *binaryReader) string() (string, error) {
+	data, err := r.bytes()
+
+	return string(data), err
+}
+
+func (r *binaryReader) bytes() ([]byte, error) {
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.take(n)
+}
+
+// length reads the length of a map or slice, and whether it is nil.
+func (r *binaryReader) length() (int, bool, error) {
+	n, err := r.count()
+	if err != nil || n == 0 {
+		return 0, true, err
+	}
+
+	return n - 1, false, nil
+}
+
+// dynamic reads a dynamic value written with its type tag.
+func (r *binaryReader) dynamic() (any, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case dynamicNil:
+		return nil, nil //nolint:nilnil
+	case dynamicFalse, dynamicTrue:
+		return tag == dynamicTrue, nil
+	case dynamicString:
+		return r.string()
+	case dynamicNumber:
+		s, err := r.string()
+
+		return json.Number(s), err
+	case dynamicFloat:
+		return r.float()
+	case dynamicInt:
+		n, err := r.varint()
+
+		return int(n), err
+	case dynamicInt64:
+		return r.varint()
+	case dynamicMap:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+
+		m := make(map[string]any, n)
+
+		for range n {
+			key, err := r.string()
+			if err != nil {
+				return nil, err
+			}
+
+			if m[key], err = r.dynamic(); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	case dynamicNilMap:
+		return map[string]any(nil), nil
+	case dynamicSlice:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+
+		s := make([]any, n)
+
+		for i := range s {
+			if s[i], err = r.dynamic(); err != nil {
+				return nil, err
+			}
+		}
+
+		return s, nil
+	case dynamicNilSlice:
+		return []any(nil), nil
+	default:
+		return nil, fmt.Errorf("binary payload: unknown value tag %d", tag)
+	}
+}
+
+// exportBinary serializes all Stub values stored in the searcher into a
+// compact binary payload.
+//
+// The payload is meant for trusted caches of the same version of the
+// package, it is much faster to decode than JSON but is not an interchange
+// format. Custom matchers are not serialized, like in the JSON export.
+//
+// Returns:
+// - []byte: The binary payload.
+// - error: An error if a Stub value cannot be serialized.
+func (s *searcher) exportBinary() ([]byte, error) {
+	codec, err := stubCodec()
+	if err != nil {
+		return nil, err
+	}
+
+	stubs := s.all()
+
+	// Sort the stubs to make the payload stable.
+	slices.SortFunc(stubs, compareStubs)
+
+	w := &binaryWriter{}
+	w.uvarint(binaryVersion)
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, stubLayout())
+	w.uvarint(uint64(len(stubs)))
+
+	for _, stub := range stubs {
+		if err := codec.encode(w, reflect.ValueOf(stub).Elem()); err != nil {
+			return nil, fmt.Errorf("stub %s: %w", stub.ID, err)
+		}
+	}
+
+	return w.buf, nil
+}
+
+// importBinary parses a binary payload made by exportBinary and stores its
+// Stub values in the searcher.
+//
+// The Stub values are restored as they were stored, empty maps and slices
+// included, and are written like by upsert but without Stub.Validate, so
+// the stubs writes accept are restored even if Import would refuse them.
+//
+// Parameters:
+// - data: The binary payload.
+//
+// Returns:
+// - error: An error if the payload cannot be parsed or has another version,
+// or the errors upsert refuses the Stub values for.
+func (s *searcher) importBinary(data []byte) error {
+	codec, err := stubCodec()
+	if err != nil {
+		return err
+	}
+
+	r := &binaryReader{data: data}
+
+	version, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+
+	if version != binaryVersion {
+		return fmt.Errorf("%w: binary payload version %d", ErrUnsupportedVersion, version)
+	}
+
+	layout, err := r.take(8) //nolint:mnd
+	if err != nil {
+		return err
+	}
+
+	if binary.LittleEndian.Uint64(layout) != stubLayout() {
+		return fmt.Errorf("%w: binary payload of stubs with other fields", ErrUnsupportedVersion)
+	}
+
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+
+	// Decode into a single allocation of the stubs.
+	values := make([]Stub, n)
+	stubs := make([]*Stub, n)
+
+	for i := range values {
+		if err := codec.decode(r, reflect.ValueOf(&values[i]).Elem()); err != nil {
+			return fmt.Errorf("stub %d: %w", i, err)
+		}
+
+		stubs[i] = &values[i]
+	}
+
+	if r.pos != len(data) {
+		return fmt.Errorf("binary payload: %d trailing bytes", len(data)-r.pos)
+	}
+
+	for _, stub := range stubs {
+		if stub.Key() == uuid.Nil {
+			stub.ID = s.newID(stub)
+		}
+	}
+
+	_, err = s.upsert(stubs...)
+
+	return err
+}
@@ -0,0 +1,29 @@
+package stuber
+
+import "github.com/google/uuid"
+
+// incrementAndGet counts a use of the stub with the given ID and returns the
+// number of its uses so far, including this one.
+//
+// The count is the one every match adds to, so the stub is reported as used
+// afterwards. Counting and reading happen under the write lock, so concurrent
+// callers each see a distinct count, e.g. to serve a stub at most N times.
+//
+// Parameters:
+// - id: The UUID of the Stub value to count.
+//
+// Returns:
+// - int: The number of uses of the Stub value.
+// - error: ErrStubNotFound if there is no Stub value with the given ID.
+func (s *searcher) incrementAndGet(id uuid.UUID) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findByID(id) == nil {
+		return 0, ErrStubNotFound
+	}
+
+	s.markUsed(id)
+
+	return s.calls[id], nil
+}
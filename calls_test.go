@@ -0,0 +1,74 @@
+package stuber_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_IncrementAndGet(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+
+	s.PutMany(stub)
+
+	_, err := s.IncrementAndGet(uuid.New())
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+
+	// Matches and explicit increments share the count.
+	_, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+
+	n, err := s.IncrementAndGet(stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []*stuber.Stub{stub}, s.Used())
+
+	// Concurrent callers each see a distinct count.
+	const workers = 50
+
+	seen := make(chan int, workers)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			n, err := s.IncrementAndGet(stub.ID)
+			if err == nil {
+				seen <- n
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(seen)
+
+	counts := make([]int, 0, workers)
+	for n := range seen {
+		counts = append(counts, n)
+	}
+
+	slices.Sort(counts)
+	require.Len(t, counts, workers)
+	require.Equal(t, 3, counts[0])
+	require.Equal(t, workers+2, counts[workers-1])
+	require.Len(t, slices.Compact(counts), workers)
+
+	// Clearing the used marks resets the count.
+	s.RecomputeUsed(func(*stuber.Stub) bool { return false })
+
+	n, err = s.IncrementAndGet(stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
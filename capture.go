@@ -0,0 +1,78 @@
+package stuber
+
+// capture stores the output fields and request headers the found stub
+// declares as captures.
+//
+// Captured values are keyed by their capture name and replace the values
+// captured earlier under the same name. Internal requests do not capture.
+//
+// Parameters:
+// - query: The Query used to find the Stub value.
+// - result: The Result of the search.
+//
+// Returns:
+// - *Result: The given Result.
+func (s *searcher) capture(query Query, result *Result) *Result {
+	found := result.found
+	if len(found.Captures) == 0 && len(found.HeaderCaptures) == 0 || query.RequestInternal() {
+		return result
+	}
+
+	data, _ := result.output.Data.(map[string]any)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, field := range found.Captures {
+		if value, ok := data[field]; ok {
+			s.captured[name] = value
+		}
+	}
+
+	for name, header := range found.HeaderCaptures {
+		if value, ok := query.Headers[header]; ok {
+			s.captured[name] = value
+		}
+	}
+
+	return result
+}
+
+// matchCaptured checks if the query carries the captured values the stub expects.
+//
+// Every field listed in the stub's captured input must be present in the
+// query data and equal to the value captured under the given name, and
+// every header listed in the stub's captured headers must be present in the
+// query headers with that value. A stub referencing a value that was not
+// captured yet does not match.
+//
+// Parameters:
+// - query: The Query used to search for a Stub value.
+// - stub: The Stub value to check.
+//
+// Returns:
+// - bool: Whether the query carries the expected captured values.
+func (s *searcher) matchCaptured(query Query, stub *Stub) bool {
+	if len(stub.Input.Captured) == 0 && len(stub.Headers.Captured) == 0 {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for field, name := range stub.Input.Captured {
+		value, ok := s.captured[name]
+		if !ok || !contains(map[string]any{field: value}, query.Data, false) {
+			return false
+		}
+	}
+
+	for header, name := range stub.Headers.Captured {
+		value, ok := s.captured[name]
+		if !ok || !contains(map[string]any{header: value}, query.Headers, false) {
+			return false
+		}
+	}
+
+	return true
+}
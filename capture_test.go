@@ -0,0 +1,147 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Captures(t *testing.T) {
+	stubs := func() []*stuber.Stub {
+		return []*stuber.Stub{
+			{
+				ID:       uuid.New(),
+				Service:  "Auth",
+				Method:   "Login",
+				Input:    stuber.InputData{Equals: map[string]interface{}{"user": "bob"}},
+				Output:   stuber.Output{Data: map[string]interface{}{"token": "abc"}},
+				Captures: map[string]string{"session": "token"},
+			},
+			{
+				ID:      uuid.New(),
+				Service: "Profile",
+				Method:  "Get",
+				Input: stuber.InputData{
+					Matches:  map[string]interface{}{"token": "^[a-z]+$"},
+					Captured: map[string]string{"token": "session"},
+				},
+				Output: stuber.Output{Data: map[string]interface{}{"name": "Bob"}},
+			},
+		}
+	}
+
+	profile := stuber.Query{Service: "Profile", Method: "Get", Data: map[string]interface{}{"token": "abc"}}
+	login := stuber.Query{Service: "Auth", Method: "Login", Data: map[string]interface{}{"user": "bob"}}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stubs()...)
+
+	// Nothing has been captured yet.
+	r, err := s.FindByQuery(profile)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"name": "Bob"}, r.Similar().Output.Data)
+
+	r, err = s.FindByQuery(login)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = s.FindByQuery(profile)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	// A different token does not match the captured one.
+	r, err = s.FindByQuery(stuber.Query{Service: "Profile", Method: "Get", Data: map[string]interface{}{"token": "xyz"}})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"name": "Bob"}, r.Similar().Output.Data)
+
+	// Sweeping the used marks keeps the captured values.
+	require.Zero(t, s.SweepUsed())
+
+	r, err = s.FindByQuery(profile)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	// Recomputing the used stubs resets the captured values.
+	s.RecomputeUsed(func(*stuber.Stub) bool { return true })
+
+	r, err = s.FindByQuery(profile)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"name": "Bob"}, r.Similar().Output.Data)
+
+	// So does clearing.
+	_, err = s.FindByQuery(login)
+	require.NoError(t, err)
+
+	s.Clear()
+	s.PutMany(stubs()...)
+
+	r, err = s.FindByQuery(profile)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"name": "Bob"}, r.Similar().Output.Data)
+}
+
+func TestBudgerigar_HeaderCaptures(t *testing.T) {
+	stubs := func() []*stuber.Stub {
+		return []*stuber.Stub{
+			{
+				ID:             uuid.New(),
+				Service:        "Orders",
+				Method:         "Create",
+				Headers:        stuber.InputHeader{Matches: map[string]interface{}{"x-trace-id": "^[0-9a-f]{8}$"}},
+				Output:         stuber.Output{Data: map[string]interface{}{"id": "1"}},
+				HeaderCaptures: map[string]string{"trace": "x-trace-id"},
+			},
+			{
+				ID:      uuid.New(),
+				Service: "Orders",
+				Method:  "Confirm",
+				Headers: stuber.InputHeader{Captured: map[string]string{"x-trace-id": "trace"}},
+				Output:  stuber.Output{Data: map[string]interface{}{"confirmed": true}},
+			},
+		}
+	}
+
+	create := stuber.Query{Service: "Orders", Method: "Create", Headers: map[string]interface{}{"x-trace-id": "0af7651b"}}
+	confirm := stuber.Query{Service: "Orders", Method: "Confirm", Headers: map[string]interface{}{"x-trace-id": "0af7651b"}}
+	other := stuber.Query{Service: "Orders", Method: "Confirm", Headers: map[string]interface{}{"x-trace-id": "b7ad6b71"}}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stubs()...)
+
+	// Nothing has been captured yet.
+	r, err := s.FindByQuery(confirm)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"confirmed": true}, r.Similar().Output.Data)
+
+	r, err = s.FindByQuery(create)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	// The later step of the same trace matches, another trace does not.
+	r, err = s.FindByQuery(confirm)
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = s.FindByQuery(other)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"confirmed": true}, r.Similar().Output.Data)
+
+	// Clearing resets the captured values.
+	s.Clear()
+	s.PutMany(stubs()...)
+
+	r, err = s.FindByQuery(confirm)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"confirmed": true}, r.Similar().Output.Data)
+}
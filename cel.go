@@ -0,0 +1,118 @@
+package stuber
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ErrInvalidCEL is returned when the CEL expression of a stub does not compile.
+var ErrInvalidCEL = errors.New("invalid cel expression")
+
+// compileCEL compiles the CEL expression of a stub.
+//
+// The expression sees the query data as the data variable and the query
+// headers as the headers variable, both maps, and must evaluate to a bool.
+// Numbers of different types compare by value.
+//
+// Parameters:
+// - expr: The CEL expression.
+//
+// Returns:
+// - cel.Program: The compiled program.
+// - error: An error wrapping ErrInvalidCEL if the expression does not compile.
+func compileCEL(expr string) (cel.Program, error) { //nolint:ireturn
+	env, err := cel.NewEnv(
+		cel.Variable("data", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCEL, err)
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCEL, issues.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("%w: result is %s, not bool", ErrInvalidCEL, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCEL, err)
+	}
+
+	return program, nil
+}
+
+// celPrograms caches the compiled CEL programs of the stored stubs.
+type celPrograms = compiled[cel.Program]
+
+// matchCEL checks if the query satisfies the CEL expression of a stub.
+//
+// An empty expression accepts every query. An expression that does not
+// compile, fails to evaluate, e.g. on an absent field, or does not evaluate
+// to true does not match. The program is taken from the cache, which may be
+// nil for a stub that is not stored.
+func matchCEL(stub *Stub, query Query, programs *celPrograms) bool {
+	if stub.CEL == "" {
+		return true
+	}
+
+	program := programs.get(stub.ID, stub.CEL, compileCEL)
+	if program == nil {
+		return false
+	}
+
+	out, _, err := program.Eval(map[string]any{
+		"data":    celValue(query.Data),
+		"headers": celValue(query.Headers),
+	})
+	if err != nil {
+		return false
+	}
+
+	result, ok := out.Value().(bool)
+
+	return ok && result
+}
+
+// celValue converts the value into the types CEL understands.
+//
+// JSON numbers become integers when they are whole, otherwise doubles, and
+// nil maps become empty ones.
+func celValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			converted[key] = celValue(item)
+		}
+
+		return converted
+	case []any:
+		converted := make([]any, len(v))
+		for i, item := range v {
+			converted[i] = celValue(item)
+		}
+
+		return converted
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+
+		return v.String()
+	default:
+		return v
+	}
+}
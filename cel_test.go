@@ -0,0 +1,93 @@
+package stuber_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_CEL(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(method, expr string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Orders",
+			Method:  method,
+			CEL:     expr,
+			Output:  stuber.Output{Data: map[string]interface{}{"message": method}},
+		}
+	}
+
+	amount := newStub("Charge", `data.amount > 100 && data.currency in ["EUR", "USD"]`)
+	items := newStub("Ship", `data.items.exists(i, i.sku.startsWith("X-")) && headers["x-tenant"] == "acme"`)
+
+	require.Len(t, s.PutMany(amount, items), 2)
+
+	tests := []struct {
+		query stuber.Query
+		found *stuber.Stub
+	}{
+		{stuber.Query{Service: "Orders", Method: "Charge", Data: map[string]interface{}{"amount": 150, "currency": "EUR"}}, amount},
+		{stuber.Query{
+			Service: "Orders",
+			Method:  "Charge",
+			Data:    map[string]interface{}{"amount": json.Number("100.5"), "currency": "USD"},
+		}, amount},
+		{stuber.Query{Service: "Orders", Method: "Charge", Data: map[string]interface{}{"amount": 50, "currency": "EUR"}}, nil},
+		{stuber.Query{Service: "Orders", Method: "Charge", Data: map[string]interface{}{"amount": 150, "currency": "GBP"}}, nil},
+		{stuber.Query{Service: "Orders", Method: "Charge", Data: map[string]interface{}{"currency": "EUR"}}, nil},
+		{stuber.Query{
+			Service: "Orders",
+			Method:  "Ship",
+			Headers: map[string]interface{}{"x-tenant": "acme"},
+			Data: map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"sku": "A-1"},
+				map[string]interface{}{"sku": "X-2"},
+			}},
+		}, items},
+		{stuber.Query{
+			Service: "Orders",
+			Method:  "Ship",
+			Data:    map[string]interface{}{"items": []interface{}{map[string]interface{}{"sku": "X-2"}}},
+		}, nil},
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(test.query)
+		if test.found == nil {
+			require.ErrorIs(t, err, stuber.ErrStubNotFound)
+			require.Nil(t, r)
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Same(t, test.found, r.Found())
+	}
+
+	// An updated stub is matched with its new expression.
+	raised := *amount
+	raised.CEL = `data.amount > 1000`
+	s.UpdateMany(&raised)
+
+	r, err := s.FindByQuery(tests[0].query)
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	// Expressions that do not compile or do not produce a bool are rejected.
+	for _, expr := range []string{`data.amount >`, `data.amount + 1`, `unknown.field == 1`} {
+		ids, err := s.PutManyE(newStub("Refund", expr))
+		require.ErrorIs(t, err, stuber.ErrInvalidCEL)
+		require.Nil(t, ids)
+		require.ErrorIs(t, newStub("Refund", expr).Validate(), stuber.ErrInvalidCEL)
+	}
+
+	require.Len(t, s.All(), 2)
+	require.ErrorIs(t, s.Import([]byte(`[{"service":"Orders","method":"Refund","cel":"data.(","output":{"data":{}}}]`)), stuber.ErrInvalidCEL)
+}
@@ -0,0 +1,80 @@
+package stuber
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// ErrInvalidChecksum is returned when a stub's body checksum has an unknown
+// algorithm or a value that is not hexadecimal.
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+const (
+	// ChecksumCRC32 is the IEEE CRC-32 of the body, as 8 hexadecimal digits.
+	ChecksumCRC32 = "crc32"
+
+	// ChecksumSHA256 is the SHA-256 of the body, as hexadecimal digits. A
+	// shorter value matches the digests it is a prefix of.
+	ChecksumSHA256 = "sha256"
+)
+
+// Checksum is the expected checksum of the request body, e.g. to simulate
+// content-addressed routing.
+//
+// The checksum is computed over Query.RawBody, or over the JSON encoding of
+// the query data if there is no raw body. Values are compared
+// case-insensitively.
+type Checksum struct {
+	Algorithm string `json:"algorithm"` // The algorithm, ChecksumCRC32 or ChecksumSHA256.
+	Value     string `json:"value"`     // The expected checksum in hexadecimal.
+}
+
+// matchChecksum checks if the body of the query has the expected checksum.
+func matchChecksum(expected *Checksum, query Query) bool {
+	if expected == nil {
+		return true
+	}
+
+	body := query.RawBody
+	if body == nil {
+		raw, err := json.Marshal(query.Data)
+		if err != nil {
+			return false
+		}
+
+		body = raw
+	}
+
+	value := strings.ToLower(expected.Value)
+
+	switch expected.Algorithm {
+	case ChecksumCRC32:
+		return fmt.Sprintf("%08x", crc32.ChecksumIEEE(body)) == value
+	case ChecksumSHA256:
+		sum := sha256.Sum256(body)
+
+		return value != "" && strings.HasPrefix(hex.EncodeToString(sum[:]), value)
+	default:
+		return false
+	}
+}
+
+// validate checks the algorithm and the value of the checksum.
+func (c *Checksum) validate() error {
+	switch c.Algorithm {
+	case ChecksumCRC32, ChecksumSHA256:
+	default:
+		return fmt.Errorf("%w: unknown algorithm %q", ErrInvalidChecksum, c.Algorithm)
+	}
+
+	if c.Value == "" || strings.Trim(strings.ToLower(c.Value), "0123456789abcdef") != "" {
+		return fmt.Errorf("%w: %q is not hexadecimal", ErrInvalidChecksum, c.Value)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package stuber_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_BodyChecksum(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	body := []byte(`{"order":"42"}`)
+	sum := sha256.Sum256(body)
+
+	crc := &stuber.Stub{
+		ID:           uuid.New(),
+		Service:      "Blobs",
+		Method:       "Put",
+		BodyChecksum: &stuber.Checksum{Algorithm: stuber.ChecksumCRC32, Value: fmt.Sprintf("%08X", crc32.ChecksumIEEE(body))},
+		Output:       stuber.Output{Data: map[string]interface{}{"algorithm": "crc32"}},
+	}
+	sha := &stuber.Stub{
+		ID:           uuid.New(),
+		Service:      "Blobs",
+		Method:       "Get",
+		BodyChecksum: &stuber.Checksum{Algorithm: stuber.ChecksumSHA256, Value: hex.EncodeToString(sum[:])[:12]},
+		Output:       stuber.Output{Data: map[string]interface{}{"algorithm": "sha256"}},
+	}
+
+	s.PutMany(crc, sha)
+
+	for method, stub := range map[string]*stuber.Stub{"Put": crc, "Get": sha} {
+		// The raw body is checked if present, otherwise the encoded data.
+		for _, query := range []stuber.Query{
+			{Service: "Blobs", Method: method, RawBody: body},
+			{Service: "Blobs", Method: method, Data: map[string]interface{}{"order": "42"}},
+		} {
+			r, err := s.FindByQuery(query)
+			require.NoError(t, err)
+			require.Same(t, stub, r.Found())
+		}
+
+		r, err := s.FindByQuery(stuber.Query{Service: "Blobs", Method: method, RawBody: []byte(`{"order":"43"}`)})
+		require.NoError(t, err)
+		require.Nil(t, r.Found())
+		require.Same(t, stub, r.Similar())
+
+		r, err = s.FindByQuery(stuber.Query{Service: "Blobs", Method: method, Data: map[string]interface{}{"order": "42", "retry": true}})
+		require.ErrorIs(t, err, stuber.ErrStubNotFound)
+		require.Nil(t, r)
+	}
+
+	for _, checksum := range []stuber.Checksum{
+		{Algorithm: "md5", Value: "abcd"},
+		{Algorithm: stuber.ChecksumCRC32, Value: "xyz"},
+		{Algorithm: stuber.ChecksumSHA256},
+	} {
+		invalid := *crc
+		invalid.BodyChecksum = &checksum
+		require.ErrorIs(t, invalid.Validate(), stuber.ErrInvalidChecksum)
+	}
+}
@@ -0,0 +1,84 @@
+package stuber
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// compiled caches the values compiled from an expression of the stored
+// stubs, e.g. their CEL programs, by stub ID. It has its own lock, so it can
+// be written while matching.
+//
+// Entries are released when their stubs change or are deleted, and an entry
+// compiled from another expression, e.g. of a stub not stored yet with the
+// same ID, is compiled again. The zero value is an empty cache.
+type compiled[T any] struct {
+	mu      sync.RWMutex
+	entries map[uuid.UUID]compiledEntry[T]
+}
+
+// compiledEntry is a compiled value with its expression. The value is the
+// zero value if the expression does not compile.
+type compiledEntry[T any] struct {
+	expr  string
+	value T
+}
+
+// get returns the value compiled from the expression of the stub with the
+// given ID, compiling it on first use. A nil cache compiles the expression
+// every time.
+//
+// Parameters:
+// - id: The UUID of the stub.
+// - expr: The expression of the stub.
+// - compile: The function compiling the expression.
+//
+// Returns:
+// - T: The compiled value, the zero value if the expression does not compile.
+func (c *compiled[T]) get(id uuid.UUID, expr string, compile func(string) (T, error)) T {
+	if c == nil {
+		value, _ := compile(expr)
+
+		return value
+	}
+
+	c.mu.RLock()
+	cached, ok := c.entries[id]
+	c.mu.RUnlock()
+
+	if ok && cached.expr == expr {
+		return cached.value
+	}
+
+	value, _ := compile(expr)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[uuid.UUID]compiledEntry[T])
+	}
+
+	c.entries[id] = compiledEntry[T]{expr: expr, value: value}
+
+	return value
+}
+
+// release drops the values of the stubs with the given IDs.
+func (c *compiled[T]) release(ids ...uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+}
+
+// reset drops all the values.
+func (c *compiled[T]) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
@@ -0,0 +1,106 @@
+package stuber
+
+import (
+	"fmt"
+
+	"github.com/gripmock/deeply"
+)
+
+// Condition is a group of matchers of the request data.
+//
+// A condition matches when its equals, contains and matches maps match and,
+// if set, every condition of All and at least one condition of Any match.
+// This nests, so conditions express any combination of AND and OR groups.
+type Condition struct {
+	Equals   map[string]interface{} `json:"equals,omitempty"`   // The data to match exactly.
+	Contains map[string]interface{} `json:"contains,omitempty"` // The data to match partially.
+	Matches  map[string]interface{} `json:"matches,omitempty"`  // The data to match using regular expressions.
+	All      []Condition            `json:"all,omitempty"`      // The conditions that must all match.
+	Any      []Condition            `json:"any,omitempty"`      // The conditions of which one must match.
+}
+
+// matchAny checks if at least one of the conditions matches the data.
+//
+// An empty group matches any data.
+func matchAny(conditions []Condition, data map[string]any, orderIgnore bool) bool {
+	if len(conditions) == 0 {
+		return true
+	}
+
+	for _, condition := range conditions {
+		if condition.match(data, orderIgnore) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match checks if the condition matches the data.
+func (c Condition) match(data map[string]any, orderIgnore bool) bool {
+	if !present(c.Equals, data) || !present(c.Contains, data) {
+		return false
+	}
+
+	if !equals(c.Equals, data, orderIgnore) || !contains(c.Contains, data, orderIgnore) ||
+		!matches(c.Matches, data, orderIgnore) {
+		return false
+	}
+
+	for _, condition := range c.All {
+		if !condition.match(data, orderIgnore) {
+			return false
+		}
+	}
+
+	return matchAny(c.Any, data, orderIgnore)
+}
+
+// rankAny ranks the data against a group of alternative conditions.
+//
+// The group contributes the best rank of its conditions.
+func rankAny(conditions []Condition, data map[string]any) float64 {
+	var best float64
+
+	for _, condition := range conditions {
+		best = max(best, condition.rank(data))
+	}
+
+	return best
+}
+
+// rank ranks the data against the condition.
+//
+// The ranks of the condition's maps and of its All conditions are summed,
+// the Any conditions contribute their best rank.
+func (c Condition) rank(data map[string]any) float64 {
+	rank := deeply.RankMatch(c.Equals, data) +
+		deeply.RankMatch(c.Contains, data) +
+		deeply.RankMatch(c.Matches, data)
+
+	for _, condition := range c.All {
+		rank += condition.rank(data)
+	}
+
+	return rank + rankAny(c.Any, data)
+}
+
+// compileConditions checks that the regular expressions of the conditions,
+// including the nested ones, compile.
+func compileConditions(conditions []Condition) error {
+	for i, condition := range conditions {
+		if err := compileMatches(condition.Matches); err != nil {
+			return fmt.Errorf("condition %d: %w", i, err)
+		}
+
+		if err := compileConditions(condition.All); err != nil {
+			return fmt.Errorf("condition %d: %w", i, err)
+		}
+
+		if err := compileConditions(condition.Any); err != nil {
+			return fmt.Errorf("condition %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
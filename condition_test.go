@@ -0,0 +1,87 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_AnyConditions(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	status := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Get",
+		Input: stuber.InputData{Any: []stuber.Condition{
+			{Equals: map[string]interface{}{"status": "A"}},
+			{Equals: map[string]interface{}{"status": "B"}},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "status"}},
+	}
+
+	nested := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "List",
+		Input: stuber.InputData{
+			Contains: map[string]interface{}{"region": "eu"},
+			Any: []stuber.Condition{{All: []stuber.Condition{
+				{Any: []stuber.Condition{
+					{Contains: map[string]interface{}{"kind": "retail"}},
+					{Contains: map[string]interface{}{"kind": "wholesale"}},
+				}},
+				{Any: []stuber.Condition{
+					{Matches: map[string]interface{}{"code": "^X[0-9]+$"}},
+					{Contains: map[string]interface{}{"priority": true}},
+				}},
+			}}},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "nested"}},
+	}
+
+	s.PutMany(status, nested)
+
+	for _, value := range []string{"A", "B"} {
+		r, err := s.FindByQuery(stuber.Query{Service: "Orders", Method: "Get", Data: map[string]interface{}{"status": value}})
+		require.NoError(t, err)
+		require.Same(t, status, r.Found())
+	}
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Orders", Method: "Get", Data: map[string]interface{}{"status": "C"}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	tests := []struct {
+		data  map[string]interface{}
+		found bool
+	}{
+		{map[string]interface{}{"region": "eu", "kind": "retail", "code": "X1"}, true},
+		{map[string]interface{}{"region": "eu", "kind": "wholesale", "priority": true}, true},
+		{map[string]interface{}{"region": "eu", "kind": "retail", "code": "Y1"}, false},
+		{map[string]interface{}{"region": "eu", "kind": "other", "code": "X1"}, false},
+		{map[string]interface{}{"region": "us", "kind": "retail", "code": "X1"}, false},
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(stuber.Query{Service: "Orders", Method: "List", Data: test.data})
+		if !test.found {
+			require.NoError(t, err)
+			require.Nil(t, r.Found())
+			require.Same(t, nested, r.Similar())
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Same(t, nested, r.Found())
+	}
+
+	invalid := *status
+	invalid.Input.Any = []stuber.Condition{{Any: []stuber.Condition{{Matches: map[string]interface{}{"code": "("}}}}}
+	require.Error(t, invalid.Validate())
+}
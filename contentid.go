@@ -0,0 +1,46 @@
+package stuber
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// contentNamespace is the name of the namespace of content-based stub IDs.
+const contentNamespace = "github.com/gripmock/stuber"
+
+// WithContentIDs makes the searcher give stubs without an ID a UUIDv5
+// derived from their content instead of a random UUIDv4.
+//
+// The content is everything but the ID and the creation time, so identical
+// stubs get the same ID across runs and replace each other when stored.
+func WithContentIDs() Option {
+	return func(s *searcher) {
+		s.contentIDs = true
+	}
+}
+
+// newID returns the ID of a stub that has none.
+//
+// Parameters:
+// - stub: The stub to identify.
+//
+// Returns:
+// - uuid.UUID: A content-based UUIDv5 if enabled, otherwise a random UUIDv4.
+func (s *searcher) newID(stub *Stub) uuid.UUID {
+	if !s.contentIDs {
+		return uuid.New()
+	}
+
+	content := *stub
+	content.ID = uuid.Nil
+	content.CreatedAt = time.Time{}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return uuid.New()
+	}
+
+	return uuid.NewSHA1(uuid.NewSHA1(uuid.NameSpaceURL, []byte(contentNamespace)), raw)
+}
@@ -0,0 +1,45 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ContentIDs(t *testing.T) {
+	newStub := func(message string) *stuber.Stub {
+		return &stuber.Stub{
+			Service: "Greeter",
+			Method:  "SayHello",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": message}},
+		}
+	}
+
+	first := stuber.NewBudgerigar(features.New(), stuber.WithContentIDs())
+	second := stuber.NewBudgerigar(features.New(), stuber.WithContentIDs())
+
+	ids := first.PutMany(newStub("Hello"), newStub("Hi"))
+	require.Len(t, ids, 2)
+	require.Equal(t, byte(5), ids[0][6]>>4)
+	require.NotEqual(t, ids[0], ids[1])
+
+	// The same content gets the same ID across searchers and is stored once.
+	require.Equal(t, ids[:1], second.PutMany(newStub("Hello")))
+	require.NoError(t, second.Import([]byte(`[{"service":"Greeter","method":"SayHello",`+
+		`"input":{"equals":{"name":"Bob"}},"output":{"data":{"message":"Hello"}}}]`)))
+	require.Len(t, second.All(), 1)
+
+	// Explicit IDs are kept.
+	explicit := newStub("Hello")
+	explicit.ID = uuid.New()
+	require.Equal(t, []uuid.UUID{explicit.ID}, first.PutMany(explicit))
+
+	// Random IDs remain the default.
+	random := stuber.NewBudgerigar(features.New())
+	require.Equal(t, byte(4), random.PutMany(newStub("Hello"))[0][6]>>4)
+}
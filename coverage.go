@@ -0,0 +1,84 @@
+package stuber
+
+import "strings"
+
+// missingMethods returns the expected methods without any stub, e.g. to
+// check in CI that every RPC of a proto surface is mocked.
+//
+// A method counts as stubbed if a stub has it as its method or one of its
+// aliases, or as a prefix ending with MethodPrefixToken.
+//
+// Parameters:
+// - expected: The expected methods keyed by service.
+//
+// Returns:
+// - map[string][]string: The methods without stubs keyed by service, in the
+// order they were given. Fully covered services are omitted.
+func (s *searcher) missingMethods(expected map[string][]string) map[string][]string {
+	missing := make(map[string][]string)
+
+	for service, methods := range expected {
+		for _, method := range methods {
+			if !s.storage.has(service, method) && len(s.findByPrefix(service, method)) == 0 {
+				missing[service] = append(missing[service], method)
+			}
+		}
+	}
+
+	return missing
+}
+
+// unconstrainedFields returns the fields of a sample query that no stub of
+// the service and method matches on, revealing gaps in the mock coverage.
+//
+// Fields are dot-separated paths. A field counts as constrained if a data
+// matcher of any stub references it, an object containing it, or a field
+// nested in it. Header, CEL and custom matchers are not taken into account.
+//
+// Parameters:
+// - service: The service of the stubs.
+// - method: The method of the stubs.
+// - fields: The field paths of the sample query.
+//
+// Returns:
+// - []string: The unconstrained fields, in the order they were given.
+// - error: ErrServiceNotFound or ErrMethodNotFound if there are no stubs.
+func (s *searcher) unconstrainedFields(service, method string, fields []string) ([]string, error) {
+	stubs, err := s.findBy(service, method)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]struct{})
+
+	for _, stub := range stubs {
+		for term := range matcherTerms(stub) {
+			matcher, path, ok := strings.Cut(term, ":")
+			if ok && strings.HasPrefix(matcher, "input.") {
+				paths[path] = struct{}{}
+			}
+		}
+	}
+
+	var results []string
+
+	for _, field := range fields {
+		if !constrained(paths, field) {
+			results = append(results, field)
+		}
+	}
+
+	return results, nil
+}
+
+// constrained checks if the field, one of its parents or one of its nested
+// fields is among the paths.
+func constrained(paths map[string]struct{}, field string) bool {
+	for path := range paths {
+		if path == field || strings.HasPrefix(field, path+".") || strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+
+	return false
+}
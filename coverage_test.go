@@ -0,0 +1,88 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_MissingMethods(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	get := &stuber.Stub{ID: uuid.New(), Service: "Users", Method: "Get", Output: stuber.Output{Error: "boom"}}
+
+	s.PutMany(
+		get,
+		&stuber.Stub{ID: uuid.New(), Service: "Users", Method: "List", Methods: []string{"Search"}, Output: stuber.Output{Error: "boom"}},
+		&stuber.Stub{ID: uuid.New(), Service: "Users", Method: "Batch*", Output: stuber.Output{Error: "boom"}},
+	)
+
+	require.Empty(t, s.MissingMethods(map[string][]string{
+		"Users": {"Get", "List", "Search", "BatchGet"},
+	}))
+
+	require.Equal(t, map[string][]string{
+		"Users":  {"Delete", "Create"},
+		"Orders": {"Get"},
+	}, s.MissingMethods(map[string][]string{
+		"Users":  {"Get", "Delete", "List", "Create"},
+		"Orders": {"Get"},
+	}))
+
+	// A method whose stubs were all deleted is missing again.
+	s.DeleteByID(get.ID)
+
+	require.Equal(t, map[string][]string{"Users": {"Get"}}, s.MissingMethods(map[string][]string{"Users": {"Get"}}))
+}
+
+func TestBudgerigar_UnconstrainedFields(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Orders",
+			Method:  "Create",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"customer": map[string]interface{}{"id": "1"}}},
+			Output:  stuber.Output{Data: map[string]interface{}{}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Orders",
+			Method:  "Create",
+			Headers: stuber.InputHeader{Equals: map[string]interface{}{"currency": "EUR"}},
+			Input: stuber.InputData{
+				OneOf: map[string][]interface{}{"status": {"new"}},
+				Items: map[string]stuber.ItemBounds{"lines": {MinItems: 1}},
+			},
+			Output: stuber.Output{Data: map[string]interface{}{}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Orders",
+			Method:  "Cancel",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"currency": "EUR"}},
+			Output:  stuber.Output{Data: map[string]interface{}{}},
+		},
+	)
+
+	fields, err := s.UnconstrainedFields("Orders", "Create", []string{
+		"customer", "customer.id", "customer.name", "status", "lines.0.sku", "currency", "notes",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"customer.name", "currency", "notes"}, fields)
+
+	fields, err = s.UnconstrainedFields("Orders", "Create", []string{"status"})
+	require.NoError(t, err)
+	require.Empty(t, fields)
+
+	_, err = s.UnconstrainedFields("Orders", "Refund", []string{"status"})
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+
+	_, err = s.UnconstrainedFields("Payments", "Create", []string{"status"})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+}
@@ -0,0 +1,672 @@
+package stuber
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidExpression is returned when a matcher expression cannot be parsed.
+var ErrInvalidExpression = errors.New("invalid matcher expression")
+
+// headersPrefix is the first path segment that refers to the query headers.
+const headersPrefix = "headers"
+
+// ExpressionError describes where a matcher expression is malformed.
+type ExpressionError struct {
+	Pos int    // The zero-based byte offset of the error in the expression.
+	Msg string // The description of the error.
+}
+
+// Error returns the description of the error with its position.
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", ErrInvalidExpression, e.Pos, e.Msg)
+}
+
+// Unwrap returns ErrInvalidExpression.
+func (e *ExpressionError) Unwrap() error {
+	return ErrInvalidExpression
+}
+
+// ParseMatcher parses a matcher expression into a Matcher usable as Stub.Matcher.
+// Stub.Expr takes the same expressions, and is kept when the stub is exported.
+//
+// An expression compares fields of the query with literals and combines the
+// comparisons with &&, || and !, grouped by parentheses, for example:
+//
+//	user.id == "42" && amount > 100 && headers.auth =~ "Bearer .*"
+//
+// Fields are dot-separated paths into the query data, numeric segments index
+// arrays. Paths starting with "headers." refer to the query headers instead.
+// Literals are double-quoted strings, numbers, true, false and null.
+//
+// The operators are == and !=, the ordering operators <, <=, > and >= for
+// numbers and strings, and =~ and !~ matching a regular expression. A field
+// that is absent equals null only, and fails every other comparison except !=.
+//
+// Parameters:
+// - expr: The expression to parse.
+//
+// Returns:
+// - Matcher: The Matcher evaluating the expression.
+// - error: An *ExpressionError with the position of the first syntax error.
+func ParseMatcher(expr string) (Matcher, error) { //nolint:ireturn
+	root, err := compileExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return MatcherFunc(root.eval), nil
+}
+
+// compileExpr parses a matcher expression into its syntax tree.
+//
+// Parameters:
+// - expr: The expression to parse.
+//
+// Returns:
+// - node: The root of the syntax tree.
+// - error: An *ExpressionError with the position of the first syntax error.
+func compileExpr(expr string) (node, error) { //nolint:ireturn
+	p := &parser{lexer: lexer{input: expr}}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return node, nil
+}
+
+// exprNodes caches the syntax trees of the matcher expressions of the stored stubs.
+type exprNodes = compiled[node]
+
+// matchExpr checks if the query satisfies the matcher expression of a stub.
+//
+// An empty expression accepts every query, one that does not compile does
+// not match. The syntax tree is taken from the cache, which may be nil for
+// a stub that is not stored.
+func matchExpr(stub *Stub, query Query, nodes *exprNodes) bool {
+	if stub.Expr == "" {
+		return true
+	}
+
+	root := nodes.get(stub.ID, stub.Expr, compileExpr)
+
+	return root != nil && root.eval(query)
+}
+
+// rankExpr ranks how well the query satisfies the matcher expression of a
+// stub by the number of its comparisons that hold, so a more specific
+// expression ranks higher. A comparison under a negation counts when it fails.
+//
+// Returns zero if the stub has no expression or it does not compile.
+func rankExpr(stub *Stub, query Query, nodes *exprNodes) float64 {
+	if stub.Expr == "" {
+		return 0
+	}
+
+	root := nodes.get(stub.ID, stub.Expr, compileExpr)
+	if root == nil {
+		return 0
+	}
+
+	satisfied, _ := root.count(query)
+
+	return float64(satisfied)
+}
+
+// tokenKind is the kind of a lexical token of a matcher expression.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPath
+	tokenString
+	tokenNumber
+	tokenLiteral
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+// token is a lexical token of a matcher expression.
+type token struct {
+	kind  tokenKind
+	pos   int
+	text  string
+	value any
+}
+
+// String describes the token for error messages.
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+// lexer splits a matcher expression into tokens.
+type lexer struct {
+	input string
+	pos   int
+}
+
+// operators lists the operators, longer ones first.
+var operators = []struct { //nolint:gochecknoglobals
+	text string
+	kind tokenKind
+}{
+	{"&&", tokenAnd}, {"||", tokenOr},
+	{"==", tokenOp}, {"!=", tokenOp}, {"=~", tokenOp}, {"!~", tokenOp},
+	{"<=", tokenOp}, {">=", tokenOp}, {"<", tokenOp}, {">", tokenOp},
+	{"!", tokenNot}, {"(", tokenLParen}, {")", tokenRParen},
+}
+
+// next returns the next token of the expression.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(rune(l.input[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+	if start == len(l.input) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.input[start]
+
+	switch {
+	case c == '"':
+		return l.string()
+	case c == '-' || c >= '0' && c <= '9':
+		return l.number()
+	case isPathByte(c) && !(c >= '0' && c <= '9') && c != '.':
+		for l.pos < len(l.input) && isPathByte(l.input[l.pos]) {
+			l.pos++
+		}
+
+		text := l.input[start:l.pos]
+
+		switch text {
+		case "true", "false":
+			return token{kind: tokenLiteral, pos: start, text: text, value: text == "true"}, nil
+		case "null":
+			return token{kind: tokenLiteral, pos: start, text: text}, nil
+		}
+
+		if strings.HasPrefix(text, ".") || strings.HasSuffix(text, ".") || strings.Contains(text, "..") {
+			return token{}, &ExpressionError{Pos: start, Msg: fmt.Sprintf("malformed path %q", text)}
+		}
+
+		return token{kind: tokenPath, pos: start, text: text}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.input[start:], op.text) {
+			l.pos += len(op.text)
+
+			return token{kind: op.kind, pos: start, text: op.text}, nil
+		}
+	}
+
+	return token{}, &ExpressionError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+}
+
+// string scans a double-quoted string literal.
+func (l *lexer) string() (token, error) {
+	start := l.pos
+
+	for l.pos++; l.pos < len(l.input); l.pos++ {
+		switch l.input[l.pos] {
+		case '\\':
+			l.pos++
+		case '"':
+			l.pos++
+			text := l.input[start:l.pos]
+
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return token{}, &ExpressionError{Pos: start, Msg: "malformed string " + text}
+			}
+
+			return token{kind: tokenString, pos: start, text: text, value: value}, nil
+		}
+	}
+
+	return token{}, &ExpressionError{Pos: start, Msg: "unterminated string"}
+}
+
+// number scans a number literal.
+func (l *lexer) number() (token, error) {
+	start := l.pos
+
+	for l.pos++; l.pos < len(l.input); l.pos++ {
+		c := l.input[l.pos]
+		if !(c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' ||
+			(c == '-' || c == '+') && (l.input[l.pos-1] == 'e' || l.input[l.pos-1] == 'E')) {
+			break
+		}
+	}
+
+	text := l.input[start:l.pos]
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, &ExpressionError{Pos: start, Msg: fmt.Sprintf("malformed number %q", text)}
+	}
+
+	return token{kind: tokenNumber, pos: start, text: text, value: value}, nil
+}
+
+// isPathByte checks if the byte may be part of a field path.
+func isPathByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parser builds the syntax tree of a matcher expression.
+type parser struct {
+	lexer lexer
+	tok   token
+}
+
+// next advances to the next token.
+func (p *parser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+
+	return nil
+}
+
+// errorf returns an error at the position of the current token.
+func (p *parser) errorf(format string, args ...any) error {
+	return &ExpressionError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses a disjunction of conjunctions.
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokenOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses a conjunction of unary expressions.
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokenAnd {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a comparison.
+func (p *parser) parseUnary() (node, error) {
+	switch p.tok.kind {
+	case tokenNot:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{operand: operand}, nil
+	case tokenLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != tokenRParen {
+			return nil, p.errorf("expected \")\", got %s", p.tok)
+		}
+
+		return inner, p.next()
+	case tokenPath:
+		return p.parseComparison()
+	default:
+		return nil, p.errorf("expected a field, \"!\" or \"(\", got %s", p.tok)
+	}
+}
+
+// parseComparison parses a field compared with a literal.
+func (p *parser) parseComparison() (node, error) {
+	comparison := compareNode{path: strings.Split(p.tok.text, ".")}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokenOp {
+		return nil, p.errorf("expected an operator, got %s", p.tok)
+	}
+
+	comparison.op = p.tok.text
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	switch p.tok.kind {
+	case tokenString, tokenNumber, tokenLiteral:
+		comparison.value = p.tok.value
+	default:
+		return nil, p.errorf("expected a literal, got %s", p.tok)
+	}
+
+	switch comparison.op {
+	case "=~", "!~":
+		pattern, ok := comparison.value.(string)
+		if !ok {
+			return nil, p.errorf("operator %s expects a string, got %s", comparison.op, p.tok)
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, p.errorf("invalid regular expression: %v", err)
+		}
+
+		comparison.re = re
+	case "<", "<=", ">", ">=":
+		if _, ok := comparison.value.(bool); ok || comparison.value == nil {
+			return nil, p.errorf("operator %s expects a number or a string, got %s", comparison.op, p.tok)
+		}
+	}
+
+	return comparison, p.next()
+}
+
+// node is a node of the syntax tree of a matcher expression.
+type node interface {
+	eval(query Query) bool
+	// count returns the number of comparisons below the node that hold for
+	// the query, failing ones under a negation, and the number of all of them.
+	count(query Query) (int, int)
+}
+
+// andNode is true when both operands are true.
+type andNode struct{ left, right node }
+
+func (n andNode) eval(query Query) bool {
+	return n.left.eval(query) && n.right.eval(query)
+}
+
+func (n andNode) count(query Query) (int, int) {
+	return countBoth(query, n.left, n.right)
+}
+
+// orNode is true when either operand is true.
+type orNode struct{ left, right node }
+
+func (n orNode) eval(query Query) bool {
+	return n.left.eval(query) || n.right.eval(query)
+}
+
+func (n orNode) count(query Query) (int, int) {
+	return countBoth(query, n.left, n.right)
+}
+
+// countBoth sums the counts of both operands.
+func countBoth(query Query, left, right node) (int, int) {
+	leftSatisfied, leftTotal := left.count(query)
+	rightSatisfied, rightTotal := right.count(query)
+
+	return leftSatisfied + rightSatisfied, leftTotal + rightTotal
+}
+
+// notNode negates its operand.
+type notNode struct{ operand node }
+
+func (n notNode) eval(query Query) bool {
+	return !n.operand.eval(query)
+}
+
+func (n notNode) count(query Query) (int, int) {
+	satisfied, total := n.operand.count(query)
+
+	return total - satisfied, total
+}
+
+// compareNode compares a field of the query with a literal.
+type compareNode struct {
+	path  []string
+	op    string
+	value any
+	re    *regexp.Regexp
+}
+
+func (n compareNode) eval(query Query) bool {
+	var (
+		actual any
+		ok     bool
+	)
+
+	if n.path[0] == headersPrefix && len(n.path) > 1 {
+		actual, ok = lookup(query.Headers, n.path[1:])
+	} else {
+		actual, ok = lookup(query.Data, n.path)
+	}
+
+	switch n.op {
+	case "==":
+		return ok && equalLiteral(actual, n.value) || !ok && n.value == nil
+	case "!=":
+		return !ok && n.value != nil || ok && !equalLiteral(actual, n.value)
+	case "=~":
+		s, isText := text(actual)
+
+		return ok && isText && n.re.MatchString(s)
+	case "!~":
+		s, isText := text(actual)
+
+		return ok && isText && !n.re.MatchString(s)
+	default:
+		return ok && order(actual, n.value, n.op)
+	}
+}
+
+func (n compareNode) count(query Query) (int, int) {
+	if n.eval(query) {
+		return 1, 1
+	}
+
+	return 0, 1
+}
+
+// splitPath splits a field path into its segments.
+//
+// Segments are separated by dots, and array indexes may be written either as
+// segments or in brackets, so "items[0].sku" and "items.0.sku" are the same
+// path. Indexes are zero-based, an out-of-range or negative index resolves
+// to nothing.
+func splitPath(path string) []string {
+	return strings.Split(strings.NewReplacer("[", ".", "]", "").Replace(path), ".")
+}
+
+// lookup resolves the path in the value, numeric segments index arrays.
+func lookup(value any, path []string) (any, bool) {
+	for _, segment := range path {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+
+			value = next
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// equalLiteral checks if the value equals the literal.
+//
+// Numbers are compared by value, and a string literal also equals a number
+// written the same way.
+func equalLiteral(actual, literal any) bool {
+	if literal == nil {
+		return actual == nil
+	}
+
+	if n, ok := literal.(float64); ok {
+		a, isNumber := number(actual)
+
+		return isNumber && a == n
+	}
+
+	if s, ok := literal.(string); ok {
+		a, isText := text(actual)
+
+		return isText && a == s
+	}
+
+	b, ok := actual.(bool)
+
+	return ok && b == literal
+}
+
+// order compares the value with the literal using the ordering operator.
+func order(actual, literal any, op string) bool {
+	var c int
+
+	switch l := literal.(type) {
+	case float64:
+		a, ok := number(actual)
+		if !ok {
+			return false
+		}
+
+		c = cmp.Compare(a, l)
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return false
+		}
+
+		c = strings.Compare(a, l)
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// number converts a numeric value to float64.
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// text converts a string or numeric value to a string.
+func text(value any) (string, bool) {
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+
+	if n, ok := value.(json.Number); ok {
+		return n.String(), true
+	}
+
+	if f, ok := number(value); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+
+	return "", false
+}
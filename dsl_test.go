@@ -0,0 +1,159 @@
+package stuber_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestParseMatcher(t *testing.T) {
+	query := stuber.Query{
+		Headers: map[string]interface{}{"auth": "Bearer abc"},
+		Data: map[string]interface{}{
+			"user":   map[string]interface{}{"id": "42", "name": "bob"},
+			"amount": json.Number("150"),
+			"count":  3,
+			"active": true,
+			"note":   nil,
+			"items":  []interface{}{"a", "b"},
+		},
+	}
+
+	for expr, expected := range map[string]bool{
+		`user.id == "42" && amount > 100 && headers.auth =~ "Bearer .*"`: true,
+		`user.id == "43" || amount >= 150`:                               true,
+		`!(user.name == "bob")`:                                          false,
+		`amount < 100`:                                                   false,
+		`amount == "150"`:                                                true,
+		`count <= 3 && count != 4`:                                       true,
+		`active == true && note == null`:                                 true,
+		`missing == null && missing != "x"`:                              true,
+		`missing > 1`:                                                    false,
+		`items.1 == "b" && items.2 == null`:                              true,
+		`user.name !~ "^a" && user.name >= "b"`:                          true,
+		`headers.auth == "Bearer abc" && headers.missing == null`:        true,
+		`amount > -1e3 && (count == 1 || count == 3)`:                    true,
+	} {
+		m, err := stuber.ParseMatcher(expr)
+		require.NoError(t, err, expr)
+		require.Equal(t, expected, m.Match(query), expr)
+	}
+}
+
+func TestParseMatcher_Errors(t *testing.T) {
+	for expr, pos := range map[string]int{
+		``:                      0,
+		`user.id`:               7,
+		`user.id == `:           11,
+		`user.id == "42`:        11,
+		`user.id == "42" &&`:    18,
+		`(amount > 1`:           11,
+		`amount > 1)`:           10,
+		`amount # 1`:            7,
+		`amount =~ 1`:           10,
+		`name =~ "("`:           8,
+		`active > true`:         9,
+		`user..id == 1`:         0,
+		`amount == 1 amount`:    12,
+		`amount == 1.2.3`:       10,
+		`== 1`:                  0,
+		`!`:                     1,
+		`amount > 1 || (a == 1`: 21,
+	} {
+		_, err := stuber.ParseMatcher(expr)
+		require.ErrorIs(t, err, stuber.ErrInvalidExpression, expr)
+
+		var exprErr *stuber.ExpressionError
+
+		require.ErrorAs(t, err, &exprErr, expr)
+		require.Equal(t, pos, exprErr.Pos, expr)
+	}
+}
+
+func TestParseMatcher_Stub(t *testing.T) {
+	m, err := stuber.ParseMatcher(`amount > 100 && headers.auth =~ "^Bearer "`)
+	require.NoError(t, err)
+
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Payments",
+		Method:  "Charge",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"currency": "EUR"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"status": "ok"}},
+		Matcher: m,
+	}
+
+	s.PutMany(stub)
+
+	r, err := s.FindByQuery(stuber.Query{
+		Service: "Payments",
+		Method:  "Charge",
+		Headers: map[string]interface{}{"auth": "Bearer abc"},
+		Data:    map[string]interface{}{"amount": 150, "currency": "EUR"},
+	})
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+
+	r, err = s.FindByQuery(stuber.Query{
+		Service: "Payments",
+		Method:  "Charge",
+		Headers: map[string]interface{}{"auth": "Bearer abc"},
+		Data:    map[string]interface{}{"amount": 50, "currency": "EUR"},
+	})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, stub, r.Similar())
+}
+
+func TestStub_Expr(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	require.NoError(t, s.Import([]byte(`[
+		{"service":"Payments","method":"Charge","expr":"amount > 100","output":{"data":{"status":"large"}}},
+		{"service":"Payments","method":"Charge","expr":"amount > 100 && headers.auth =~ \"^Bearer \"","output":{"data":{"status":"authorized"}}}
+	]`)))
+
+	// The expression holding more comparisons ranks higher.
+	r, err := s.FindByQuery(stuber.Query{
+		Service: "Payments",
+		Method:  "Charge",
+		Headers: map[string]interface{}{"auth": "Bearer abc"},
+		Data:    map[string]interface{}{"amount": 150},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"status": "authorized"}, r.Found().Output.Data)
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Payments", Method: "Charge", Data: map[string]interface{}{"amount": 150}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"status": "large"}, r.Found().Output.Data)
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Payments", Method: "Charge", Data: map[string]interface{}{"amount": 50}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	// Unlike a Matcher, the expression is kept by an export.
+	data, err := s.Export()
+	require.NoError(t, err)
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.Import(data))
+
+	r, err = restored.FindByQuery(stuber.Query{Service: "Payments", Method: "Charge", Data: map[string]interface{}{"amount": 150}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"status": "large"}, r.Found().Output.Data)
+
+	// Expressions that do not parse are refused.
+	ids, err := s.PutManyE(&stuber.Stub{Service: "Payments", Method: "Charge", Expr: "amount >", Output: stuber.Output{Error: "boom"}})
+	require.ErrorIs(t, err, stuber.ErrInvalidExpression)
+	require.Nil(t, ids)
+	require.ErrorIs(t, s.Import([]byte(`[{"service":"Payments","method":"Charge","expr":"amount >","output":{"data":{}}}]`)),
+		stuber.ErrInvalidExpression)
+	require.Len(t, s.All(), 2)
+}
@@ -0,0 +1,151 @@
+package stuber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// findDuplicates groups the Stub values that are near-duplicates of each other.
+//
+// Only stubs of the same service and method are compared. The similarity of
+// two stubs is the Jaccard index of their content: the headers, input and
+// output are flattened into sets of path and value pairs, and the size of
+// the intersection is divided by the size of the union. Identical content
+// scores 1, content without any common pair scores 0. Stubs are grouped
+// when their similarity is at least the threshold, and the groups are
+// closed transitively. The searcher is not modified.
+//
+// Parameters:
+// - threshold: The minimum similarity, between 0 and 1.
+//
+// Returns:
+// - [][]uuid.UUID: The groups of two or more stub IDs, each sorted, ordered by their first ID.
+func (s *searcher) findDuplicates(threshold float64) [][]uuid.UUID {
+	byMethod := make(map[group][]*Stub)
+
+	for _, stub := range s.all() {
+		key := group{service: stub.Service, method: stub.Method}
+		byMethod[key] = append(byMethod[key], stub)
+	}
+
+	// Union-find over the stub IDs.
+	parent := make(map[uuid.UUID]uuid.UUID)
+
+	var root func(id uuid.UUID) uuid.UUID
+
+	root = func(id uuid.UUID) uuid.UUID {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = root(p)
+
+			return parent[id]
+		}
+
+		return id
+	}
+
+	for _, stubs := range byMethod {
+		contents := make([]map[string]struct{}, len(stubs))
+		for i, stub := range stubs {
+			contents[i] = flattenContent(stub)
+		}
+
+		for i := range stubs {
+			for j := i + 1; j < len(stubs); j++ {
+				if jaccard(contents[i], contents[j]) >= threshold {
+					parent[root(stubs[j].ID)] = root(stubs[i].ID)
+				}
+			}
+		}
+	}
+
+	groups := make(map[uuid.UUID][]uuid.UUID)
+
+	for id := range parent {
+		groups[root(id)] = append(groups[root(id)], id)
+	}
+
+	results := make([][]uuid.UUID, 0, len(groups))
+
+	for r, ids := range groups {
+		if !slices.Contains(ids, r) {
+			ids = append(ids, r)
+		}
+
+		slices.SortFunc(ids, func(a, b uuid.UUID) int {
+			return bytes.Compare(a[:], b[:])
+		})
+
+		results = append(results, ids)
+	}
+
+	slices.SortFunc(results, func(a, b []uuid.UUID) int {
+		return bytes.Compare(a[0][:], b[0][:])
+	})
+
+	return results
+}
+
+// flattenContent returns the path and value pairs of the stub's headers,
+// input and output.
+func flattenContent(stub *Stub) map[string]struct{} {
+	pairs := make(map[string]struct{})
+
+	raw, err := json.Marshal(map[string]any{
+		"headers": stub.Headers,
+		"input":   stub.Input,
+		"output":  stub.Output,
+	})
+	if err != nil {
+		return pairs
+	}
+
+	var content any
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return pairs
+	}
+
+	flatten("", content, pairs)
+
+	return pairs
+}
+
+// flatten adds the path and value pairs of the leaves of the value.
+func flatten(path string, value any, pairs map[string]struct{}) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			flatten(path+"."+key, item, pairs)
+		}
+	case []any:
+		for i, item := range v {
+			flatten(path+"."+strconv.Itoa(i), item, pairs)
+		}
+	case nil:
+		// Absent and null values carry no content.
+	default:
+		pairs[fmt.Sprintf("%s=%v", path, v)] = struct{}{}
+	}
+}
+
+// jaccard returns the size of the intersection of the sets divided by the
+// size of their union, two empty sets are identical.
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	common := 0
+
+	for pair := range a {
+		if _, ok := b[pair]; ok {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(a)+len(b)-common)
+}
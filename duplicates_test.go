@@ -0,0 +1,51 @@
+package stuber_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_FindDuplicates(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(method, name, message string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  method,
+			Input: stuber.InputData{Equals: map[string]interface{}{
+				"name":   name,
+				"lang":   "en",
+				"region": "eu",
+			}},
+			Output: stuber.Output{Data: map[string]interface{}{"message": message, "code": 0}},
+		}
+	}
+
+	first := newStub("SayHello", "bob", "Hello")
+	second := newStub("SayHello", "bob", "Hello")
+	third := newStub("SayHello", "bob", "Hi")
+	other := newStub("SayHello", "alice", "Bye")
+	elsewhere := newStub("SayHi", "bob", "Hello")
+
+	s.PutMany(first, second, third, other, elsewhere)
+
+	sorted := func(ids ...uuid.UUID) []uuid.UUID {
+		slices.SortFunc(ids, func(a, b uuid.UUID) int {
+			return bytes.Compare(a[:], b[:])
+		})
+
+		return ids
+	}
+
+	require.Equal(t, [][]uuid.UUID{sorted(first.ID, second.ID)}, s.FindDuplicates(1))
+	require.Equal(t, [][]uuid.UUID{sorted(first.ID, second.ID, third.ID)}, s.FindDuplicates(0.6))
+	require.Equal(t, [][]uuid.UUID{sorted(first.ID, second.ID, third.ID, other.ID)}, s.FindDuplicates(0))
+}
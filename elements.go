@@ -0,0 +1,61 @@
+package stuber
+
+import (
+	"slices"
+
+	"github.com/gripmock/deeply"
+)
+
+// matchElements checks if the array fields of the query data contain every
+// element the stub's input data requires.
+//
+// Fields are dot-separated paths. The elements may be in any position and
+// the array may hold other elements too. A field that is absent or is not
+// an array does not match.
+func matchElements(input InputData, data map[string]any) bool {
+	return countElements(input, data) == requiredElements(input)
+}
+
+// rankElements returns the share of the required elements the array fields
+// of the query data contain.
+func rankElements(input InputData, data map[string]any) float64 {
+	required := requiredElements(input)
+	if required == 0 {
+		return 0
+	}
+
+	return float64(countElements(input, data)) / float64(required)
+}
+
+// requiredElements returns the number of elements the stub's input data requires.
+func requiredElements(input InputData) int {
+	n := 0
+	for _, elements := range input.Elements {
+		n += len(elements)
+	}
+
+	return n
+}
+
+// countElements returns the number of the required elements the array
+// fields of the query data contain.
+func countElements(input InputData, data map[string]any) int {
+	n := 0
+
+	for path, elements := range input.Elements {
+		value, _ := lookup(data, splitPath(path))
+
+		items, ok := value.([]any)
+		if !ok {
+			continue
+		}
+
+		for _, element := range elements {
+			if slices.ContainsFunc(items, func(item any) bool { return deeply.Equals(element, item) }) {
+				n++
+			}
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,62 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Elements(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Articles",
+		Method:  "Search",
+		Input: stuber.InputData{Elements: map[string][]interface{}{
+			"tags":       {"x", "z"},
+			"filter.ids": {float64(7)},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "tagged"}},
+	}
+
+	s.PutMany(stub)
+
+	ids := func(values ...float64) map[string]interface{} {
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = value
+		}
+
+		return map[string]interface{}{"ids": items}
+	}
+
+	tests := []struct {
+		data  map[string]interface{}
+		found bool
+	}{
+		{map[string]interface{}{"tags": []interface{}{"x", "y", "z"}, "filter": ids(7)}, true},
+		{map[string]interface{}{"tags": []interface{}{"z", "x"}, "filter": ids(1, 7)}, true},
+		{map[string]interface{}{"tags": []interface{}{"x", "y"}, "filter": ids(7)}, false},
+		{map[string]interface{}{"tags": "x z", "filter": ids(7)}, false},
+		{map[string]interface{}{"tags": []interface{}{"x", "z"}}, false},
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(stuber.Query{Service: "Articles", Method: "Search", Data: test.data})
+		if !test.found {
+			require.NoError(t, err)
+			require.Nil(t, r.Found())
+			require.Same(t, stub, r.Similar())
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+	}
+}
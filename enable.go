@@ -0,0 +1,47 @@
+package stuber
+
+import "github.com/google/uuid"
+
+// setEnabledWhere enables or disables all the stubs satisfying the predicate,
+// e.g. to disable all the error-path stubs of a scenario at once.
+//
+// Disabled stubs are stored but skipped by searches, they can still be found
+// by their ID. The stubs are replaced by modified copies under the write lock.
+// Stubs of locked services are left as they are. The predicate runs under
+// the write lock and must not call back into the searcher.
+//
+// Parameters:
+// - pred: The predicate selecting the stubs to toggle.
+// - enabled: Whether to enable or disable the stubs.
+//
+// Returns:
+// - int: The number of stubs whose state changed.
+func (s *searcher) setEnabledWhere(pred func(*Stub) bool, enabled bool) int {
+	s.mu.Lock()
+
+	var changed []*Stub
+
+	for _, stub := range s.all() {
+		if stub.Disabled != enabled || !pred(stub) || s.checkUnlocked(stub.Service) != nil {
+			continue
+		}
+
+		// Copy the stored stub, so readers holding the previous pointer are not affected.
+		toggled := *stub
+		toggled.Disabled = !enabled
+
+		changed = append(changed, &toggled)
+	}
+
+	var ids []uuid.UUID
+	if len(changed) > 0 {
+		ids = s.storage.upsert(s.castToValue(changed)...)
+		s.touch(ids...)
+	}
+
+	s.mu.Unlock()
+
+	s.subscribers.emit(ChangeUpdated, ids)
+
+	return len(ids)
+}
@@ -0,0 +1,64 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_SetEnabledWhere(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	ok := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Get",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"id": "1"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"status": "ok"}},
+	}
+	failure := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Get",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{"x-fail": "true"}},
+		Input:   stuber.InputData{Contains: map[string]interface{}{"id": "1"}},
+		Output:  stuber.Output{Error: "boom"},
+	}
+	locked := &stuber.Stub{ID: uuid.New(), Service: "Payments", Method: "Get", Output: stuber.Output{Error: "declined"}}
+
+	s.PutMany(ok, failure, locked)
+	s.LockService("Payments")
+
+	isError := func(stub *stuber.Stub) bool { return stub.Output.Error != "" }
+	query := stuber.Query{
+		Service: "Orders",
+		Method:  "Get",
+		Headers: map[string]interface{}{"x-fail": "true"},
+		Data:    map[string]interface{}{"id": "1"},
+	}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, failure, r.Found())
+
+	require.Equal(t, 1, s.SetEnabledWhere(isError, false))
+	require.Equal(t, 0, s.SetEnabledWhere(isError, false))
+	require.True(t, s.FindByID(failure.ID).Disabled)
+	require.False(t, failure.Disabled)
+	require.False(t, s.FindByID(locked.ID).Disabled)
+
+	// The disabled stub no longer competes.
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, ok, r.Found())
+
+	require.Equal(t, 1, s.SetEnabledWhere(func(*stuber.Stub) bool { return true }, true))
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Equal(t, failure.ID, r.Found().ID)
+}
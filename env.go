@@ -0,0 +1,160 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrUnresolvedEnv is returned when a stub references an environment
+// variable that is not set.
+var ErrUnresolvedEnv = errors.New("unresolved environment reference")
+
+// envRefPrefix starts every environment reference.
+const envRefPrefix = "${env:"
+
+// envRef matches an environment reference, e.g. "${env:EXPECTED_TENANT}".
+var envRef = regexp.MustCompile(`\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`) //nolint:gochecknoglobals
+
+// withEnv returns the input and header matchers of the stub with their
+// environment references replaced by the values of the variables.
+//
+// References are resolved once, when the stub is stored, so a stub keeps
+// matching the same values however the environment changes afterwards, and
+// exports carry the resolved values. A reference may be the whole expected
+// string or a part of it, the value is inserted as is, also into regular
+// expressions. Only string values are resolved, the keys are left as they
+// are. Matchers without references are returned unchanged, without copying.
+//
+// Returns:
+// - InputData: The input matchers with the references resolved.
+// - InputHeader: The header matchers with the references resolved.
+// - error: An error wrapping ErrUnresolvedEnv if a variable is not set.
+func (s *Stub) withEnv() (InputData, InputHeader, error) {
+	input, headers := s.Input, s.Headers
+
+	var errs [8]error
+
+	input.Equals, errs[0] = resolveEnvMap(input.Equals)
+	input.Contains, errs[1] = resolveEnvMap(input.Contains)
+	input.Matches, errs[2] = resolveEnvMap(input.Matches)
+	input.OneOf, errs[3] = resolveEnvMap(input.OneOf)
+	input.Elements, errs[4] = resolveEnvMap(input.Elements)
+
+	headers.Equals, errs[5] = resolveEnvMap(headers.Equals)
+	headers.Contains, errs[6] = resolveEnvMap(headers.Contains)
+	headers.Matches, errs[7] = resolveEnvMap(headers.Matches)
+
+	return input, headers, errors.Join(errs[:]...)
+}
+
+// resolveEnvMap resolves the environment references in the values of the
+// map. The map is copied only if one of its values has a reference.
+func resolveEnvMap[T any](m map[string]T) (map[string]T, error) {
+	var resolved map[string]T
+
+	for key, value := range m {
+		if !hasEnvRef(value) {
+			continue
+		}
+
+		v, err := resolveEnv(value)
+		if err != nil {
+			return m, err
+		}
+
+		if resolved == nil {
+			resolved = maps.Clone(m)
+		}
+
+		resolved[key] = v.(T) //nolint:forcetypeassert
+	}
+
+	if resolved == nil {
+		return m, nil
+	}
+
+	return resolved, nil
+}
+
+// hasEnvRef checks if the value holds a string with an environment reference,
+// recursing into maps and slices.
+func hasEnvRef(value any) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(v, envRefPrefix)
+	case map[string]any:
+		for _, item := range v {
+			if hasEnvRef(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if hasEnvRef(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// resolveEnv returns a copy of the value with the environment references of
+// its strings replaced, recursing into maps and slices.
+func resolveEnv(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expandEnv(v)
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+
+		for key, item := range v {
+			r, err := resolveEnv(item)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[key] = r
+		}
+
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+
+		for i, item := range v {
+			r, err := resolveEnv(item)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i] = r
+		}
+
+		return resolved, nil
+	}
+
+	return value, nil
+}
+
+// expandEnv replaces the environment references of the string with the values
+// of the variables.
+func expandEnv(s string) (string, error) {
+	var err error
+
+	expanded := envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("%w: %s", ErrUnresolvedEnv, name)
+		}
+
+		return value
+	})
+
+	return expanded, err
+}
@@ -0,0 +1,72 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_EnvReferences(t *testing.T) {
+	t.Setenv("STUBER_EXPECTED_TENANT", "acme")
+	t.Setenv("STUBER_EXPECTED_REGION", "eu")
+
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		Service: "Tenants",
+		Method:  "Get",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{"x-tenant": "${env:STUBER_EXPECTED_TENANT}"}},
+		Input: stuber.InputData{
+			Contains: map[string]interface{}{"region": "${env:STUBER_EXPECTED_REGION}", "zone": "zone-1"},
+			Matches:  map[string]interface{}{"name": "^${env:STUBER_EXPECTED_TENANT}-[0-9]+$"},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"ok": true}},
+	}
+
+	contains := stub.Input.Contains
+
+	require.Len(t, s.PutMany(stub), 1)
+
+	// The references are resolved on insert, without modifying the caller's maps.
+	require.Equal(t, "eu", s.FindByID(stub.ID).Input.Contains["region"])
+	require.Equal(t, "${env:STUBER_EXPECTED_REGION}", contains["region"])
+
+	query := stuber.Query{
+		Service: "Tenants",
+		Method:  "Get",
+		Headers: map[string]interface{}{"x-tenant": "acme"},
+		Data:    map[string]interface{}{"region": "eu", "zone": "zone-1", "name": "acme-42"},
+	}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+
+	// Changing the environment afterwards does not affect the stored stub.
+	t.Setenv("STUBER_EXPECTED_REGION", "us")
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+
+	// References to unset variables are rejected, nothing is inserted.
+	unset := &stuber.Stub{
+		Service: "Tenants",
+		Method:  "List",
+		Input:   stuber.InputData{OneOf: map[string][]any{"region": {"eu", "${env:STUBER_UNSET_REGION}"}}},
+		Output:  stuber.Output{Data: map[string]interface{}{}},
+	}
+
+	ids, err := s.PutManyE(unset, &stuber.Stub{Service: "Tenants", Method: "List", Output: stuber.Output{Error: "boom"}})
+	require.ErrorIs(t, err, stuber.ErrUnresolvedEnv)
+	require.Nil(t, ids)
+	require.Len(t, s.All(), 1)
+
+	err = s.Import([]byte(`[{"service":"Tenants","method":"List",` +
+		`"input":{"contains":{"region":"${env:STUBER_UNSET_REGION}"}},"output":{"data":{}}}]`))
+	require.ErrorIs(t, err, stuber.ErrUnresolvedEnv)
+	require.ErrorContains(t, err, "STUBER_UNSET_REGION")
+}
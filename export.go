@@ -0,0 +1,204 @@
+package stuber
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// export serializes all Stub values stored in the searcher into a JSON array.
+//
+// The stubs are sorted by service, method and ID, so identical stub sets
+// always produce identical payloads.
+//
+// Returns:
+// - []byte: The JSON array of Stub values.
+// - error: An error if a Stub value cannot be serialized.
+func (s *searcher) export() ([]byte, error) {
+	stubs := s.all()
+
+	// Sort the stubs to make the payload stable.
+	slices.SortFunc(stubs, compareStubs)
+
+	return json.Marshal(stubs)
+}
+
+// exportTo writes all Stub values stored in the searcher to the writer as
+// newline-delimited JSON, one Stub value per line.
+//
+// The stubs are written one at a time in the order of export, so the
+// payload is never held in memory as a whole.
+//
+// Parameters:
+// - w: The writer receiving the Stub values.
+//
+// Returns:
+// - error: An error if a Stub value cannot be serialized or written.
+func (s *searcher) exportTo(w io.Writer) error {
+	stubs := s.all()
+
+	// Sort the stubs to make the payload stable.
+	slices.SortFunc(stubs, compareStubs)
+
+	encoder := json.NewEncoder(w)
+
+	for _, stub := range stubs {
+		if err := encoder.Encode(stub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importFrom reads newline-delimited JSON Stub values from the reader and
+// inserts each of them as soon as it is read.
+//
+// Unlike importJSON it is not transactional: reading stops at the first
+// Stub value that cannot be parsed, is invalid or belongs to a locked
+// service, and the Stub values read before it stay inserted.
+//
+// Parameters:
+// - r: The reader providing the Stub values.
+//
+// Returns:
+// - error: An error naming the line of the first Stub value that could not be loaded.
+func (s *searcher) importFrom(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	for line := 1; ; line++ {
+		var stub Stub
+
+		if err := decoder.Decode(&stub); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err := stub.Validate(); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if stub.Key() == uuid.Nil {
+			stub.ID = s.newID(&stub)
+		}
+
+		if _, err := s.upsert(&stub); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// exportByService serializes the Stub values stored in the searcher into one
+// JSON array per service.
+//
+// Every payload has the same form as the export payload and can be imported
+// on its own. Services without stubs are omitted.
+//
+// Returns:
+// - map[string][]byte: The JSON arrays of Stub values, keyed by service.
+// - error: An error if a Stub value cannot be serialized.
+func (s *searcher) exportByService() (map[string][]byte, error) {
+	stubs := s.all()
+
+	// Sort the stubs to make the payloads stable.
+	slices.SortFunc(stubs, compareStubs)
+
+	byService := make(map[string][]*Stub)
+	for _, stub := range stubs {
+		byService[stub.Service] = append(byService[stub.Service], stub)
+	}
+
+	payloads := make(map[string][]byte, len(byService))
+
+	for service, group := range byService {
+		payload, err := json.Marshal(group)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", service, err)
+		}
+
+		payloads[service] = payload
+	}
+
+	return payloads, nil
+}
+
+// importJSON parses a JSON array of Stub values and loads them into the searcher.
+//
+// Parameters:
+// - data: The JSON array of Stub values.
+//
+// Returns:
+// - error: An error if the payload cannot be parsed or any Stub value is invalid.
+func (s *searcher) importJSON(data []byte) error {
+	var stubs []*Stub
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&stubs); err != nil {
+		return err
+	}
+
+	return s.load(stubs)
+}
+
+// load validates the given Stub values and inserts them into the searcher.
+//
+// Loading is transactional: every Stub value is validated first, and if any
+// of them is invalid nothing is inserted. The returned error joins the
+// validation errors of all invalid Stub values, each prefixed with its index.
+// Stub values without a key get a new UUID. Nothing is inserted if any Stub
+// value belongs to a locked service.
+//
+// Parameters:
+// - stubs: The Stub values to load.
+//
+// Returns:
+// - error: The joined validation errors, or nil if all Stub values were loaded.
+func (s *searcher) load(stubs []*Stub) error {
+	var errs []error
+
+	// Validate all the Stub values before touching the storage.
+	for i, stub := range stubs {
+		if stub == nil {
+			stub = &Stub{}
+		}
+
+		if err := stub.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("stub %d: %w", i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Generate a new UUID for the Stub values that do not have a key.
+	for _, stub := range stubs {
+		if stub.Key() == uuid.Nil {
+			stub.ID = s.newID(stub)
+		}
+	}
+
+	// Insert all the Stub values under a single write lock.
+	_, err := s.upsert(stubs...)
+
+	return err
+}
+
+// compareStubs orders Stub values by service, method and ID.
+func compareStubs(a, b *Stub) int {
+	return cmp.Or(
+		cmp.Compare(a.Service, b.Service),
+		cmp.Compare(a.Method, b.Method),
+		bytes.Compare(a.ID[:], b.ID[:]),
+	)
+}
@@ -0,0 +1,347 @@
+package stuber_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ExportImport(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter2",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "hello Bob"}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Output:  stuber.Output{Error: "boom"},
+		},
+	)
+
+	payload, err := s.Export()
+	require.NoError(t, err)
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.Import(payload))
+	require.Len(t, restored.All(), 2)
+	require.Equal(t, s.ETag(), restored.ETag())
+
+	again, err := restored.Export()
+	require.NoError(t, err)
+	require.JSONEq(t, string(payload), string(again))
+}
+
+func TestBudgerigar_ImportTransactional(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	payload := `[
+		{"service":"Greeter1","method":"SayHello1","output":{"data":{"message":"ok"}}},
+		{"service":"","method":"SayHello1","output":{"data":{"message":"no service"}}},
+		{"service":"Greeter1","method":"SayHello1","input":{"matches":{"name":"("}},"output":{"data":{}}},
+		{"service":"Greeter1","method":"SayHello1","output":{}}
+	]`
+
+	err := s.Import([]byte(payload))
+	require.Error(t, err)
+	require.ErrorIs(t, err, stuber.ErrServiceEmpty)
+	require.ErrorIs(t, err, stuber.ErrOutputEmpty)
+	require.NotContains(t, err.Error(), "stub 0")
+	require.Contains(t, err.Error(), "stub 1")
+	require.Contains(t, err.Error(), "stub 2")
+	require.Contains(t, err.Error(), "stub 3")
+	require.Empty(t, s.All())
+
+	require.Error(t, s.Import([]byte(`{`)))
+	require.Empty(t, s.All())
+
+	require.NoError(t, s.Import([]byte(`[{"service":"Greeter1","method":"SayHello1","output":{"data":{}}}]`)))
+	require.Len(t, s.All(), 1)
+	require.NotEqual(t, uuid.Nil, s.All()[0].ID)
+}
+
+func TestBudgerigar_ImportResolve(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	payload := `[
+		{"service":"Users","method":"Get","input":{"equals":{"id":"1"}},"output":{"data":{"team":"a"}},"priority":1},
+		{"service":"Users","method":"Get","input":{"equals":{"id":"1"}},"output":{"data":{"team":"b"}},"priority":5},
+		{"service":"Users","method":"Get","input":{"equals":{"id":"1"}},"output":{"data":{"team":"c"}},"priority":5},
+		{"service":"Users","method":"Get","input":{"equals":{"id":"2"}},"output":{"data":{"team":"a"}}},
+		{"service":"Users","method":"List","input":{"equals":{"id":"1"}},"output":{"data":{"team":"a"}}}
+	]`
+
+	dropped, err := s.ImportResolve([]byte(payload))
+	require.NoError(t, err)
+	require.Len(t, dropped, 2)
+	require.Equal(t, map[string]interface{}{"team": "a"}, dropped[0].Output.Data)
+	require.Equal(t, map[string]interface{}{"team": "c"}, dropped[1].Output.Data)
+	require.Len(t, s.All(), 3)
+
+	// The conflicting stub with the highest priority wins, the first one on a tie.
+	r, err := s.FindByQuery(stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{"id": "1"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"team": "b"}, r.Output().Data)
+
+	// Nothing is loaded if a kept stub is invalid.
+	s.Clear()
+
+	dropped, err = s.ImportResolve([]byte(`[
+		{"service":"Users","method":"Get","output":{"data":{}},"priority":1},
+		{"service":"Users","method":"Get","output":{}, "priority":2}
+	]`))
+	require.ErrorIs(t, err, stuber.ErrOutputEmpty)
+	require.Nil(t, dropped)
+	require.Empty(t, s.All())
+
+	_, err = s.ImportResolve([]byte(`{`))
+	require.Error(t, err)
+}
+
+func TestBudgerigar_ExportByService(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(
+		&stuber.Stub{ID: uuid.New(), Service: "Greeter1", Method: "SayHello1", Output: stuber.Output{Error: "boom"}},
+		&stuber.Stub{ID: uuid.New(), Service: "Greeter1", Method: "SayHello2", Output: stuber.Output{Error: "boom"}},
+		&stuber.Stub{ID: uuid.New(), Service: "Greeter2", Method: "SayHello1", Output: stuber.Output{Error: "boom"}},
+	)
+
+	payloads, err := s.ExportByService()
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+
+	restored := stuber.NewBudgerigar(features.New())
+
+	for service, payload := range payloads {
+		single := stuber.NewBudgerigar(features.New())
+		require.NoError(t, single.Import(payload))
+
+		for _, stub := range single.All() {
+			require.Equal(t, service, stub.Service)
+		}
+
+		require.NoError(t, restored.Import(payload))
+	}
+
+	require.Equal(t, s.ETag(), restored.ETag())
+
+	empty, err := stuber.NewBudgerigar(features.New()).ExportByService()
+	require.NoError(t, err)
+	require.Empty(t, empty)
+}
+
+func TestBudgerigar_ResponseMetadata(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(&stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output: stuber.Output{
+			Headers:  map[string]string{"x-ratelimit-remaining": "9"},
+			Trailers: map[string]string{"x-checksum": "abc"},
+			Data:     map[string]interface{}{"message": "Hello"},
+		},
+	})
+
+	payload, err := s.Export()
+	require.NoError(t, err)
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.Import(payload))
+
+	r, err := restored.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"x-ratelimit-remaining": "9"}, r.ResponseHeaders())
+	require.Equal(t, map[string]string{"x-checksum": "abc"}, r.ResponseTrailers())
+}
+
+func TestBudgerigar_ExportToImportFrom(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(
+		&stuber.Stub{ID: uuid.New(), Service: "Greeter1", Method: "SayHello1", Output: stuber.Output{Error: "boom"}},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter2",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "hello Bob"}},
+		},
+	)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, s.ExportTo(&buf))
+	require.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.ImportFrom(&buf))
+	require.Equal(t, s.ETag(), restored.ETag())
+
+	// Loading stops at the first invalid line and keeps the earlier ones.
+	partial := stuber.NewBudgerigar(features.New())
+	err := partial.ImportFrom(strings.NewReader(
+		`{"service":"Greeter1","method":"SayHello1","output":{"data":{}}}` + "\n" +
+			`{"service":"","method":"SayHello1","output":{"data":{}}}` + "\n" +
+			`{"service":"Greeter1","method":"SayHello2","output":{"data":{}}}` + "\n",
+	))
+	require.ErrorIs(t, err, stuber.ErrServiceEmpty)
+	require.Contains(t, err.Error(), "line 2")
+	require.Len(t, partial.All(), 1)
+
+	require.Error(t, partial.ImportFrom(strings.NewReader(`{`)))
+}
+
+func TestBudgerigar_ExportImportBinary(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	matched := &stuber.Stub{
+		ID:       uuid.New(),
+		Service:  "Greeter2",
+		Method:   "SayHello1",
+		Input:    stuber.InputData{Equals: map[string]interface{}{"name": "Bob", "tags": []interface{}{"a", 1.5, true}}},
+		Output:   stuber.Output{Data: map[string]interface{}{"message": "hello Bob", "count": json.Number("42")}},
+		Matcher:  stuber.MatcherFunc(func(stuber.Query) bool { return true }),
+		Trailers: map[string]string{"x-checksum": "abc"},
+	}
+
+	s.PutMany(
+		matched,
+		&stuber.Stub{ID: uuid.New(), Service: "Greeter1", Method: "SayHello1", Output: stuber.Output{Error: "boom"}},
+	)
+
+	payload, err := s.ExportBinary()
+	require.NoError(t, err)
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.ImportBinary(payload))
+
+	expected, err := s.Export()
+	require.NoError(t, err)
+
+	actual, err := restored.Export()
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+	require.Equal(t, s.ETag(), restored.ETag())
+	require.Nil(t, restored.FindByID(matched.ID).Matcher)
+
+	require.ErrorIs(t, restored.ImportBinary([]byte("not a payload")), stuber.ErrUnsupportedVersion)
+	require.ErrorIs(t, restored.ImportBinary(payload[:len(payload)-1]), io.ErrUnexpectedEOF)
+}
+
+func TestBudgerigar_ExportImportBinary_Values(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	// Empty maps and slices stay empty, nil ones stay nil, and the dynamic
+	// values keep their types, but for the ones JSON does not decode into.
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input: stuber.InputData{
+			Equals:   map[string]interface{}{},
+			Contains: map[string]interface{}{"tags": []interface{}{}, "meta": map[string]interface{}(nil)},
+		},
+		Output: stuber.Output{
+			Headers: map[string]string{},
+			Data: map[string]interface{}{
+				"count": 42,
+				"total": int64(7),
+				"ratio": 0.5,
+				"names": []string{"a", "b"},
+				"empty": map[string]interface{}{},
+			},
+		},
+		Methods: []string{},
+	}
+
+	// A stub without output is stored by a write, so it is restored as well.
+	s.PutMany(stub, &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHi"})
+
+	payload, err := s.ExportBinary()
+	require.NoError(t, err)
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.ImportBinary(payload))
+	require.Len(t, restored.All(), 2)
+
+	actual := restored.FindByID(stub.ID)
+	require.NotNil(t, actual.Input.Equals)
+	require.Empty(t, actual.Input.Equals)
+	require.Nil(t, actual.Input.Matches)
+	require.Equal(t, map[string]interface{}{"tags": []interface{}{}, "meta": map[string]interface{}(nil)}, actual.Input.Contains)
+	require.NotNil(t, actual.Output.Headers)
+	require.NotNil(t, actual.Methods)
+	require.Nil(t, actual.Occurrences)
+	require.Equal(t, map[string]interface{}{
+		"count": 42,
+		"total": int64(7),
+		"ratio": 0.5,
+		"names": []interface{}{"a", "b"},
+		"empty": map[string]interface{}{},
+	}, actual.Output.Data)
+	require.True(t, stub.CreatedAt.Equal(actual.CreatedAt))
+}
+
+func benchmarkStubs(n int) *stuber.Budgerigar {
+	s := stuber.NewBudgerigar(features.New())
+
+	for i := range n {
+		s.PutMany(&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  "SayHello" + strconv.Itoa(i%100),
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob", "id": float64(i)}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello", "tags": []interface{}{"a", "b"}}},
+		})
+	}
+
+	return s
+}
+
+func BenchmarkImport(b *testing.B) {
+	s := benchmarkStubs(1000)
+
+	jsonPayload, err := s.Export()
+	require.NoError(b, err)
+
+	binaryPayload, err := s.ExportBinary()
+	require.NoError(b, err)
+
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for range b.N {
+			if err := stuber.NewBudgerigar(features.New()).Import(jsonPayload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for range b.N {
+			if err := stuber.NewBudgerigar(features.New()).ImportBinary(binaryPayload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
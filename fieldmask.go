@@ -0,0 +1,179 @@
+package stuber
+
+import (
+	"slices"
+	"strings"
+)
+
+// maskNode is a level of a field mask, keyed by field name. A nil node
+// selects the whole value of its field.
+type maskNode map[string]maskNode
+
+// newMask builds the tree of the dot-separated field mask paths.
+//
+// A path selecting a field also selects everything below it, so a longer
+// path under an already selected field has no effect.
+func newMask(paths []string) maskNode {
+	root := maskNode{}
+
+	for _, path := range paths {
+		node := root
+		segments := strings.Split(path, ".")
+
+		for i, segment := range segments {
+			child, ok := node[segment]
+
+			// Stop if the field is already selected as a whole.
+			if ok && child == nil {
+				break
+			}
+
+			if i == len(segments)-1 {
+				node[segment] = nil
+
+				break
+			}
+
+			if !ok {
+				child = maskNode{}
+				node[segment] = child
+			}
+
+			node = child
+		}
+	}
+
+	return root
+}
+
+// covers checks if the mask selects the whole value at the path.
+func (m maskNode) covers(path string) bool {
+	node := m
+
+	for _, segment := range strings.Split(path, ".") {
+		child, ok := node[segment]
+		if !ok {
+			return false
+		}
+
+		if child == nil {
+			return true
+		}
+
+		node = child
+	}
+
+	return false
+}
+
+// apply returns the fields of the data the mask selects.
+//
+// Objects left without any selected field are dropped, so data outside the
+// mask is indistinguishable from absent data.
+func (m maskNode) apply(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+
+	result := make(map[string]any, len(m))
+
+	for key, child := range m {
+		value, ok := data[key]
+		if !ok {
+			continue
+		}
+
+		if child == nil {
+			result[key] = value
+
+			continue
+		}
+
+		if nested, ok := value.(map[string]any); ok {
+			if masked := child.apply(nested); len(masked) > 0 {
+				result[key] = masked
+			}
+		}
+	}
+
+	return result
+}
+
+// applyConditions returns the conditions with their data restricted to the mask.
+func (m maskNode) applyConditions(conditions []Condition) []Condition {
+	if conditions == nil {
+		return nil
+	}
+
+	result := make([]Condition, len(conditions))
+
+	for i, condition := range conditions {
+		result[i] = Condition{
+			Equals:   m.apply(condition.Equals),
+			Contains: m.apply(condition.Contains),
+			Matches:  m.apply(condition.Matches),
+			All:      m.applyConditions(condition.All),
+			Any:      m.applyConditions(condition.Any),
+		}
+	}
+
+	return result
+}
+
+// maskPaths returns the entries of a map keyed by dot-separated paths whose
+// path lies within the mask.
+func maskPaths[V any](m maskNode, entries map[string]V) map[string]V {
+	if entries == nil {
+		return nil
+	}
+
+	result := make(map[string]V, len(entries))
+
+	for path, value := range entries {
+		if m.covers(path) {
+			result[path] = value
+		}
+	}
+
+	return result
+}
+
+// withFieldMask restricts the query data and the stub's input data to the
+// query's field mask, so fields outside the mask are ignored on both sides.
+//
+// The stub is copied rather than modified. The query and the stub are
+// returned as is if the query carries no field mask.
+func withFieldMask(query Query, stub *Stub) (Query, *Stub) {
+	if len(query.FieldMask) == 0 {
+		return query, stub
+	}
+
+	mask := newMask(query.FieldMask)
+
+	query.Data = mask.apply(query.Data)
+
+	masked := *stub
+	input := &masked.Input
+
+	input.Equals = mask.apply(input.Equals)
+	input.Contains = mask.apply(input.Contains)
+	input.Matches = mask.apply(input.Matches)
+	input.Defaults = mask.apply(input.Defaults)
+	input.Any = mask.applyConditions(input.Any)
+	input.Items = maskPaths(mask, input.Items)
+	input.Times = maskPaths(mask, input.Times)
+	input.Elements = maskPaths(mask, input.Elements)
+	input.OneOf = maskPaths(mask, input.OneOf)
+	input.Keys = maskPaths(mask, input.Keys)
+	input.TypeOf = maskPaths(mask, input.TypeOf)
+	input.NotEquals = maskPaths(mask, input.NotEquals)
+
+	return query, &masked
+}
+
+// validFieldMask checks that no path of the field mask has an empty segment.
+func validFieldMask(paths []string) bool {
+	return !slices.ContainsFunc(paths, func(path string) bool {
+		return slices.Contains(strings.Split(path, "."), "")
+	})
+}
@@ -0,0 +1,78 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_FieldMask(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Update",
+		Input: stuber.InputData{
+			Equals: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":    "Alice",
+					"email":   "alice@example.com",
+					"address": map[string]interface{}{"city": "Paris", "zip": "75001"},
+				},
+			},
+			OneOf: map[string][]interface{}{"user.role": {"admin"}},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "updated"}},
+	}
+
+	s.PutMany(stub)
+
+	query := func(mask ...string) stuber.Query {
+		return stuber.Query{Service: "Users", Method: "Update", FieldMask: mask, Data: map[string]interface{}{
+			"user": map[string]interface{}{
+				"name":    "Alice",
+				"email":   "other@example.com",
+				"address": map[string]interface{}{"city": "Paris", "zip": "10115"},
+			},
+		}}
+	}
+
+	// Without a mask, the differing fields and the missing role fail the match.
+	r, err := s.FindByQuery(query())
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, stub, r.Similar())
+
+	for _, mask := range [][]string{
+		{"user.name"},
+		{"user.name", "user.address.city"},
+		{"user.address.city"},
+		{"user.address.city", "user.address.city.code"},
+		{"user.nickname"},
+	} {
+		r, err := s.FindByQuery(query(mask...))
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found(), mask)
+	}
+
+	for _, mask := range [][]string{
+		{"user.email"},
+		{"user.address"},
+		{"user.name", "user.address.zip"},
+		{"user.role"},
+		{"user"},
+	} {
+		r, err := s.FindByQuery(query(mask...))
+		require.NoError(t, err)
+		require.Nil(t, r.Found(), mask)
+	}
+
+	// The stored stub is not affected by the mask.
+	require.Len(t, s.FindByID(stub.ID).Input.Equals["user"], 3)
+	require.Len(t, s.FindByID(stub.ID).Input.OneOf, 1)
+}
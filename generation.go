@@ -0,0 +1,120 @@
+package stuber
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// touch starts a new generation and records it as the modification
+// generation of the stubs with the given IDs. The caller holds the write lock.
+//
+// The IDs are removed from the deletion log, since the stubs exist again.
+func (s *searcher) touch(ids ...uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	s.generation++
+
+	for _, id := range ids {
+		s.modGeneration[id] = s.generation
+		delete(s.tombstones, id)
+	}
+
+	// A changed stub gets another chance to match.
+	s.quarantine.release(ids...)
+	s.celPrograms.release(ids...)
+	s.exprNodes.release(ids...)
+}
+
+// bury starts a new generation and records it as the deletion generation of
+// the stubs with the given IDs. The caller holds the write lock.
+func (s *searcher) bury(ids ...uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	s.generation++
+
+	for _, id := range ids {
+		s.tombstones[id] = s.generation
+		delete(s.modGeneration, id)
+	}
+
+	s.quarantine.release(ids...)
+	s.celPrograms.release(ids...)
+	s.exprNodes.release(ids...)
+}
+
+// currentGeneration returns the generation of the last change of the stub set.
+//
+// Every write starts a new generation, a searcher without writes is at
+// generation zero.
+//
+// Returns:
+// - uint64: The current generation.
+func (s *searcher) currentGeneration() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.generation
+}
+
+// changedSince returns the changes of the stub set after the given
+// generation, e.g. to sync a remote copy incrementally.
+//
+// A client starts from generation zero, applies the returned changes and
+// asks for the next ones with the current generation read beforehand. The
+// deleted IDs are kept for as long as the searcher lives, one entry per ID,
+// so any past generation can be asked for.
+//
+// Parameters:
+// - gen: The generation the caller has already seen.
+//
+// Returns:
+// - []*Stub: The stubs added or updated after the generation, sorted by generation.
+// - []uuid.UUID: The IDs of the stubs deleted after the generation, sorted by generation.
+func (s *searcher) changedSince(gen uint64) ([]*Stub, []uuid.UUID) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changed := make([]*Stub, 0)
+
+	for id, modified := range s.modGeneration {
+		if modified <= gen {
+			continue
+		}
+
+		if stub := s.findByID(id); stub != nil {
+			changed = append(changed, stub)
+		}
+	}
+
+	slices.SortFunc(changed, func(a, b *Stub) int {
+		if c := cmp.Compare(s.modGeneration[a.ID], s.modGeneration[b.ID]); c != 0 {
+			return c
+		}
+
+		return slices.Compare(a.ID[:], b.ID[:])
+	})
+
+	deleted := make([]uuid.UUID, 0)
+
+	for id, removed := range s.tombstones {
+		if removed > gen {
+			deleted = append(deleted, id)
+		}
+	}
+
+	slices.SortFunc(deleted, func(a, b uuid.UUID) int {
+		if c := cmp.Compare(s.tombstones[a], s.tombstones[b]); c != 0 {
+			return c
+		}
+
+		return slices.Compare(a[:], b[:])
+	})
+
+	return changed, deleted
+}
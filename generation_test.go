@@ -0,0 +1,71 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ChangedSince(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	require.Zero(t, s.Generation())
+
+	first := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "first"}}
+	second := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHi", Output: stuber.Output{Error: "second"}}
+	third := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayBye", Output: stuber.Output{Error: "third"}}
+
+	s.PutMany(first, second)
+
+	added := s.Generation()
+	require.NotZero(t, added)
+
+	changed, deleted := s.ChangedSince(0)
+	require.ElementsMatch(t, []*stuber.Stub{first, second}, changed)
+	require.Empty(t, deleted)
+
+	changed, deleted = s.ChangedSince(added)
+	require.Empty(t, changed)
+	require.Empty(t, deleted)
+
+	// Adds, updates and deletes after the generation are reported in order.
+	s.PutMany(third)
+	require.NoError(t, s.MergeByID(first.ID, &stuber.Stub{Output: stuber.Output{Error: "patched"}}))
+	require.Equal(t, 1, s.DeleteByID(second.ID))
+
+	changed, deleted = s.ChangedSince(added)
+	require.Len(t, changed, 2)
+	require.Same(t, third, changed[0])
+	require.Equal(t, "patched", changed[1].Output.Error)
+	require.Equal(t, []uuid.UUID{second.ID}, deleted)
+
+	// A deleted stub added again is reported as changed only.
+	deletedAt := s.Generation()
+
+	s.PutMany(second)
+
+	changed, deleted = s.ChangedSince(added)
+	require.Len(t, changed, 3)
+	require.Empty(t, deleted)
+
+	changed, deleted = s.ChangedSince(deletedAt)
+	require.Equal(t, []*stuber.Stub{second}, changed)
+	require.Empty(t, deleted)
+
+	// Clearing deletes every stub.
+	cleared := s.Generation()
+
+	s.Clear()
+
+	changed, deleted = s.ChangedSince(cleared)
+	require.Empty(t, changed)
+	require.ElementsMatch(t, []uuid.UUID{first.ID, second.ID, third.ID}, deleted)
+
+	changed, deleted = s.ChangedSince(s.Generation())
+	require.Empty(t, changed)
+	require.Empty(t, deleted)
+}
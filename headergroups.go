@@ -0,0 +1,53 @@
+package stuber
+
+import "encoding/json"
+
+// headerConstraints is the canonical form of the header matchers of a stub.
+type headerConstraints struct {
+	Equals   map[string]any `json:"equals,omitempty"`
+	Contains map[string]any `json:"contains,omitempty"`
+	Matches  map[string]any `json:"matches,omitempty"`
+	Exact    bool           `json:"exact,omitempty"`
+}
+
+// groupByHeaderConstraints groups the stubs of the service and method by
+// their header matchers, e.g. to audit the body variants of the responses
+// gated by the same auth headers.
+//
+// The signature of a group is the JSON of the stub's exact, partial and
+// regular expression header matchers, with sorted keys, and whether the stub
+// rejects undeclared headers. Unlike a matcher signature it includes the
+// expected values, so "x-role: admin" and "x-role: user" are different
+// groups. Stubs without header matchers share the signature "{}".
+//
+// Parameters:
+// - service: The service of the stubs.
+// - method: The method of the stubs.
+//
+// Returns:
+// - map[string][]*Stub: The stubs keyed by their header signature, in storage order.
+// - error: ErrServiceNotFound or ErrMethodNotFound if there are no such stubs.
+func (s *searcher) groupByHeaderConstraints(service, method string) (map[string][]*Stub, error) {
+	stubs, err := s.findBy(service, method)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]*Stub)
+
+	for _, stub := range stubs {
+		raw, err := json.Marshal(headerConstraints{
+			Equals:   stub.Headers.Equals,
+			Contains: stub.Headers.Contains,
+			Matches:  stub.Headers.Matches,
+			Exact:    stub.HeadersExact,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		groups[string(raw)] = append(groups[string(raw)], stub)
+	}
+
+	return groups, nil
+}
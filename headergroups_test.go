@@ -0,0 +1,53 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_GroupByHeaderConstraints(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(role string, input map[string]interface{}) *stuber.Stub {
+		stub := &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Accounts",
+			Method:  "Get",
+			Input:   stuber.InputData{Contains: input},
+			Output:  stuber.Output{Error: role},
+		}
+
+		if role != "" {
+			stub.Headers = stuber.InputHeader{Equals: map[string]interface{}{"x-role": role}}
+		}
+
+		return stub
+	}
+
+	adminMain := newStub("admin", map[string]interface{}{"account": "main"})
+	adminSavings := newStub("admin", map[string]interface{}{"account": "savings"})
+	user := newStub("user", map[string]interface{}{"account": "main"})
+	anonymous := newStub("", map[string]interface{}{"account": "main"})
+	other := &stuber.Stub{ID: uuid.New(), Service: "Accounts", Method: "List", Output: stuber.Output{Error: "list"}}
+
+	s.PutMany(adminMain, adminSavings, user, anonymous, other)
+
+	groups, err := s.GroupByHeaderConstraints("Accounts", "Get")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]*stuber.Stub{
+		`{"equals":{"x-role":"admin"}}`: {adminMain, adminSavings},
+		`{"equals":{"x-role":"user"}}`:  {user},
+		`{}`:                            {anonymous},
+	}, groups)
+
+	_, err = s.GroupByHeaderConstraints("Accounts", "Delete")
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+
+	_, err = s.GroupByHeaderConstraints("Users", "Get")
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+}
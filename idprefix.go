@@ -0,0 +1,55 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrAmbiguousPrefix is returned when an ID prefix expected to identify a
+// single stub matches several.
+var ErrAmbiguousPrefix = errors.New("ambiguous id prefix")
+
+// findByIDPrefix returns the Stub values whose ID starts with the given
+// prefix, like a short commit hash.
+//
+// The prefix is compared case-insensitively against the canonical form of the
+// IDs, hyphens included. The stubs are sorted by ID.
+//
+// Parameters:
+// - prefix: The beginning of the ID.
+// - unique: Whether the prefix must identify a single Stub value.
+//
+// Returns:
+// - []*Stub: The Stub values whose ID starts with the prefix.
+// - error: ErrStubNotFound if the prefix is empty or matches no Stub value,
+// or an error wrapping ErrAmbiguousPrefix if unique is set and it matches
+// several.
+func (s *searcher) findByIDPrefix(prefix string, unique bool) ([]*Stub, error) {
+	prefix = strings.ToLower(prefix)
+	if prefix == "" {
+		return nil, ErrStubNotFound
+	}
+
+	var results []*Stub
+
+	for _, stub := range s.all() {
+		if strings.HasPrefix(stub.ID.String(), prefix) {
+			results = append(results, stub)
+		}
+	}
+
+	switch {
+	case len(results) == 0:
+		return nil, ErrStubNotFound
+	case unique && len(results) > 1:
+		return nil, fmt.Errorf("%w: %q matches %d stubs", ErrAmbiguousPrefix, prefix, len(results))
+	}
+
+	slices.SortFunc(results, func(a, b *Stub) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return results, nil
+}
@@ -0,0 +1,46 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_FindByIDPrefix(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	first := &stuber.Stub{
+		ID:      uuid.MustParse("3f2a1c00-0000-4000-8000-000000000001"),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output:  stuber.Output{Error: "boom"},
+	}
+	second := &stuber.Stub{
+		ID:      uuid.MustParse("3f2b9e00-0000-4000-8000-000000000002"),
+		Service: "Greeter",
+		Method:  "SayHi",
+		Output:  stuber.Output{Error: "boom"},
+	}
+
+	s.PutMany(second, first)
+
+	stubs, err := s.FindByIDPrefix("3F2A", true)
+	require.NoError(t, err)
+	require.Equal(t, []*stuber.Stub{first}, stubs)
+
+	stubs, err = s.FindByIDPrefix("3f2", false)
+	require.NoError(t, err)
+	require.Equal(t, []*stuber.Stub{first, second}, stubs)
+
+	_, err = s.FindByIDPrefix("3f2", true)
+	require.ErrorIs(t, err, stuber.ErrAmbiguousPrefix)
+
+	for _, prefix := range []string{"4", ""} {
+		_, err = s.FindByIDPrefix(prefix, false)
+		require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	}
+}
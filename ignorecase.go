@@ -0,0 +1,61 @@
+package stuber
+
+import "strings"
+
+// withIgnoreCase lowercases the strings of the fields the stub compares
+// case-insensitively, in both the query data and the stub's input data.
+//
+// The exact, partial and default inputs and the allowed values are folded,
+// regular expressions are left as they are. The query and the stub are
+// copied rather than modified, and returned as is if the stub has no
+// case-insensitive fields.
+func withIgnoreCase(query Query, stub *Stub) (Query, *Stub) {
+	if len(stub.Input.IgnoreCase) == 0 {
+		return query, stub
+	}
+
+	folded := *stub
+	input := &folded.Input
+
+	for path, ignore := range stub.Input.IgnoreCase {
+		if !ignore {
+			continue
+		}
+
+		segments := splitPath(path)
+
+		query.Data = transformAt(query.Data, segments, fold)
+		input.Equals = transformAt(input.Equals, segments, fold)
+		input.Contains = transformAt(input.Contains, segments, fold)
+		input.Defaults = transformAt(input.Defaults, segments, fold)
+		input.OneOf = transformOneOf(input.OneOf, path, fold)
+	}
+
+	return query, &folded
+}
+
+// fold lowercases the strings of the value, recursing into maps and slices.
+func fold(value any) any {
+	switch v := value.(type) {
+	case string:
+		return strings.ToLower(v)
+	case map[string]any:
+		result := make(map[string]any, len(v))
+
+		for key, item := range v {
+			result[key] = fold(item)
+		}
+
+		return result
+	case []any:
+		result := make([]any, len(v))
+
+		for i, item := range v {
+			result[i] = fold(item)
+		}
+
+		return result
+	default:
+		return value
+	}
+}
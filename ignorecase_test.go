@@ -0,0 +1,71 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_IgnoreCase(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Find",
+		Input: stuber.InputData{
+			Contains: map[string]interface{}{
+				"country": "US",
+				"name":    "Alice",
+				"contact": map[string]interface{}{"email": "Alice@Example.com"},
+			},
+			OneOf: map[string][]interface{}{"plan": {"Pro", "Team"}},
+			IgnoreCase: map[string]bool{
+				"country":       true,
+				"contact.email": true,
+				"plan":          true,
+				"name":          false,
+			},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"found": true}},
+	}
+
+	s.PutMany(stub)
+
+	query := func(country, name, email, plan string) stuber.Query {
+		return stuber.Query{Service: "Users", Method: "Find", Data: map[string]interface{}{
+			"country": country,
+			"name":    name,
+			"contact": map[string]interface{}{"email": email},
+			"plan":    plan,
+		}}
+	}
+
+	for _, q := range []stuber.Query{
+		query("US", "Alice", "Alice@Example.com", "Pro"),
+		query("us", "Alice", "alice@example.com", "team"),
+		query("Us", "Alice", "ALICE@EXAMPLE.COM", "PRO"),
+	} {
+		r, err := s.FindByQuery(q)
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found(), q.Data)
+	}
+
+	// The name stays case-sensitive, and other values still differ.
+	for _, q := range []stuber.Query{
+		query("us", "alice", "alice@example.com", "pro"),
+		query("uk", "Alice", "alice@example.com", "pro"),
+		query("us", "Alice", "alice@example.com", "free"),
+	} {
+		r, err := s.FindByQuery(q)
+		require.NoError(t, err)
+		require.Nil(t, r.Found(), q.Data)
+	}
+
+	// The stored stub keeps its case.
+	require.Equal(t, "US", s.FindByID(stub.ID).Input.Contains["country"])
+}
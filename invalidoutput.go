@@ -0,0 +1,49 @@
+package stuber
+
+import "encoding/json"
+
+// invalidOutputs returns the stubs with an output that cannot be encoded as
+// JSON, e.g. to catch malformed payloads after a bulk import.
+//
+// Outputs declared as JSON bytes with json.RawMessage must hold valid JSON,
+// other outputs must not contain values without a JSON form, such as NaN,
+// channels or functions. Raw binary outputs given as []byte are encoded as
+// base64 and always pass. The outputs of occurrences and output switch cases
+// are checked as well. The stubs are returned in insertion order.
+//
+// Returns:
+// - []*Stub: The stubs with an invalid output.
+func (s *searcher) invalidOutputs() []*Stub {
+	var results []*Stub
+
+	for _, stub := range s.allOrdered() {
+		if !validOutputs(stub) {
+			results = append(results, stub)
+		}
+	}
+
+	return results
+}
+
+// validOutputs checks if every output of the stub can be encoded as JSON.
+func validOutputs(stub *Stub) bool {
+	outputs := []Output{stub.Output}
+
+	for _, occurrence := range stub.Occurrences {
+		outputs = append(outputs, occurrence.Output)
+	}
+
+	if stub.Switch != nil {
+		for _, output := range stub.Switch.Cases {
+			outputs = append(outputs, output)
+		}
+	}
+
+	for _, output := range outputs {
+		if _, err := json.Marshal(output.Data); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
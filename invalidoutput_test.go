@@ -0,0 +1,33 @@
+package stuber_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_InvalidOutputs(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(data interface{}) *stuber.Stub {
+		return &stuber.Stub{ID: uuid.New(), Service: "Files", Method: "Get", Output: stuber.Output{Data: data}}
+	}
+
+	valid := newStub(map[string]interface{}{"name": "report.pdf"})
+	raw := newStub(json.RawMessage(`{"name":"report.pdf"}`))
+	binary := newStub([]byte{0xff, 0x00, 0xfe})
+	corrupt := newStub(json.RawMessage(`{"name":"report.pdf"`))
+	notANumber := newStub(map[string]interface{}{"size": math.NaN()})
+	occurrence := newStub(map[string]interface{}{"name": "report.pdf"})
+	occurrence.Occurrences = []stuber.Occurrence{{Times: 1, Output: stuber.Output{Data: json.RawMessage(`[1,`)}}}
+
+	s.PutMany(valid, corrupt, raw, binary, notANumber, occurrence)
+
+	require.Equal(t, []*stuber.Stub{corrupt, notANumber, occurrence}, s.InvalidOutputs())
+}
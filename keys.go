@@ -0,0 +1,89 @@
+package stuber
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/gripmock/deeply"
+)
+
+// KeyMatch matches an object field of the query data whose keys are not
+// known in advance, e.g. settings keyed by user.
+//
+// The field matches when any of its keys matches Pattern and holds Value.
+// An empty Pattern accepts every key and a nil Value accepts every value,
+// so a KeyMatch with only a Pattern checks that such a key exists.
+type KeyMatch struct {
+	Pattern string `json:"pattern,omitempty"` // The regular expression keys must match, if set.
+	Value   any    `json:"value,omitempty"`   // The value a key must hold, if set.
+}
+
+// matchKeys checks if the object fields of the query data have a key
+// satisfying the stub's key matchers.
+//
+// Fields are dot-separated paths, a field that is absent or is not an object
+// does not match.
+func matchKeys(input InputData, data map[string]any) bool {
+	return countKeys(input, data) == len(input.Keys)
+}
+
+// rankKeys returns the share of the stub's key matchers the query data satisfies.
+func rankKeys(input InputData, data map[string]any) float64 {
+	if len(input.Keys) == 0 {
+		return 0
+	}
+
+	return float64(countKeys(input, data)) / float64(len(input.Keys))
+}
+
+// countKeys returns the number of the stub's key matchers the query data satisfies.
+func countKeys(input InputData, data map[string]any) int {
+	n := 0
+
+	for path, km := range input.Keys {
+		value, _ := lookup(data, splitPath(path))
+
+		fields, ok := value.(map[string]any)
+		if ok && km.match(fields) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// match checks if any key of the fields matches the pattern and holds the value.
+func (k KeyMatch) match(fields map[string]any) bool {
+	var pattern *regexp.Regexp
+
+	if k.Pattern != "" {
+		var err error
+
+		if pattern, err = regexp.Compile(k.Pattern); err != nil {
+			return false
+		}
+	}
+
+	for key, value := range fields {
+		if pattern != nil && !pattern.MatchString(key) {
+			continue
+		}
+
+		if k.Value == nil || deeply.Equals(k.Value, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileKeys checks that the key patterns of the key matchers compile.
+func compileKeys(keys map[string]KeyMatch) error {
+	for path, km := range keys {
+		if _, err := regexp.Compile(km.Pattern); err != nil {
+			return fmt.Errorf("keys %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Keys(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Settings",
+		Method:  "Get",
+		Input: stuber.InputData{Keys: map[string]stuber.KeyMatch{
+			"users":        {Value: "admin"},
+			"meta.flags":   {Pattern: "^beta-"},
+			"meta.regions": {Pattern: "^eu-", Value: true},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "matched"}},
+	}
+
+	s.PutMany(stub)
+
+	query := func(users, flags, regions map[string]interface{}) stuber.Query {
+		return stuber.Query{Service: "Settings", Method: "Get", Data: map[string]interface{}{
+			"users": users,
+			"meta":  map[string]interface{}{"flags": flags, "regions": regions},
+		}}
+	}
+
+	users := map[string]interface{}{"u1": "viewer", "u42": "admin"}
+	flags := map[string]interface{}{"beta-search": false}
+	regions := map[string]interface{}{"us-east": true, "eu-west": true}
+
+	r, err := s.FindByQuery(query(users, flags, regions))
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+
+	tests := []stuber.Query{
+		query(map[string]interface{}{"u1": "viewer"}, flags, regions),
+		query(users, map[string]interface{}{"search": true}, regions),
+		query(users, flags, map[string]interface{}{"us-east": true, "eu-west": false}),
+		query(users, flags, nil),
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(test)
+		require.NoError(t, err)
+		require.Nil(t, r.Found())
+		require.Same(t, stub, r.Similar())
+	}
+
+	invalid := *stub
+	invalid.Input.Keys = map[string]stuber.KeyMatch{"users": {Pattern: "("}}
+	require.Error(t, invalid.Validate())
+}
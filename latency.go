@@ -0,0 +1,102 @@
+package stuber
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// ErrInvalidLatency is returned when a stub's latency profile has a
+// percentile outside of 0 to 100 or NaN, a negative delay, or delays decreasing
+// with the percentile.
+var ErrInvalidLatency = errors.New("invalid latency profile")
+
+// maxPercentile is the highest percentile of a latency profile.
+const maxPercentile = 100
+
+// LatencyProfile is the distribution of the delays of a stub's responses,
+// given by the delays at some percentiles, e.g. p50=10ms and p99=200ms.
+//
+// Between the percentiles the delay is interpolated linearly, starting from
+// no delay at the 0th percentile unless it is given. Beyond the highest
+// percentile the delay of the highest percentile is used.
+type LatencyProfile struct {
+	Percentiles []LatencyPercentile `json:"percentiles"` // The delays at the percentiles, in any order.
+}
+
+// LatencyPercentile is the delay of a percentile of the responses.
+type LatencyPercentile struct {
+	Percentile float64       `json:"percentile"` // The percentile, from 0 to 100.
+	Delay      time.Duration `json:"delay"`      // The delay not exceeded by the percentile of the responses.
+}
+
+// WithLatencySource sets the source of the random numbers, in [0, 1), used to
+// sample the delays of the latency profiles, e.g. a seeded generator to make
+// tests reproducible. The source may be called concurrently.
+//
+// By default, or if the source is nil, the delays are sampled with
+// math/rand/v2.
+func WithLatencySource(source func() float64) Option {
+	return func(s *searcher) {
+		if source != nil {
+			s.latencySource = source
+		}
+	}
+}
+
+// sample returns the delay at the percentile of the given random number in [0, 1).
+func (p *LatencyProfile) sample(random float64) time.Duration {
+	points := p.sorted()
+	if len(points) == 0 {
+		return 0
+	}
+
+	percentile := random * maxPercentile
+	prev := LatencyPercentile{}
+
+	for _, point := range points {
+		if percentile <= point.Percentile {
+			if point.Percentile == prev.Percentile {
+				return point.Delay
+			}
+
+			share := (percentile - prev.Percentile) / (point.Percentile - prev.Percentile)
+
+			return prev.Delay + time.Duration(share*float64(point.Delay-prev.Delay))
+		}
+
+		prev = point
+	}
+
+	return prev.Delay
+}
+
+// sorted returns the percentiles sorted in ascending order.
+func (p *LatencyProfile) sorted() []LatencyPercentile {
+	return slices.SortedFunc(slices.Values(p.Percentiles), func(a, b LatencyPercentile) int {
+		return cmp.Compare(a.Percentile, b.Percentile)
+	})
+}
+
+// validate checks the percentiles and delays of the profile.
+func (p *LatencyProfile) validate() error {
+	var prev time.Duration
+
+	for _, point := range p.sorted() {
+		switch {
+		case point.Percentile < 0 || point.Percentile > maxPercentile || math.IsNaN(point.Percentile):
+			return fmt.Errorf("%w: percentile %v", ErrInvalidLatency, point.Percentile)
+		case point.Delay < 0:
+			return fmt.Errorf("%w: delay %s", ErrInvalidLatency, point.Delay)
+		case point.Delay < prev:
+			return fmt.Errorf("%w: delay %s of percentile %v is below a lower percentile", ErrInvalidLatency, point.Delay, point.Percentile)
+		}
+
+		prev = point.Delay
+	}
+
+	return nil
+}
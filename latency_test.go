@@ -0,0 +1,81 @@
+package stuber_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestResult_SampleDelay(t *testing.T) {
+	random := []float64{0, 0.25, 0.5, 0.9, 0.99, 0.999}
+
+	var calls int
+
+	s := stuber.NewBudgerigar(features.New(), stuber.WithLatencySource(func() float64 {
+		r := random[calls%len(random)]
+		calls++
+
+		return r
+	}))
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+		Latency: &stuber.LatencyProfile{Percentiles: []stuber.LatencyPercentile{
+			{Percentile: 99, Delay: 200 * time.Millisecond},
+			{Percentile: 50, Delay: 10 * time.Millisecond},
+		}},
+	}
+
+	s.PutMany(stub)
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+
+	// Every call draws a new delay from the distribution.
+	require.Equal(t, time.Duration(0), r.SampleDelay())
+	require.Equal(t, 5*time.Millisecond, r.SampleDelay())
+	require.Equal(t, 10*time.Millisecond, r.SampleDelay())
+	require.InDelta(t, float64(10*time.Millisecond)+40.0/49*float64(190*time.Millisecond), float64(r.SampleDelay()), 1)
+	require.Equal(t, 200*time.Millisecond, r.SampleDelay())
+	require.Equal(t, 200*time.Millisecond, r.SampleDelay())
+
+	// The default source stays within the bounds of the profile.
+	s = stuber.NewBudgerigar(features.New())
+	s.PutMany(stub)
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+
+	for range 100 {
+		delay := r.SampleDelay()
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, 200*time.Millisecond)
+	}
+
+	// Stubs without a profile respond immediately.
+	s.PutMany(&stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHi", Output: stuber.Output{Error: "boom"}})
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHi"})
+	require.NoError(t, err)
+	require.Zero(t, r.SampleDelay())
+
+	for _, profile := range []stuber.LatencyProfile{
+		{Percentiles: []stuber.LatencyPercentile{{Percentile: 101, Delay: time.Second}}},
+		{Percentiles: []stuber.LatencyPercentile{{Percentile: math.NaN(), Delay: time.Second}}},
+		{Percentiles: []stuber.LatencyPercentile{{Percentile: 50, Delay: -time.Second}}},
+		{Percentiles: []stuber.LatencyPercentile{{Percentile: 50, Delay: time.Second}, {Percentile: 99, Delay: time.Millisecond}}},
+	} {
+		invalid := *stub
+		invalid.Latency = &profile
+		require.ErrorIs(t, invalid.Validate(), stuber.ErrInvalidLatency)
+	}
+}
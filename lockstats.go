@@ -0,0 +1,115 @@
+package stuber
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockStats is the time spent waiting to acquire the searcher's locks.
+type LockStats struct {
+	Reads    int64         // The number of read locks acquired.
+	ReadMax  time.Duration // The longest wait for a read lock.
+	ReadAvg  time.Duration // The average wait for a read lock.
+	Writes   int64         // The number of write locks acquired.
+	WriteMax time.Duration // The longest wait for a write lock.
+	WriteAvg time.Duration // The average wait for a write lock.
+}
+
+// WithLockStats makes the searcher time every wait for its read and write
+// locks, available through LockStats.
+//
+// It is disabled by default, since timing every lock adds overhead to each
+// search.
+func WithLockStats() Option {
+	return func(s *searcher) {
+		s.lockTiming = &lockTiming{}
+	}
+}
+
+// lockWaits accumulates the waits for one kind of lock.
+type lockWaits struct {
+	count atomic.Int64
+	total atomic.Int64 // nanoseconds
+	max   atomic.Int64 // nanoseconds
+}
+
+// record adds a wait.
+func (w *lockWaits) record(wait time.Duration) {
+	w.count.Add(1)
+	w.total.Add(int64(wait))
+
+	// Raise the maximum unless another wait raised it further.
+	for {
+		current := w.max.Load()
+		if int64(wait) <= current || w.max.CompareAndSwap(current, int64(wait)) {
+			return
+		}
+	}
+}
+
+// stats returns the number, the longest and the average of the waits.
+func (w *lockWaits) stats() (int64, time.Duration, time.Duration) {
+	count := w.count.Load()
+	if count == 0 {
+		return 0, 0, 0
+	}
+
+	return count, time.Duration(w.max.Load()), time.Duration(w.total.Load() / count)
+}
+
+// lockTiming accumulates the waits for read and write locks.
+type lockTiming struct {
+	reads, writes lockWaits
+}
+
+// timedRWMutex is a sync.RWMutex that records the time spent waiting for
+// it when timing is set.
+type timedRWMutex struct {
+	sync.RWMutex
+
+	timing *lockTiming // the waits, nil when timing is disabled
+}
+
+// Lock locks the mutex for writing.
+func (m *timedRWMutex) Lock() {
+	if m.timing == nil {
+		m.RWMutex.Lock()
+
+		return
+	}
+
+	start := time.Now()
+	m.RWMutex.Lock()
+	m.timing.writes.record(time.Since(start))
+}
+
+// RLock locks the mutex for reading.
+func (m *timedRWMutex) RLock() {
+	if m.timing == nil {
+		m.RWMutex.RLock()
+
+		return
+	}
+
+	start := time.Now()
+	m.RWMutex.RLock()
+	m.timing.reads.record(time.Since(start))
+}
+
+// lockStats returns the waits for the searcher's and its storage's locks.
+//
+// Returns:
+// - LockStats: The waits, all zero when lock timing is disabled.
+func (s *searcher) lockStats() LockStats {
+	if s.lockTiming == nil {
+		return LockStats{}
+	}
+
+	var stats LockStats
+
+	stats.Reads, stats.ReadMax, stats.ReadAvg = s.lockTiming.reads.stats()
+	stats.Writes, stats.WriteMax, stats.WriteAvg = s.lockTiming.writes.stats()
+
+	return stats
+}
@@ -0,0 +1,35 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_LockStats(t *testing.T) {
+	stub := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+	query := stuber.Query{Service: "Greeter", Method: "SayHello"}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stub)
+
+	_, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Equal(t, stuber.LockStats{}, s.LockStats())
+
+	s = stuber.NewBudgerigar(features.New(), stuber.WithLockStats())
+	s.PutMany(stub)
+
+	_, err = s.FindByQuery(query)
+	require.NoError(t, err)
+
+	stats := s.LockStats()
+	require.Positive(t, stats.Reads)
+	require.Positive(t, stats.Writes)
+	require.GreaterOrEqual(t, stats.ReadMax, stats.ReadAvg)
+	require.GreaterOrEqual(t, stats.WriteMax, stats.WriteAvg)
+}
@@ -1,37 +1,249 @@
 package stuber
 
 import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"regexp"
+
 	"github.com/gripmock/deeply"
 )
 
+// Matcher is a custom condition a query must satisfy to match a stub.
+//
+// It is checked in addition to the stub's input and header matchers.
+type Matcher interface {
+	Match(query Query) bool
+}
+
+// MatcherFunc is an adapter to use an ordinary function as a Matcher.
+type MatcherFunc func(query Query) bool
+
+// Match calls f(query).
+func (f MatcherFunc) Match(query Query) bool {
+	return f(query)
+}
+
 // match checks if a given query matches a given stub.
 //
 // It checks if the query matches the stub's input data and headers using
-// the equals, contains, and matches methods.
-func match(query Query, stub *Stub) bool {
+// the equals, contains, and matches methods, and carries the stub's trailers
+// and comes from the stub's peer. The input data's Any group requires at
+// least one of its conditions to match as well. A stub requiring
+// an empty body only matches queries without data. If the query carries a
+// field mask, only the masked fields are compared. Strings of the fields the
+// stub marks to ignore case are compared case-insensitively, and floats
+// within the stub's tolerances compare equal. The CEL program and the matcher
+// expression of the stub are taken from the caches, which may be nil for a
+// stub that is not stored.
+func match(query Query, stub *Stub, programs *celPrograms, nodes *exprNodes) bool {
+	query, stub = withFieldMask(query, stub)
+	query, stub = withIgnoreCase(query, stub)
+	query = withTolerance(query, stub)
+
 	// Check if the query's input data matches the stub's input data.
-	dataMatch := equals(stub.Input.Equals, query.Data, stub.Input.IgnoreArrayOrder) &&
-		contains(stub.Input.Contains, query.Data, stub.Input.IgnoreArrayOrder) &&
-		matches(stub.Input.Matches, query.Data, stub.Input.IgnoreArrayOrder)
+	dataMatch := (!stub.EmptyBody || len(query.Data) == 0) &&
+		matchData(query, stub) && matchSize(stub.Input, query.Data) &&
+		matchItems(stub.Input, query.Data) && matchTimes(stub.Input, query.Data) &&
+		matchElements(stub.Input, query.Data) && matchKeys(stub.Input, query.Data) &&
+		matchOneOf(stub.Input, query.Data) && matchTypeOf(stub.Input, query.Data) &&
+		matchNotEquals(stub.Input, query.Data) &&
+		matchSequence(stub.Input, query.DataSequence) && matchChecksum(stub.BodyChecksum, query)
 
-	// Check if the query's headers match the stub's headers.
+	// Check if the query's headers, trailers and peer match the stub's ones.
 	headersMatch := equals(stub.Headers.Equals, query.Headers, false) &&
 		contains(stub.Headers.Contains, query.Headers, false) &&
-		matches(stub.Headers.Matches, query.Headers, false)
+		matches(stub.Headers.Matches, query.Headers, false) &&
+		(!stub.HeadersExact || onlyDeclared(stub.Headers, query.Headers)) &&
+		matchTrailers(stub.Trailers, query.Trailers) && matchPeer(stub.PeerMatch, query.Peer)
 
 	// Return true if both the data and headers match, otherwise false.
-	return dataMatch && headersMatch
+	return dataMatch && headersMatch && matchCEL(stub, query, programs) && matchExpr(stub, query, nodes) &&
+		(stub.Matcher == nil || stub.Matcher.Match(query))
+}
+
+// onlyDeclared checks if every header of the query is declared by one of the
+// stub's header matchers.
+func onlyDeclared(declared InputHeader, headers map[string]any) bool {
+	for name := range headers {
+		_, equals := declared.Equals[name]
+		_, contains := declared.Contains[name]
+		_, matches := declared.Matches[name]
+		_, captured := declared.Captured[name]
+
+		if !equals && !contains && !matches && !captured {
+			return false
+		}
+	}
+
+	return true
+}
+
+// needsDeadline checks if matching the stub may take an unbounded time.
+//
+// It returns true for stubs with custom, CEL, matcher expression, regular
+// expression, alternative condition, key or normalizer matchers.
+func needsDeadline(stub *Stub) bool {
+	return stub.Matcher != nil || len(stub.Input.Matches) > 0 || len(stub.Headers.Matches) > 0 ||
+		len(stub.Input.Any) > 0 || len(stub.Input.Keys) > 0 || len(stub.Input.Normalizers) > 0 || stub.CEL != "" ||
+		stub.Expr != ""
+}
+
+// matchData checks if the query's input data matches the stub's input data.
+//
+// Absent query fields are filled from the stub's defaults first. Every field
+// the stub expects must then be present in the query data, so an expected
+// false or null never matches an absent field. If the query carries a
+// MatchModeOverride, or else the stub has a MatchMode, the stub's equals and
+// contains matchers are combined and compared using that mode instead.
+func matchData(query Query, stub *Stub) bool {
+	orderIgnore := stub.Input.IgnoreArrayOrder
+	data := withDefaults(query.Data, stub.Input.Defaults)
+
+	// Require every expected field to be present.
+	if !present(stub.Input.Equals, data) || !present(stub.Input.Contains, data) {
+		return false
+	}
+
+	switch cmp.Or(query.MatchModeOverride, stub.MatchMode) {
+	case MatchModeEquals:
+		return equals(mergeInput(stub.Input), data, orderIgnore) &&
+			matches(stub.Input.Matches, data, orderIgnore) &&
+			matchAny(stub.Input.Any, data, orderIgnore)
+	case MatchModeContains:
+		return contains(mergeInput(stub.Input), data, orderIgnore) &&
+			matches(stub.Input.Matches, data, orderIgnore) &&
+			matchAny(stub.Input.Any, data, orderIgnore)
+	default:
+		return equals(stub.Input.Equals, data, orderIgnore) &&
+			contains(stub.Input.Contains, data, orderIgnore) &&
+			matches(stub.Input.Matches, data, orderIgnore) &&
+			matchAny(stub.Input.Any, data, orderIgnore)
+	}
+}
+
+// withDefaults returns the data with the absent top-level fields filled from
+// the defaults. The data is returned as is if there is nothing to fill.
+func withDefaults(data, defaults map[string]any) map[string]any {
+	if len(defaults) == 0 {
+		return data
+	}
+
+	result := make(map[string]any, len(data)+len(defaults))
+
+	maps.Copy(result, defaults)
+	maps.Copy(result, data)
+
+	return result
+}
+
+// present checks if every field of the expected map is present in the actual
+// value, recursing into nested maps.
+//
+// Field values are not compared, this only tells an explicit false or null
+// apart from an absent field.
+func present(expected map[string]any, actual any) bool {
+	if len(expected) == 0 {
+		return true
+	}
+
+	fields, ok := actual.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	for key, value := range expected {
+		field, ok := fields[key]
+		if !ok {
+			return false
+		}
+
+		if nested, ok := value.(map[string]any); ok && !present(nested, field) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchSize checks if the serialized size of the query data is within the
+// byte range of the stub's input data.
+//
+// Both bounds are inclusive, a zero bound is not checked.
+func matchSize(input InputData, data map[string]any) bool {
+	// Skip the serialization if the stub has no size bounds.
+	if input.MinBytes == 0 && input.MaxBytes == 0 {
+		return true
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return false
+	}
+
+	if input.MinBytes > 0 && len(raw) < input.MinBytes {
+		return false
+	}
+
+	return input.MaxBytes == 0 || len(raw) <= input.MaxBytes
+}
+
+// matchItems checks if the collection fields of the query data have a number
+// of items within the bounds of the stub's input data.
+//
+// Fields are dot-separated paths, a field that is absent or is not an array
+// or an object does not match.
+func matchItems(input InputData, data map[string]any) bool {
+	for path, bounds := range input.Items {
+		value, ok := lookup(data, splitPath(path))
+		if !ok {
+			return false
+		}
+
+		var n int
+
+		switch v := value.(type) {
+		case []any:
+			n = len(v)
+		case map[string]any:
+			n = len(v)
+		default:
+			return false
+		}
+
+		if bounds.MinItems > 0 && n < bounds.MinItems || bounds.MaxItems > 0 && n > bounds.MaxItems {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeInput combines the equals and contains matchers of the input data.
+func mergeInput(input InputData) map[string]any {
+	merged := make(map[string]any, len(input.Equals)+len(input.Contains))
+
+	maps.Copy(merged, input.Equals)
+	maps.Copy(merged, input.Contains)
+
+	return merged
 }
 
 // rankMatch ranks how well a given query matches a given stub.
 //
 // It ranks the query's input data and headers against the stub's input data
-// and headers using the RankMatch method from the deeply package.
-func rankMatch(query Query, stub *Stub) float64 {
-	// Rank the query's input data against the stub's input data.
-	dataRank := deeply.RankMatch(stub.Input.Equals, query.Data) +
-		deeply.RankMatch(stub.Input.Contains, query.Data) +
-		deeply.RankMatch(stub.Input.Matches, query.Data)
+// and headers using the RankMatch method from the deeply package. The rank
+// does not depend on the query's MatchModeOverride, but is restricted to the
+// query's field mask and weighted by the query's scoring. The matcher
+// expression of the stub ranks with the input data, its syntax tree is taken
+// from the cache, which may be nil for a stub that is not stored.
+func rankMatch(query Query, stub *Stub, nodes *exprNodes) float64 {
+	query, stub = withFieldMask(query, stub)
+	query, stub = withIgnoreCase(query, stub)
+	query = withTolerance(query, stub)
+
+	// Rank the query's input data and message sequence against the stub's input data.
+	dataRank := rankBody(query.Data, stub) + rankSequence(stub.Input, query.DataSequence) + rankExpr(stub, query, nodes)
 
 	// If the stub has headers, rank the query's headers against the stub's headers.
 	var headersRank float64
@@ -41,10 +253,36 @@ func rankMatch(query Query, stub *Stub) float64 {
 			deeply.RankMatch(stub.Headers.Matches, query.Headers)
 	}
 
+	// Rank the query's trailers against the stub's trailers.
+	headersRank += rankTrailers(stub.Trailers, query.Trailers)
+
+	// Weigh the data and headers ranks if the query overrides the scoring.
+	if query.Scoring != nil {
+		return dataRank*query.Scoring.Body + headersRank*query.Scoring.Headers
+	}
+
 	// Return the sum of the data and headers ranks.
 	return dataRank + headersRank
 }
 
+// rankBody ranks how well the request data matches the stub's input data,
+// ignoring the headers, trailers and message sequence.
+func rankBody(data map[string]any, stub *Stub) float64 {
+	// Rank the data, with the stub's defaults, against the stub's input data.
+	data = withDefaults(data, stub.Input.Defaults)
+
+	return deeply.RankMatch(stub.Input.Equals, data) +
+		deeply.RankMatch(stub.Input.Contains, data) +
+		deeply.RankMatch(stub.Input.Matches, data) +
+		rankTimes(stub.Input, data) +
+		rankAny(stub.Input.Any, data) +
+		rankElements(stub.Input, data) +
+		rankKeys(stub.Input, data) +
+		rankOneOf(stub.Input, data) +
+		rankTypeOf(stub.Input, data) +
+		rankNotEquals(stub.Input, data)
+}
+
 // equals checks if the expected map matches the actual value.
 //
 // It returns true if the expected map matches the actual value,
@@ -91,3 +329,61 @@ func matches(expected map[string]any, actual any, _ bool) bool {
 	// Use the MatchesIgnoreArrayOrder method from the deeply package.
 	return deeply.MatchesIgnoreArrayOrder(expected, actual)
 }
+
+// exceedsDepth checks if the value is nested deeper than the given limit.
+//
+// Every map or slice counts as one level. The walk stops as soon as the limit
+// is crossed, so it never recurses further than the limit itself.
+func exceedsDepth(value any, limit int) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		if limit == 0 {
+			return true
+		}
+
+		for _, item := range v {
+			if exceedsDepth(item, limit-1) {
+				return true
+			}
+		}
+	case []any:
+		if limit == 0 {
+			return true
+		}
+
+		for _, item := range v {
+			if exceedsDepth(item, limit-1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compileMatches checks that every string in the matches map is a valid
+// regular expression.
+//
+// It returns the first compilation error, otherwise nil.
+func compileMatches(value any) error {
+	switch v := value.(type) {
+	case string:
+		_, err := regexp.Compile(v)
+
+		return err
+	case map[string]any:
+		for _, item := range v {
+			if err := compileMatches(item); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := compileMatches(item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
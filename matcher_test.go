@@ -0,0 +1,325 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_SizeBounds(t *testing.T) {
+	// The data below serializes to {"name":"xxxx"}, which is 15 bytes long.
+	data := map[string]interface{}{"name": "xxxx"}
+
+	tests := []struct {
+		name     string
+		min, max int
+		found    bool
+	}{
+		{"no bounds", 0, 0, true},
+		{"min at boundary", 15, 0, true},
+		{"min above size", 16, 0, false},
+		{"max at boundary", 0, 15, true},
+		{"max below size", 0, 14, false},
+		{"exact range", 15, 15, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := stuber.NewBudgerigar(features.New())
+
+			s.PutMany(&stuber.Stub{
+				ID:      uuid.New(),
+				Service: "Greeter1",
+				Method:  "SayHello1",
+				Input:   stuber.InputData{Contains: data, MinBytes: tt.min, MaxBytes: tt.max},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+			})
+
+			r, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: data})
+			if !tt.found {
+				require.NoError(t, err)
+				require.Nil(t, r.Found())
+				require.Equal(t, map[string]interface{}{"message": "hello"}, r.Similar().Output.Data)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, r.Found())
+		})
+	}
+}
+
+func TestBudgerigar_FalseAndNullVsAbsent(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Equals",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"active": false}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "inactive"}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Contains",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"deleted": nil}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "not deleted"}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Nested",
+			Input: stuber.InputData{Contains: map[string]interface{}{
+				"user": map[string]interface{}{"active": false},
+			}},
+			Output: stuber.Output{Data: map[string]interface{}{"message": "nested"}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Default",
+			Input: stuber.InputData{
+				Equals:   map[string]interface{}{"active": false},
+				Defaults: map[string]interface{}{"active": false},
+			},
+			Output: stuber.Output{Data: map[string]interface{}{"message": "default"}},
+		},
+	)
+
+	find := func(method string, data map[string]interface{}) (*stuber.Result, error) {
+		return s.FindByQuery(stuber.Query{Service: "Users", Method: method, Data: data})
+	}
+
+	r, err := find("Equals", map[string]interface{}{"active": false})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = find("Equals", map[string]interface{}{})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"message": "inactive"}, r.Similar().Output.Data)
+
+	r, err = find("Contains", map[string]interface{}{"deleted": nil, "name": "Bob"})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = find("Contains", map[string]interface{}{"name": "Bob"})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	r, err = find("Nested", map[string]interface{}{"user": map[string]interface{}{"active": false}})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = find("Nested", map[string]interface{}{"user": map[string]interface{}{}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	r, err = find("Default", map[string]interface{}{})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = find("Default", map[string]interface{}{"active": true})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+}
+
+func TestBudgerigar_HeadersExact(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Headers: stuber.InputHeader{
+			Contains: map[string]interface{}{"authorization": "Bearer token"},
+			Matches:  map[string]interface{}{"x-request-id": "^[0-9]+$"},
+		},
+		Output:       stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+		HeadersExact: true,
+	}
+
+	s.PutMany(stub)
+
+	query := stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Headers: map[string]interface{}{"authorization": "Bearer token", "x-request-id": "42"},
+	}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+
+	query.Headers["x-tenant"] = "acme"
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, stub, r.Similar())
+
+	stub.HeadersExact = false
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+}
+
+func TestBudgerigar_ItemBounds(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Batch",
+		Method:  "Process",
+		Input: stuber.InputData{
+			Contains: map[string]interface{}{"queue": "jobs"},
+			Items: map[string]stuber.ItemBounds{
+				"items":         {MinItems: 2, MaxItems: 3},
+				"meta.labels":   {MinItems: 1},
+				"meta.warnings": {MaxItems: 1},
+			},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"status": "ok"}},
+	}
+
+	s.PutMany(stub)
+
+	query := func(items []interface{}, labels, warnings map[string]interface{}) stuber.Query {
+		return stuber.Query{
+			Service: "Batch",
+			Method:  "Process",
+			Data: map[string]interface{}{
+				"queue": "jobs",
+				"items": items,
+				"meta":  map[string]interface{}{"labels": labels, "warnings": warnings},
+			},
+		}
+	}
+
+	labels := map[string]interface{}{"env": "test"}
+	warnings := map[string]interface{}{}
+
+	for _, items := range [][]interface{}{{1, 2}, {1, 2, 3}} {
+		r, err := s.FindByQuery(query(items, labels, warnings))
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+	}
+
+	for _, q := range []stuber.Query{
+		query([]interface{}{1}, labels, warnings),
+		query([]interface{}{1, 2, 3, 4}, labels, warnings),
+		query([]interface{}{1, 2}, map[string]interface{}{}, warnings),
+		query([]interface{}{1, 2}, labels, map[string]interface{}{"a": 1, "b": 2}),
+	} {
+		r, err := s.FindByQuery(q)
+		require.NoError(t, err)
+		require.Nil(t, r.Found())
+		require.Same(t, stub, r.Similar())
+	}
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Batch", Method: "Process", Data: map[string]interface{}{"items": "1,2"}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+}
+
+func TestBudgerigar_QueryScoring(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	byBody := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Accounts",
+		Method:  "Get",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"account": "main", "currency": "EUR"}},
+		Output:  stuber.Output{Error: "body"},
+	}
+	byHeaders := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Accounts",
+		Method:  "Get",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{"x-role": "admin"}},
+		Input:   stuber.InputData{Contains: map[string]interface{}{"account": "main"}},
+		Output:  stuber.Output{Error: "headers"},
+	}
+
+	s.PutMany(byBody, byHeaders)
+
+	query := stuber.Query{
+		Service: "Accounts",
+		Method:  "Get",
+		Headers: map[string]interface{}{"x-role": "admin"},
+		Data:    map[string]interface{}{"account": "main", "currency": "EUR"},
+	}
+
+	// By default, the headers of the second stub outrank the larger body of the first.
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, byHeaders, r.Found())
+
+	// Weighing the body only lets the body decide.
+	query.Scoring = &stuber.Scoring{Body: 1}
+	require.NoError(t, query.Validate())
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, byBody, r.Found())
+
+	// The override applies to the search it is carried by only.
+	query.Scoring = nil
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, byHeaders, r.Found())
+
+	query.Scoring = &stuber.Scoring{Headers: -1, Body: 1}
+	require.ErrorIs(t, query.Validate(), stuber.ErrInvalidScoring)
+}
+
+func TestBudgerigar_EmptyBody(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	empty := &stuber.Stub{
+		ID:        uuid.New(),
+		Service:   "Health",
+		Method:    "Ping",
+		Output:    stuber.Output{Data: map[string]interface{}{"message": "empty"}},
+		EmptyBody: true,
+	}
+
+	s.PutMany(empty)
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Health", Method: "Ping"})
+	require.NoError(t, err)
+	require.Same(t, empty, r.Found())
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Health", Method: "Ping", Data: map[string]interface{}{}})
+	require.NoError(t, err)
+	require.Same(t, empty, r.Found())
+
+	query := stuber.Query{Service: "Health", Method: "Ping", Data: map[string]interface{}{"verbose": true}}
+
+	r, err = s.FindByQuery(query)
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	// A stub without matchers accepts any body.
+	anything := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Health",
+		Method:  "Ping",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "anything"}},
+	}
+
+	s.PutMany(anything)
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, anything, r.Found())
+}
@@ -0,0 +1,52 @@
+package stuber
+
+// setServiceDefault sets the match mode of the stubs of the service that do
+// not have a MatchMode of their own, e.g. to match a whole service in
+// MatchModeContains without repeating it on every stub.
+//
+// The default applies during matching only, the stored stubs are unchanged.
+// A query's MatchModeOverride still takes precedence. An empty mode removes
+// the default.
+//
+// Parameters:
+// - service: The name of the service.
+// - mode: The match mode of the stubs of the service.
+func (s *searcher) setServiceDefault(service string, mode MatchMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mode == "" {
+		delete(s.serviceModes, service)
+
+		return
+	}
+
+	if s.serviceModes == nil {
+		s.serviceModes = make(map[string]MatchMode)
+	}
+
+	s.serviceModes[service] = mode
+}
+
+// serviceDefault returns the default match mode of the service, empty if
+// there is none.
+func (s *searcher) serviceDefault(service string) MatchMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.serviceModes[service]
+}
+
+// withMatchMode returns the stub with the given match mode if it has none of
+// its own. The stub is copied rather than modified, and returned as is if
+// there is nothing to set.
+func withMatchMode(stub *Stub, mode MatchMode) *Stub {
+	if mode == "" || stub.MatchMode != "" {
+		return stub
+	}
+
+	inherited := *stub
+	inherited.MatchMode = mode
+
+	return &inherited
+}
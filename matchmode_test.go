@@ -0,0 +1,148 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_MatchModeOverride(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "equals"}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter2",
+			Method:  "SayHello2",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "contains"}},
+		},
+	)
+
+	data := map[string]interface{}{"name": "Bob", "age": "42"}
+
+	// The equals stub does not match extra fields by default.
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: data})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"message": "equals"}, r.Similar().Output.Data)
+
+	r, err = s.FindByQuery(stuber.Query{
+		Service:           "Greeter1",
+		Method:            "SayHello1",
+		Data:              data,
+		MatchModeOverride: stuber.MatchModeContains,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	// The contains stub matches extra fields by default.
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter2", Method: "SayHello2", Data: data})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	r, err = s.FindByQuery(stuber.Query{
+		Service:           "Greeter2",
+		Method:            "SayHello2",
+		Data:              data,
+		MatchModeOverride: stuber.MatchModeEquals,
+	})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"message": "contains"}, r.Similar().Output.Data)
+}
+
+func TestBudgerigar_SetServiceDefault(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	inherited := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Error: "inherited"},
+	}
+	own := &stuber.Stub{
+		ID:        uuid.New(),
+		Service:   "Users",
+		Method:    "List",
+		Input:     stuber.InputData{Equals: map[string]interface{}{"team": "core"}},
+		Output:    stuber.Output{Error: "own"},
+		MatchMode: stuber.MatchModeEquals,
+	}
+	other := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Teams",
+		Method:  "Get",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "core"}},
+		Output:  stuber.Output{Error: "other"},
+	}
+
+	s.PutMany(inherited, own, other)
+
+	getUser := stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{"name": "Bob", "age": 42}}
+	listUsers := stuber.Query{Service: "Users", Method: "List", Data: map[string]interface{}{"team": "core", "page": 2}}
+	getTeam := stuber.Query{Service: "Teams", Method: "Get", Data: map[string]interface{}{"name": "core", "size": 5}}
+
+	// Without a default, the exact inputs reject the extra fields.
+	for _, test := range []struct {
+		query   stuber.Query
+		similar *stuber.Stub
+	}{{getUser, inherited}, {listUsers, own}, {getTeam, other}} {
+		r, err := s.FindByQuery(test.query)
+		require.NoError(t, err)
+		require.Nil(t, r.Found())
+		require.Same(t, test.similar, r.Similar())
+	}
+
+	s.SetServiceDefault("Users", stuber.MatchModeContains)
+
+	// Stubs without a mode of their own inherit the default of their service.
+	r, err := s.FindByQuery(getUser)
+	require.NoError(t, err)
+	require.Same(t, inherited, r.Found())
+	require.Empty(t, s.FindByID(inherited.ID).MatchMode)
+
+	// The stub's own mode takes precedence, other services are unaffected.
+	r, err = s.FindByQuery(listUsers)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, own, r.Similar())
+
+	r, err = s.FindByQuery(getTeam)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, other, r.Similar())
+
+	// The query's override still takes precedence over the default.
+	getUser.MatchModeOverride = stuber.MatchModeEquals
+
+	r, err = s.FindByQuery(getUser)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, inherited, r.Similar())
+
+	getUser.MatchModeOverride = ""
+
+	// An empty mode removes the default.
+	s.SetServiceDefault("Users", "")
+
+	r, err = s.FindByQuery(getUser)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, inherited, r.Similar())
+
+	require.ErrorIs(t, (&stuber.Stub{Service: "Users", Method: "Get", MatchMode: "fuzzy", Output: stuber.Output{Error: "boom"}}).Validate(),
+		stuber.ErrInvalidMatchMode)
+}
@@ -0,0 +1,58 @@
+package stuber
+
+import (
+	"encoding/json"
+	"unsafe"
+
+	"github.com/google/uuid"
+)
+
+// mapEntryOverhead is the estimated number of bytes a map entry takes
+// besides its key and value, covering buckets and load factor slack.
+const mapEntryOverhead = 16
+
+// approxMemory estimates the number of bytes used by the stored stubs.
+//
+// The estimate is the serialized size of every stub plus the size of its
+// struct and the entries of the indexes referring to it. It ignores the
+// cached views and the allocator's own overhead, so it is not exact, but it
+// grows linearly with the number and the size of the stubs. It is computed
+// under the storage read lock.
+//
+// Returns:
+// - int64: The estimated number of bytes.
+func (s *searcher) approxMemory() int64 {
+	st, release := s.storage.read()
+	defer release()
+
+	var (
+		id    = int64(unsafe.Sizeof(uuid.UUID{}))
+		value = int64(unsafe.Sizeof(Value(nil)))
+		total int64
+	)
+
+	for _, v := range st.itemsByID {
+		// The stub itself, with the ID index entry.
+		total += int64(unsafe.Sizeof(Stub{})) + id + value + mapEntryOverhead
+
+		if raw, err := json.Marshal(v); err == nil {
+			total += int64(len(raw))
+		}
+	}
+
+	// The position index entries.
+	for _, values := range st.items {
+		total += id + int64(len(values))*value + mapEntryOverhead
+	}
+
+	// The left and right name index entries.
+	for name := range st.lefts {
+		total += int64(len(name)) + 8 + mapEntryOverhead
+	}
+
+	for name := range st.rights {
+		total += int64(len(name)) + 8 + mapEntryOverhead
+	}
+
+	return total
+}
@@ -0,0 +1,49 @@
+package stuber_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ApproxMemory(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+	require.Zero(t, s.ApproxMemory())
+
+	newStub := func(message string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  "SayHello",
+			Output:  stuber.Output{Data: map[string]interface{}{"message": message}},
+		}
+	}
+
+	for range 10 {
+		s.PutMany(newStub("Hello"))
+	}
+
+	ten := s.ApproxMemory()
+	require.Positive(t, ten)
+
+	for range 10 {
+		s.PutMany(newStub("Hello"))
+	}
+
+	// The estimate grows linearly, up to the shared name indexes.
+	twenty := s.ApproxMemory()
+	require.InDelta(t, 2*ten, twenty, float64(ten)/10)
+
+	large := stuber.NewBudgerigar(features.New())
+	large.PutMany(newStub(strings.Repeat("Hello", 1000)))
+
+	small := stuber.NewBudgerigar(features.New())
+	small.PutMany(newStub("Hello"))
+
+	require.Greater(t, large.ApproxMemory(), small.ApproxMemory()+4000)
+}
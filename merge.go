@@ -0,0 +1,181 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrMergeConflict is returned when stub sets conflict under MergeError.
+var ErrMergeConflict = errors.New("merge conflict")
+
+// MergePolicy decides which stub is kept when two stub sets conflict.
+//
+// Stubs conflict when they have the same ID or the same content, which is
+// everything but the ID and the creation time.
+type MergePolicy int
+
+const (
+	// MergeKeepDst keeps the destination stub and skips the source one.
+	MergeKeepDst MergePolicy = iota
+	// MergeKeepSrc replaces the destination stub with the source one.
+	MergeKeepSrc
+	// MergeError fails the merge without changing the destination.
+	MergeError
+)
+
+// mergeMu serializes merges, so the storages of two searchers are always
+// locked in the same order.
+var mergeMu sync.Mutex //nolint:gochecknoglobals
+
+// Merge copies the stubs of src into dst, resolving conflicts by the policy.
+//
+// Both Budgerigars are locked for writing during the merge. On error, dst
+// is left unchanged, including when a stub of a locked service of dst would
+// be written. The added, replaced and removed stubs of dst are reported by
+// ChangedSince like any other write.
+//
+// Parameters:
+// - dst: The Budgerigar receiving the stubs.
+// - src: The Budgerigar providing the stubs.
+// - policy: The policy resolving conflicting stubs.
+//
+// Returns:
+// - error: An error wrapping ErrMergeConflict listing the conflicting IDs under
+// MergeError, or ErrServiceLocked if a locked service of dst is affected.
+func Merge(dst, src *Budgerigar, policy MergePolicy) error {
+	return mergeSearchers(dst.searcher, src.searcher, policy)
+}
+
+// mergeSearchers copies the stubs of src into dst, resolving conflicts by the policy.
+//
+// Parameters:
+// - dst: The searcher receiving the stubs.
+// - src: The searcher providing the stubs.
+// - policy: The policy resolving conflicting stubs.
+//
+// Returns:
+// - error: An error wrapping ErrMergeConflict listing the conflicting IDs under MergeError.
+func mergeSearchers(dst, src *searcher, policy MergePolicy) error {
+	if dst == src {
+		return nil
+	}
+
+	mergeMu.Lock()
+	defer mergeMu.Unlock()
+
+	// Keep the locked services of the destination fixed during the merge, and
+	// record the changed stubs in its generations.
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	dst.storage.mu.Lock()
+	defer dst.storage.mu.Unlock()
+
+	src.storage.mu.Lock()
+	defer src.storage.mu.Unlock()
+
+	st, publish := dst.storage.edit()
+
+	// Index the destination stubs by their content.
+	byContent := make(map[string]uuid.UUID, len(st.itemsByID))
+
+	for id, v := range st.itemsByID {
+		if hash, ok := contentHash(v); ok {
+			byContent[hash] = id
+		}
+	}
+
+	type step struct {
+		value    Value
+		conflict uuid.UUID // The conflicting destination stub, uuid.Nil if none.
+	}
+
+	// Plan the merge before touching the destination.
+	steps := make([]step, 0, len(src.storage.load().itemsByID))
+	conflicts := make([]error, 0)
+
+	for id, v := range src.storage.load().itemsByID {
+		conflict := uuid.Nil
+
+		if _, exists := st.itemsByID[id]; exists {
+			conflict = id
+		} else if hash, ok := contentHash(v); ok {
+			conflict = byContent[hash]
+		}
+
+		if conflict != uuid.Nil && policy == MergeError {
+			conflicts = append(conflicts, fmt.Errorf("stub %s: conflicts with %s", id, conflict))
+		}
+
+		// Refuse to write the stubs of locked destination services.
+		if conflict == uuid.Nil || policy != MergeKeepDst {
+			services := []string{v.Left()}
+			if conflict != uuid.Nil {
+				services = append(services, st.itemsByID[conflict].Left())
+			}
+
+			if err := dst.checkUnlocked(services...); err != nil {
+				return err
+			}
+		}
+
+		steps = append(steps, step{value: v, conflict: conflict})
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %w", ErrMergeConflict, errors.Join(conflicts...))
+	}
+
+	var added, updated, deleted []uuid.UUID
+
+	for _, step := range steps {
+		id := step.value.Key()
+
+		switch {
+		case step.conflict == uuid.Nil:
+			added = append(added, id)
+		case policy == MergeKeepDst:
+			continue
+		case step.conflict == id:
+			updated = append(updated, id)
+		default:
+			// The source stub has the content of another destination stub, replace it.
+			if dst.storage.remove(st, step.conflict) > 0 {
+				deleted = append(deleted, step.conflict)
+			}
+
+			added = append(added, id)
+		}
+
+		dst.storage.put(st, step.value)
+	}
+
+	publish()
+
+	dst.bury(deleted...)
+	dst.touch(append(added, updated...)...)
+
+	dst.subscribers.emit(ChangeDeleted, deleted)
+	dst.subscribers.emit(ChangeAdded, added)
+	dst.subscribers.emit(ChangeUpdated, updated)
+
+	return nil
+}
+
+// contentHash returns the hash of the stub without its ID and creation time.
+func contentHash(v Value) (string, bool) {
+	stub, ok := v.(*Stub)
+	if !ok {
+		return "", false
+	}
+
+	content := *stub
+	content.ID = uuid.Nil
+	content.CreatedAt = time.Time{}
+
+	return hashStub(content)
+}
@@ -0,0 +1,110 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestMerge(t *testing.T) {
+	shared := uuid.New()
+
+	newStub := func(id uuid.UUID, message string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      id,
+			Service: "Greeter",
+			Method:  "SayHello",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": message}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": message}},
+		}
+	}
+
+	setup := func() (*stuber.Budgerigar, *stuber.Budgerigar, *stuber.Stub) {
+		dst := stuber.NewBudgerigar(features.New())
+		dst.PutMany(newStub(shared, "dst"), newStub(uuid.New(), "same"))
+
+		src := stuber.NewBudgerigar(features.New())
+		duplicate := newStub(uuid.New(), "same")
+		src.PutMany(newStub(shared, "src"), duplicate, newStub(uuid.New(), "new"))
+
+		return dst, src, duplicate
+	}
+
+	t.Run("keep dst", func(t *testing.T) {
+		dst, src, _ := setup()
+
+		require.NoError(t, stuber.Merge(dst, src, stuber.MergeKeepDst))
+		require.Len(t, dst.All(), 3)
+		require.Equal(t, map[string]interface{}{"message": "dst"}, dst.FindByID(shared).Output.Data)
+	})
+
+	t.Run("keep src", func(t *testing.T) {
+		dst, src, duplicate := setup()
+
+		require.NoError(t, stuber.Merge(dst, src, stuber.MergeKeepSrc))
+		require.Len(t, dst.All(), 3)
+		require.Equal(t, map[string]interface{}{"message": "src"}, dst.FindByID(shared).Output.Data)
+		require.Same(t, duplicate, dst.FindByID(duplicate.ID))
+	})
+
+	t.Run("changed since", func(t *testing.T) {
+		dst, src, duplicate := setup()
+
+		var replaced uuid.UUID
+
+		for _, stub := range dst.All() {
+			if stub.ID != shared {
+				replaced = stub.ID
+			}
+		}
+
+		gen := dst.Generation()
+
+		require.NoError(t, stuber.Merge(dst, src, stuber.MergeKeepSrc))
+
+		changed, deleted := dst.ChangedSince(gen)
+		require.Equal(t, []uuid.UUID{replaced}, deleted)
+
+		ids := make([]uuid.UUID, 0, len(changed))
+		for _, stub := range changed {
+			ids = append(ids, stub.ID)
+		}
+
+		require.Len(t, ids, 3)
+		require.Contains(t, ids, shared)
+		require.Contains(t, ids, duplicate.ID)
+		require.Greater(t, dst.Generation(), gen)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		dst, src, _ := setup()
+		etag := dst.ETag()
+
+		err := stuber.Merge(dst, src, stuber.MergeError)
+		require.ErrorIs(t, err, stuber.ErrMergeConflict)
+		require.ErrorContains(t, err, shared.String())
+		require.Len(t, dst.All(), 2)
+		require.Equal(t, etag, dst.ETag())
+
+		require.NoError(t, stuber.Merge(dst, stuber.NewBudgerigar(features.New()), stuber.MergeError))
+	})
+
+	t.Run("copy-on-write", func(t *testing.T) {
+		dst := stuber.NewBudgerigar(features.New(), stuber.WithCopyOnWrite())
+		dst.PutMany(newStub(shared, "dst"))
+
+		src := stuber.NewBudgerigar(features.New())
+		src.PutMany(newStub(shared, "src"), newStub(uuid.New(), "new"))
+
+		require.ErrorIs(t, stuber.Merge(dst, src, stuber.MergeError), stuber.ErrMergeConflict)
+		require.Len(t, dst.All(), 1)
+
+		require.NoError(t, stuber.Merge(dst, src, stuber.MergeKeepSrc))
+		require.Len(t, dst.All(), 2)
+		require.Equal(t, map[string]interface{}{"message": "src"}, dst.FindByID(shared).Output.Data)
+	})
+}
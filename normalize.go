@@ -0,0 +1,111 @@
+package stuber
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization is a set of string normalizations applied before matching.
+//
+// The zero value compares strings exactly.
+type Normalization uint8
+
+const (
+	// NormalizeTrim removes leading and trailing whitespace.
+	NormalizeTrim Normalization = 1 << iota
+	// NormalizeSpace collapses runs of whitespace into a single space.
+	NormalizeSpace
+	// NormalizeNFC converts strings to the Unicode normalization form C.
+	NormalizeNFC
+)
+
+// WithNormalization sets the string normalizations applied to the query data
+// and to the expected input of the stubs before they are compared.
+//
+// It affects matching and ranking only, the stubs and the query are not
+// modified. By default, strings are compared exactly.
+func WithNormalization(n Normalization) Option {
+	return func(s *searcher) {
+		s.normalization = n
+	}
+}
+
+// query returns a copy of the query with its data normalized.
+func (n Normalization) query(query Query) Query {
+	if n == 0 {
+		return query
+	}
+
+	query.Data = n.data(query.Data)
+
+	return query
+}
+
+// stub returns a copy of the stub with its expected input normalized.
+//
+// The regular expressions of the stub are left as they are.
+func (n Normalization) stub(stub *Stub) *Stub {
+	if n == 0 {
+		return stub
+	}
+
+	normalized := *stub
+	normalized.Input.Equals = n.data(stub.Input.Equals)
+	normalized.Input.Contains = n.data(stub.Input.Contains)
+	normalized.Input.Defaults = n.data(stub.Input.Defaults)
+
+	return &normalized
+}
+
+// data returns a copy of the map with all its strings normalized.
+func (n Normalization) data(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+
+	result := make(map[string]any, len(data))
+
+	for k, v := range data {
+		result[k] = n.value(v)
+	}
+
+	return result
+}
+
+// value normalizes the strings of the value, recursing into maps and slices.
+func (n Normalization) value(v any) any {
+	switch v := v.(type) {
+	case string:
+		return n.string(v)
+	case map[string]any:
+		return n.data(v)
+	case []any:
+		result := make([]any, len(v))
+
+		for i, item := range v {
+			result[i] = n.value(item)
+		}
+
+		return result
+	default:
+		return v
+	}
+}
+
+// string applies the normalizations to the string.
+func (n Normalization) string(s string) string {
+	if n&NormalizeNFC != 0 {
+		s = norm.NFC.String(s)
+	}
+
+	if n&NormalizeSpace != 0 {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+
+	if n&NormalizeTrim != 0 {
+		s = strings.TrimSpace(s)
+	}
+
+	return s
+}
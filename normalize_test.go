@@ -0,0 +1,58 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Normalization(t *testing.T) {
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input: stuber.InputData{Equals: map[string]interface{}{
+			"name": "José García",
+			"tags": []interface{}{"a b"},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "Hola"}},
+	}
+
+	query := stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Data: map[string]interface{}{
+			"name": "  Jose\u0301   Garci\u0301a\n",
+			"tags": []interface{}{"a  b "},
+		},
+	}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stub)
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, stub, r.Similar())
+
+	s = stuber.NewBudgerigar(features.New(),
+		stuber.WithNormalization(stuber.NormalizeTrim|stuber.NormalizeSpace|stuber.NormalizeNFC))
+	s.PutMany(stub)
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+	require.Equal(t, "José García", stub.Input.Equals["name"])
+
+	s = stuber.NewBudgerigar(features.New(), stuber.WithNormalization(stuber.NormalizeTrim))
+	s.PutMany(stub)
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, stub, r.Similar())
+}
@@ -0,0 +1,160 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownNormalizer is returned when a stub references a normalizer that
+// is not registered on the searcher.
+var ErrUnknownNormalizer = errors.New("unknown normalizer")
+
+// registerNormalizer registers a function that stubs can reference by name to
+// normalize a field before comparison, e.g. to format phone numbers as E.164.
+//
+// A later registration under the same name replaces the function. The
+// function may be called concurrently and must not call back into the
+// searcher.
+//
+// Parameters:
+// - name: The name stubs reference the normalizer by.
+// - fn: The function normalizing a field value.
+func (s *searcher) registerNormalizer(name string, fn func(any) any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.normalizers[name] = fn
+}
+
+// checkNormalizers checks that every normalizer the stub references is
+// registered.
+//
+// The caller must hold the lock.
+func (s *searcher) checkNormalizers(stub *Stub) error {
+	for path, name := range stub.Input.Normalizers {
+		if _, ok := s.normalizers[name]; !ok {
+			return fmt.Errorf("stub %s: %w %q of %s", stub.ID, ErrUnknownNormalizer, name, path)
+		}
+	}
+
+	return nil
+}
+
+// withNormalizers applies the normalizers the stub references to their
+// fields, in both the query data and the stub's input data.
+//
+// The exact, partial and default inputs and the allowed values are
+// normalized, regular expressions are left as they are. The query and the
+// stub are copied rather than modified, and returned as is if the stub
+// references no normalizer.
+func (s *searcher) withNormalizers(query Query, stub *Stub) (Query, *Stub) {
+	if len(stub.Input.Normalizers) == 0 {
+		return query, stub
+	}
+
+	normalized := *stub
+	input := &normalized.Input
+
+	for path, fn := range s.normalizersOf(stub) {
+		// Skip the normalizers not registered, e.g. of a stub restored from a
+		// snapshot of another searcher.
+		if fn == nil {
+			continue
+		}
+
+		segments := splitPath(path)
+
+		query.Data = transformAt(query.Data, segments, fn)
+		input.Equals = transformAt(input.Equals, segments, fn)
+		input.Contains = transformAt(input.Contains, segments, fn)
+		input.Defaults = transformAt(input.Defaults, segments, fn)
+		input.OneOf = transformOneOf(input.OneOf, path, fn)
+	}
+
+	return query, &normalized
+}
+
+// normalizersOf returns the normalizers the stub references, keyed by path.
+func (s *searcher) normalizersOf(stub *Stub) map[string]func(any) any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	normalizers := make(map[string]func(any) any, len(stub.Input.Normalizers))
+
+	for path, name := range stub.Input.Normalizers {
+		normalizers[path] = s.normalizers[name]
+	}
+
+	return normalizers
+}
+
+// transformAt returns a copy of the data with the value at the path replaced
+// by its transformation. Numeric segments index arrays. The data is returned
+// as is if the path is absent.
+func transformAt(data map[string]any, path []string, fn func(any) any) map[string]any {
+	value, ok := data[path[0]]
+	if !ok {
+		return data
+	}
+
+	result := maps.Clone(data)
+	result[path[0]] = transformValue(value, path[1:], fn)
+
+	return result
+}
+
+// transformValue returns the value with the value at the path below it
+// replaced by its transformation, copying the objects and arrays on the way.
+func transformValue(value any, path []string, fn func(any) any) any {
+	if len(path) == 0 {
+		return fn(value)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return transformAt(v, path, fn)
+	case []any:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(v) {
+			return value
+		}
+
+		result := slices.Clone(v)
+		result[i] = transformValue(v[i], path[1:], fn)
+
+		return result
+	default:
+		return value
+	}
+}
+
+// transformOneOf returns a copy of the allowed values with the values of the
+// fields at or below the path replaced by their transformation.
+func transformOneOf(oneOf map[string][]any, path string, fn func(any) any) map[string][]any {
+	if len(oneOf) == 0 {
+		return oneOf
+	}
+
+	result := make(map[string][]any, len(oneOf))
+
+	for key, allowed := range oneOf {
+		if key != path && !strings.HasPrefix(key, path+".") {
+			result[key] = allowed
+
+			continue
+		}
+
+		values := make([]any, len(allowed))
+		for i, value := range allowed {
+			values[i] = fn(value)
+		}
+
+		result[key] = values
+	}
+
+	return result
+}
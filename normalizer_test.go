@@ -0,0 +1,168 @@
+package stuber_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_RegisterNormalizer(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	// e164 keeps the digits of a phone number, assuming the US country code.
+	s.RegisterNormalizer("e164", func(value any) any {
+		number, ok := value.(string)
+		if !ok {
+			return value
+		}
+
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+
+			return -1
+		}, number)
+
+		if len(digits) == 10 {
+			digits = "1" + digits
+		}
+
+		return "+" + digits
+	})
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Contacts",
+		Method:  "Find",
+		Input: stuber.InputData{
+			Equals:      map[string]interface{}{"phone": "+1 (555) 010-0199", "name": "Alice"},
+			Normalizers: map[string]string{"phone": "e164"},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"found": true}},
+	}
+
+	require.NotNil(t, s.PutMany(stub))
+
+	query := func(phone string) stuber.Query {
+		return stuber.Query{Service: "Contacts", Method: "Find", Data: map[string]interface{}{"phone": phone, "name": "Alice"}}
+	}
+
+	for _, phone := range []string{"+15550100199", "555-010-0199", "(555) 010 0199"} {
+		r, err := s.FindByQuery(query(phone))
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found(), phone)
+	}
+
+	r, err := s.FindByQuery(query("555-010-0198"))
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, stub, r.Similar())
+
+	// Without the normalizer the formats differ.
+	plain := *stub
+	plain.ID = uuid.New()
+	plain.Method = "Get"
+	plain.Input.Normalizers = nil
+
+	s.PutMany(&plain)
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Contacts", Method: "Get", Data: query("555-010-0199").Data})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, &plain, r.Similar())
+
+	// Stubs referencing an unregistered normalizer are rejected.
+	unknown := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Contacts",
+		Method:  "Find",
+		Input:   stuber.InputData{Normalizers: map[string]string{"phone": "missing"}},
+		Output:  stuber.Output{Data: map[string]interface{}{}},
+	}
+
+	ids, err := s.PutManyE(unknown)
+	require.ErrorIs(t, err, stuber.ErrUnknownNormalizer)
+	require.Nil(t, ids)
+	require.Nil(t, s.FindByID(unknown.ID))
+
+	err = s.Import([]byte(`[{"service":"Contacts","method":"Find","input":{"normalizers":{"phone":"missing"}},"output":{"data":{}}}]`))
+	require.ErrorIs(t, err, stuber.ErrUnknownNormalizer)
+}
+
+func TestBudgerigar_IndexedPaths(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.RegisterNormalizer("upper", func(value any) any {
+		if str, ok := value.(string); ok {
+			return strings.ToUpper(str)
+		}
+
+		return value
+	})
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Create",
+		Input: stuber.InputData{
+			Contains:    map[string]interface{}{"currency": "EUR"},
+			OneOf:       map[string][]interface{}{"items[0].sku": {"A-1", "A-2"}},
+			TypeOf:      map[string]string{"items[1].qty": "number"},
+			Normalizers: map[string]string{"items[0].sku": "upper"},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"created": true}},
+	}
+
+	require.NotNil(t, s.PutMany(stub))
+
+	query := func(items ...interface{}) stuber.Query {
+		return stuber.Query{Service: "Orders", Method: "Create", Data: map[string]interface{}{
+			"currency": "EUR",
+			"items":    items,
+		}}
+	}
+
+	item := func(sku string, qty interface{}) map[string]interface{} {
+		return map[string]interface{}{"sku": sku, "qty": qty}
+	}
+
+	// In range, the normalizer applies to the indexed element only.
+	r, err := s.FindByQuery(query(item("a-2", 1), item("b", 3)))
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+
+	for _, q := range []stuber.Query{
+		query(item("B-1", 1), item("A-1", 3)),
+		query(item("A-1", 1), item("B", "3")),
+		query(item("A-1", 1)), // items[1] is out of range.
+		query(),
+	} {
+		r, err := s.FindByQuery(q)
+		require.NoError(t, err)
+		require.Nil(t, r.Found())
+		require.Same(t, stub, r.Similar())
+	}
+
+	// A negative index resolves to no element, so it never matches.
+	negative := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Update",
+		Input:   stuber.InputData{OneOf: map[string][]interface{}{"items[-1].sku": {"A-1"}}},
+		Output:  stuber.Output{Data: map[string]interface{}{"updated": true}},
+	}
+
+	require.NotNil(t, s.PutMany(negative))
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Orders", Method: "Update", Data: map[string]interface{}{
+		"items": []interface{}{item("A-1", 1)},
+	}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+}
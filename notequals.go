@@ -0,0 +1,51 @@
+package stuber
+
+import "github.com/gripmock/deeply"
+
+// NotEqual matches a field of the query data holding anything but a
+// forbidden value, e.g. a status other than "deleted".
+//
+// An absent field does not match, unless AllowAbsent treats it as differing
+// from the forbidden value.
+type NotEqual struct {
+	Value       any  `json:"value"`                 // The value the field must not hold.
+	AllowAbsent bool `json:"allowAbsent,omitempty"` // Whether an absent field matches.
+}
+
+// matchNotEquals checks if the fields of the query data differ from the
+// values the stub's input data forbids.
+//
+// Fields are dot-separated paths.
+func matchNotEquals(input InputData, data map[string]any) bool {
+	return countNotEquals(input, data) == len(input.NotEquals)
+}
+
+// rankNotEquals returns the share of the stub's forbidden values the query data avoids.
+func rankNotEquals(input InputData, data map[string]any) float64 {
+	if len(input.NotEquals) == 0 {
+		return 0
+	}
+
+	return float64(countNotEquals(input, data)) / float64(len(input.NotEquals))
+}
+
+// countNotEquals returns the number of the stub's fields not holding their
+// forbidden value.
+func countNotEquals(input InputData, data map[string]any) int {
+	n := 0
+
+	for path, ne := range input.NotEquals {
+		value, ok := lookup(data, splitPath(path))
+
+		switch {
+		case !ok:
+			if ne.AllowAbsent {
+				n++
+			}
+		case !deeply.Equals(ne.Value, value):
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,55 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_NotEquals(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(method string, allowAbsent bool) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Orders",
+			Method:  method,
+			Input: stuber.InputData{NotEquals: map[string]stuber.NotEqual{
+				"order.status": {Value: "deleted", AllowAbsent: allowAbsent},
+			}},
+			Output: stuber.Output{Data: map[string]interface{}{"message": "listed"}},
+		}
+	}
+
+	strict := newStub("List", false)
+	lenient := newStub("Search", true)
+
+	s.PutMany(strict, lenient)
+
+	query := func(method string, order map[string]interface{}) stuber.Query {
+		return stuber.Query{Service: "Orders", Method: method, Data: map[string]interface{}{"order": order}}
+	}
+
+	for method, stub := range map[string]*stuber.Stub{"List": strict, "Search": lenient} {
+		r, err := s.FindByQuery(query(method, map[string]interface{}{"status": "active"}))
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+
+		r, err = s.FindByQuery(query(method, map[string]interface{}{"status": "deleted"}))
+		require.ErrorIs(t, err, stuber.ErrStubNotFound)
+		require.Nil(t, r)
+	}
+
+	// An absent field only matches if the stub allows it.
+	r, err := s.FindByQuery(query("List", map[string]interface{}{}))
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	r, err = s.FindByQuery(query("Search", map[string]interface{}{}))
+	require.NoError(t, err)
+	require.Same(t, lenient, r.Found())
+}
@@ -0,0 +1,58 @@
+package stuber
+
+// Occurrence is the output a stub returns for a number of its matches.
+type Occurrence struct {
+	Times  int    `json:"times"`  // The number of matches the output is returned for.
+	Output Output `json:"output"` // The output returned for those matches.
+}
+
+// OutputAt returns the output of the stub for its n-th match, counted from zero.
+//
+// The occurrences are consumed in order, each for its number of matches.
+// Once they are exhausted, the output for the request data is returned.
+//
+// Parameters:
+// - n: The number of prior matches of the stub.
+// - data: The request data.
+//
+// Returns:
+// - Output: The output of the stub for the match.
+func (s Stub) OutputAt(n int, data map[string]interface{}) Output {
+	for _, occurrence := range s.Occurrences {
+		if n < occurrence.Times {
+			return occurrence.Output
+		}
+
+		n -= max(occurrence.Times, 0)
+	}
+
+	return s.OutputFor(data)
+}
+
+// occurrence returns the number of prior matches of the stub and counts the
+// current one.
+//
+// Internal requests see the current count without advancing it.
+//
+// Parameters:
+// - query: The Query used to find the Stub value.
+// - stub: The matched Stub value.
+//
+// Returns:
+// - int: The number of prior matches of the stub.
+func (s *searcher) occurrence(query Query, stub *Stub) int {
+	if query.RequestInternal() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return s.occurrences[stub.ID]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.occurrences[stub.ID]
+	s.occurrences[stub.ID]++
+
+	return n
+}
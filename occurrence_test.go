@@ -0,0 +1,49 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Occurrences(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Payments",
+		Method:  "Charge",
+		Occurrences: []stuber.Occurrence{
+			{Times: 2, Output: stuber.Output{Error: "unavailable"}},
+			{Times: 1, Output: stuber.Output{Error: "timeout"}},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"status": "ok"}},
+	}
+
+	require.NoError(t, stub.Validate())
+
+	s.PutMany(stub)
+
+	query := stuber.Query{Service: "Payments", Method: "Charge"}
+
+	for _, expected := range []string{"unavailable", "unavailable", "timeout", "", ""} {
+		r, err := s.FindByQuery(query)
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+		require.Equal(t, expected, r.Output().Error)
+	}
+
+	s.Clear()
+	s.PutMany(stub)
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Equal(t, "unavailable", r.Output().Error)
+
+	stub.Occurrences = append(stub.Occurrences, stuber.Occurrence{Times: 1})
+	require.ErrorIs(t, stub.Validate(), stuber.ErrOutputEmpty)
+}
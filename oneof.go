@@ -0,0 +1,44 @@
+package stuber
+
+import (
+	"slices"
+
+	"github.com/gripmock/deeply"
+)
+
+// matchOneOf checks if the fields of the query data hold one of the values
+// the stub's input data allows.
+//
+// Fields are dot-separated paths. A field that is absent, or whose list of
+// allowed values is empty, does not match.
+func matchOneOf(input InputData, data map[string]any) bool {
+	return countOneOf(input, data) == len(input.OneOf)
+}
+
+// rankOneOf returns the share of the stub's enumerated fields the query data matches.
+func rankOneOf(input InputData, data map[string]any) float64 {
+	if len(input.OneOf) == 0 {
+		return 0
+	}
+
+	return float64(countOneOf(input, data)) / float64(len(input.OneOf))
+}
+
+// countOneOf returns the number of the stub's enumerated fields holding an
+// allowed value.
+func countOneOf(input InputData, data map[string]any) int {
+	n := 0
+
+	for path, allowed := range input.OneOf {
+		value, ok := lookup(data, splitPath(path))
+		if !ok {
+			continue
+		}
+
+		if slices.ContainsFunc(allowed, func(v any) bool { return deeply.Equals(v, value) }) {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,69 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_OneOf(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "List",
+		Input: stuber.InputData{OneOf: map[string][]interface{}{
+			"status":      {"pending", "active", "closed"},
+			"filter.kind": {"retail", "wholesale"},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "listed"}},
+	}
+
+	s.PutMany(stub)
+
+	query := func(status, kind interface{}) stuber.Query {
+		return stuber.Query{Service: "Orders", Method: "List", Data: map[string]interface{}{
+			"status": status,
+			"filter": map[string]interface{}{"kind": kind},
+		}}
+	}
+
+	for _, status := range []string{"pending", "active", "closed"} {
+		r, err := s.FindByQuery(query(status, "retail"))
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+	}
+
+	for _, q := range []stuber.Query{
+		query("archived", "retail"),
+		query("active", "online"),
+		query([]interface{}{"active"}, "retail"),
+		{Service: "Orders", Method: "List", Data: map[string]interface{}{"status": "active"}},
+	} {
+		r, err := s.FindByQuery(q)
+		require.NoError(t, err)
+		require.Nil(t, r.Found())
+		require.Same(t, stub, r.Similar())
+	}
+
+	// An empty list of allowed values never matches.
+	never := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Get",
+		Input:   stuber.InputData{OneOf: map[string][]interface{}{"status": {}}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "never"}},
+	}
+
+	s.PutMany(never)
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Orders", Method: "Get", Data: map[string]interface{}{"status": "active"}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+	require.Equal(t, []*stuber.Stub{never}, s.Unsatisfiable())
+}
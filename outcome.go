@@ -0,0 +1,63 @@
+package stuber
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// OutcomeCounts holds the number of searches per outcome.
+type OutcomeCounts struct {
+	ServiceNotFound uint64 `json:"serviceNotFound"` // Searches that ended in ErrServiceNotFound.
+	MethodNotFound  uint64 `json:"methodNotFound"`  // Searches that ended in ErrMethodNotFound.
+	StubNotFound    uint64 `json:"stubNotFound"`    // Searches that ended in ErrStubNotFound.
+	Found           uint64 `json:"found"`           // Searches that found an exact match.
+	Similar         uint64 `json:"similar"`         // Searches that found only a similar match.
+}
+
+// outcomes counts the outcomes of searches.
+//
+// The counters are atomic, so recording an outcome never contends with the
+// searcher's locks.
+type outcomes struct {
+	serviceNotFound atomic.Uint64
+	methodNotFound  atomic.Uint64
+	stubNotFound    atomic.Uint64
+	found           atomic.Uint64
+	similar         atomic.Uint64
+}
+
+// record counts the outcome of a search.
+//
+// Parameters:
+// - result: The Result of the search, if any.
+// - err: The error of the search, if any.
+func (o *outcomes) record(result *Result, err error) {
+	switch {
+	case errors.Is(err, ErrServiceNotFound):
+		o.serviceNotFound.Add(1)
+	case errors.Is(err, ErrMethodNotFound):
+		o.methodNotFound.Add(1)
+	case errors.Is(err, ErrStubNotFound):
+		o.stubNotFound.Add(1)
+	case err != nil || result == nil:
+		// Other errors are not counted.
+	case result.Found() != nil:
+		o.found.Add(1)
+	default:
+		o.similar.Add(1)
+	}
+}
+
+// outcomeCounts returns the number of searches per outcome.
+//
+// Returns:
+// - OutcomeCounts: The current counters.
+func (s *searcher) outcomeCounts() OutcomeCounts {
+	return OutcomeCounts{
+		ServiceNotFound: s.outcomes.serviceNotFound.Load(),
+		MethodNotFound:  s.outcomes.methodNotFound.Load(),
+		StubNotFound:    s.outcomes.stubNotFound.Load(),
+		Found:           s.outcomes.found.Load(),
+		Similar:         s.outcomes.similar.Load(),
+	}
+}
@@ -0,0 +1,51 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_OutcomeCounts(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	id := uuid.New()
+
+	s.PutMany(&stuber.Stub{
+		ID:      id,
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+	})
+
+	queries := []stuber.Query{
+		{Service: "Greeter1", Method: "SayHello1", Data: map[string]interface{}{"name": "Bob"}},
+		{Service: "Greeter1", Method: "SayHello1", Data: map[string]interface{}{"name": "Bob"}},
+		{ID: &id, Service: "Greeter1", Method: "SayHello1"},
+		{Service: "Greeter1", Method: "SayHello1", Data: map[string]interface{}{"name": "Bobby"}},
+		{Service: "Greeter2", Method: "SayHello1"},
+		{Service: "Greeter1", Method: "SayHello2"},
+	}
+
+	for _, q := range queries {
+		_, _ = s.FindByQuery(q)
+	}
+
+	require.Equal(t, stuber.OutcomeCounts{
+		ServiceNotFound: 1,
+		MethodNotFound:  1,
+		Found:           3,
+		Similar:         1,
+	}, s.OutcomeCounts())
+
+	s.Clear()
+
+	_, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1"})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+	require.Equal(t, uint64(2), s.OutcomeCounts().ServiceNotFound)
+}
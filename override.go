@@ -0,0 +1,59 @@
+package stuber
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outputOverride is an output that temporarily replaces the output of a stub.
+type outputOverride struct {
+	output Output    // the output returned instead of the stub's own
+	until  time.Time // the time the stub's own output resumes
+}
+
+// overrideOutput makes the stub with the given ID respond with the given
+// output until the deadline passes, e.g. to inject transient failures.
+//
+// Matching is unaffected, and the occurrences of the stub are still counted.
+// A later call replaces the override, a deadline in the past removes it.
+//
+// Parameters:
+// - id: The UUID of the Stub value to override.
+// - output: The output to respond with.
+// - until: The time the stub's own output resumes.
+//
+// Returns:
+// - error: ErrStubNotFound if there is no Stub value with the given ID.
+func (s *searcher) overrideOutput(id uuid.UUID, output Output, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findByID(id) == nil {
+		return ErrStubNotFound
+	}
+
+	if !time.Now().Before(until) {
+		delete(s.overrides, id)
+
+		return nil
+	}
+
+	s.overrides[id] = outputOverride{output: output, until: until}
+
+	return nil
+}
+
+// overridden returns the output overriding the stub with the given ID, if
+// its deadline has not passed yet.
+func (s *searcher) overridden(id uuid.UUID) (Output, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	override, ok := s.overrides[id]
+	if !ok || !time.Now().Before(override.until) {
+		return Output{}, false
+	}
+
+	return override.output, true
+}
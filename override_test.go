@@ -0,0 +1,56 @@
+package stuber_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_OverrideOutput(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+	}
+
+	s.PutMany(stub)
+
+	require.ErrorIs(t, s.OverrideOutput(uuid.New(), stuber.Output{Error: "boom"}, time.Now().Add(time.Hour)), stuber.ErrStubNotFound)
+
+	unavailable := codes.Unavailable
+	failure := stuber.Output{Error: "try again", Code: &unavailable}
+
+	require.NoError(t, s.OverrideOutput(stub.ID, failure, time.Now().Add(50*time.Millisecond)))
+
+	query := stuber.Query{Service: "Greeter", Method: "SayHello"}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+	require.Equal(t, failure, r.Output())
+	require.Equal(t, map[string]interface{}{"message": "hello"}, s.FindByID(stub.ID).Output.Data)
+
+	// The stub's own output resumes once the deadline passes.
+	require.Eventually(t, func() bool {
+		r, err := s.FindByQuery(query)
+
+		return err == nil && r.Output().Error == ""
+	}, time.Second, 10*time.Millisecond)
+
+	// A deadline in the past removes the override.
+	require.NoError(t, s.OverrideOutput(stub.ID, failure, time.Now().Add(time.Hour)))
+	require.NoError(t, s.OverrideOutput(stub.ID, failure, time.Now().Add(-time.Second)))
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Equal(t, stub.Output, r.Output())
+}
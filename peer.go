@@ -0,0 +1,66 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidPeer is returned when a stub's peer matcher is not a valid
+// address or CIDR range.
+var ErrInvalidPeer = errors.New("invalid peer")
+
+// matchPeer checks if the query's peer satisfies the stub's peer matcher.
+//
+// A matcher containing a slash is a CIDR range the peer's IP must fall in,
+// otherwise it is an IP the peer's IP must equal. The peer may carry a port,
+// which is ignored. A peer that is not an IP address, e.g. a unix socket
+// path, only matches a matcher with the same text.
+func matchPeer(pattern, peer string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	addr, ok := peerAddr(peer)
+
+	if strings.Contains(pattern, "/") {
+		prefix, err := netip.ParsePrefix(pattern)
+
+		return err == nil && ok && prefix.Contains(addr)
+	}
+
+	expected, err := netip.ParseAddr(pattern)
+	if err != nil || !ok {
+		return pattern == peer
+	}
+
+	return expected.Unmap() == addr
+}
+
+// peerAddr returns the IP address of the peer, with or without a port.
+func peerAddr(peer string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(peer); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(peer)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// compilePeer checks that a peer matcher with a slash is a valid CIDR range.
+func compilePeer(pattern string) error {
+	if !strings.Contains(pattern, "/") {
+		return nil
+	}
+
+	if _, err := netip.ParsePrefix(pattern); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPeer, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_PeerMatch(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(peer string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:        uuid.New(),
+			Service:   "Greeter",
+			Method:    "SayHello",
+			PeerMatch: peer,
+			Output:    stuber.Output{Data: map[string]interface{}{"peer": peer}},
+		}
+	}
+
+	exact := newStub("192.168.1.10")
+	office := newStub("10.0.0.0/8")
+	ipv6 := newStub("2001:db8::/32")
+
+	s.PutMany(exact, office, ipv6)
+
+	for peer, expected := range map[string]*stuber.Stub{
+		"192.168.1.10":       exact,
+		"192.168.1.10:50051": exact,
+		"10.20.30.40:443":    office,
+		"[2001:db8::1]:8080": ipv6,
+	} {
+		r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello", Peer: peer})
+		require.NoError(t, err)
+		require.Same(t, expected, r.Found(), peer)
+	}
+
+	for _, peer := range []string{"192.168.1.11", "172.16.0.1:443", "unix:/tmp/grpc.sock", ""} {
+		r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello", Peer: peer})
+		require.NoError(t, err)
+		require.Nil(t, r.Found(), peer)
+	}
+
+	invalid := newStub("10.0.0.0/33")
+	require.ErrorIs(t, invalid.Validate(), stuber.ErrInvalidPeer)
+
+	ids, err := s.PutManyE(invalid)
+	require.ErrorIs(t, err, stuber.ErrInvalidPeer)
+	require.Nil(t, ids)
+	require.Nil(t, s.FindByID(invalid.ID))
+	require.ErrorIs(t, s.Import([]byte(`[{"service":"Greeter","method":"SayHi","peerMatch":"::1/129","output":{"data":{}}}]`)),
+		stuber.ErrInvalidPeer)
+}
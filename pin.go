@@ -0,0 +1,83 @@
+package stuber
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// pin forces a stub as the response for the queries matching a pattern.
+type pin struct {
+	matcher Matcher   // the pattern of the pinned queries, nil for every query
+	id      uuid.UUID // the ID of the stub the pinned queries find
+}
+
+// pin makes every query of the service and method that satisfies the matcher
+// find the stub with the given ID, regardless of how the stubs rank, e.g. to
+// force a failure during an incident simulation.
+//
+// Pins of the same service and method are tried in the order they were
+// added, the first one whose matcher accepts the query wins. A nil matcher
+// accepts every query. The pinned stub may belong to another service or
+// method, and is found even if it is disabled. A pin whose stub is deleted
+// is skipped. Queries by ID are unaffected.
+//
+// Parameters:
+// - service: The service of the pinned queries.
+// - method: The method of the pinned queries.
+// - matcher: The pattern of the pinned queries, nil for every query.
+// - id: The UUID of the Stub value to respond with.
+//
+// Returns:
+// - error: ErrStubNotFound if there is no Stub value with the given ID.
+func (s *searcher) pin(service, method string, matcher Matcher, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findByID(id) == nil {
+		return ErrStubNotFound
+	}
+
+	if s.pins == nil {
+		s.pins = make(map[group][]pin)
+	}
+
+	key := group{service: service, method: method}
+	s.pins[key] = append(s.pins[key], pin{matcher: matcher, id: id})
+
+	return nil
+}
+
+// unpin removes the pins of the service and method, so their queries are
+// ranked normally again.
+//
+// Parameters:
+// - service: The service of the pinned queries.
+// - method: The method of the pinned queries.
+func (s *searcher) unpin(service, method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pins, group{service: service, method: method})
+}
+
+// pinned returns the stub the query is pinned to, nil if there is none.
+//
+// The matchers are called without holding the lock.
+func (s *searcher) pinned(query Query) *Stub {
+	s.mu.RLock()
+	pins := slices.Clone(s.pins[group{service: query.Service, method: query.Method}])
+	s.mu.RUnlock()
+
+	for _, p := range pins {
+		if p.matcher != nil && !p.matcher.Match(query) {
+			continue
+		}
+
+		if stub := s.findByID(p.id); stub != nil {
+			return stub
+		}
+	}
+
+	return nil
+}
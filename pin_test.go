@@ -0,0 +1,69 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Pin(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	bob := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"name": "Bob"}},
+	}
+	outage := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "nobody"}},
+		Output:  stuber.Output{Error: "unavailable"},
+	}
+
+	s.PutMany(bob, outage)
+
+	query := stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{"name": "Bob"}}
+	alice := stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{"name": "Alice"}}
+
+	require.ErrorIs(t, s.Pin("Users", "Get", nil, uuid.New()), stuber.ErrStubNotFound)
+
+	// Only the queries the matcher accepts are pinned, regardless of ranking.
+	require.NoError(t, s.Pin("Users", "Get", stuber.MatcherFunc(func(q stuber.Query) bool {
+		return q.Data["name"] == "Bob"
+	}), outage.ID))
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, outage, r.Found())
+	require.Equal(t, "unavailable", r.Output().Error)
+
+	r, err = s.FindByQuery(alice)
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	// A pin without a matcher applies to every query.
+	require.NoError(t, s.Pin("Users", "Get", nil, outage.ID))
+
+	r, err = s.FindByQuery(alice)
+	require.NoError(t, err)
+	require.Same(t, outage, r.Found())
+
+	// Unpinning restores the normal ranking.
+	s.Unpin("Users", "Get")
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, bob, r.Found())
+
+	r, err = s.FindByQuery(alice)
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+}
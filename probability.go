@@ -0,0 +1,46 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidProbability is returned when a stub's match probability is not
+// between 0 and 1.
+var ErrInvalidProbability = errors.New("invalid match probability")
+
+// WithMatchSource sets the source of the random numbers, in [0, 1), drawn for
+// the stubs with a match probability, e.g. a seeded generator to make tests
+// reproducible. The source may be called concurrently.
+//
+// By default, or if the source is nil, the numbers are drawn with
+// math/rand/v2.
+func WithMatchSource(source func() float64) Option {
+	return func(s *searcher) {
+		if source != nil {
+			s.matchSource = source
+		}
+	}
+}
+
+// drawMatch checks if a matching stub is allowed to match this time.
+//
+// A stub with a match probability in (0, 1) matches when a number drawn from
+// the searcher's match source is below it, other stubs always match.
+func (s *searcher) drawMatch(stub *Stub) bool {
+	if stub.MatchProbability <= 0 || stub.MatchProbability >= 1 {
+		return true
+	}
+
+	return s.matchSource() < stub.MatchProbability
+}
+
+// validateProbability checks that the match probability is between 0 and 1.
+func validateProbability(probability float64) error {
+	if probability < 0 || probability > 1 || math.IsNaN(probability) {
+		return fmt.Errorf("%w: %v", ErrInvalidProbability, probability)
+	}
+
+	return nil
+}
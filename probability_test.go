@@ -0,0 +1,102 @@
+package stuber_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_MatchProbability(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+
+	s := stuber.NewBudgerigar(features.New(), stuber.WithMatchSource(random.Float64))
+
+	newStub := func(message string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Orders",
+			Method:  "Get",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"id": "1"}},
+			Output:  stuber.Output{Error: message},
+		}
+	}
+
+	// The flaky stub outranks the fallback whenever it matches.
+	flaky, fallback := newStub("flaky"), newStub("fallback")
+	flaky.Headers = stuber.InputHeader{Equals: map[string]interface{}{"x-env": "test"}}
+	flaky.MatchProbability = 0.3
+
+	query := stuber.Query{
+		Service: "Orders",
+		Method:  "Get",
+		Headers: map[string]interface{}{"x-env": "test"},
+		Data:    map[string]interface{}{"id": "1"},
+	}
+
+	s.PutMany(flaky, fallback)
+
+	const searches = 10000
+
+	matched := 0
+
+	for range searches {
+		r, err := s.FindByQuery(query)
+		require.NoError(t, err)
+
+		if r.Found() == flaky {
+			matched++
+		} else {
+			require.Same(t, fallback, r.Found())
+		}
+	}
+
+	require.InDelta(t, 0.3, float64(matched)/searches, 0.02)
+
+	// Only searches draw, evaluating or replaying treats the flaky stub as matching.
+	draws := 0
+	counted := stuber.NewBudgerigar(features.New(), stuber.WithMatchSource(func() float64 {
+		draws++
+
+		return 0.99
+	}))
+	counted.PutMany(flaky, fallback)
+
+	eval, err := counted.Evaluate(query)
+	require.NoError(t, err)
+	require.Same(t, flaky, eval.Found)
+
+	replayed := counted.Replay([]stuber.Query{query}, false)
+	require.Equal(t, flaky.ID, replayed[0].StubID)
+	require.Zero(t, draws)
+
+	r, err := counted.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, fallback, r.Found())
+	require.Equal(t, 1, draws)
+
+	// Without a probability, or with a certain one, the stub always matches.
+	for _, probability := range []float64{0, 1} {
+		certain := *flaky
+		certain.MatchProbability = probability
+
+		s.UpdateMany(&certain)
+
+		for range 100 {
+			r, err := s.FindByQuery(query)
+			require.NoError(t, err)
+			require.Same(t, &certain, r.Found())
+		}
+	}
+
+	for _, probability := range []float64{-0.1, 1.5, math.NaN()} {
+		invalid := *flaky
+		invalid.MatchProbability = probability
+		require.ErrorIs(t, invalid.Validate(), stuber.ErrInvalidProbability)
+	}
+}
@@ -0,0 +1,123 @@
+package stuber
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Quarantined is a stub excluded from the searches because matching it failed.
+type Quarantined struct {
+	Stub   *Stub  `json:"stub"`   // The quarantined stub.
+	Reason string `json:"reason"` // Why the stub was quarantined, e.g. the value its matcher panicked with.
+}
+
+// quarantine holds the IDs of the stubs whose matchers failed, with the
+// reasons. It has its own lock, so it can be written while matching.
+//
+// The zero value is an empty quarantine.
+type quarantine struct {
+	mu      sync.RWMutex
+	reasons map[uuid.UUID]string
+}
+
+// add quarantines the stub with the given ID, keeping the first reason.
+func (q *quarantine) add(id uuid.UUID, reason string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.reasons == nil {
+		q.reasons = make(map[uuid.UUID]string)
+	}
+
+	if _, ok := q.reasons[id]; !ok {
+		q.reasons[id] = reason
+	}
+}
+
+// has checks if the stub with the given ID is quarantined.
+func (q *quarantine) has(id uuid.UUID) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	_, ok := q.reasons[id]
+
+	return ok
+}
+
+// release lifts the quarantine of the stubs with the given IDs.
+func (q *quarantine) release(ids ...uuid.UUID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, id := range ids {
+		delete(q.reasons, id)
+	}
+}
+
+// reset lifts the quarantine of all the stubs.
+func (q *quarantine) reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reasons = nil
+}
+
+// snapshot returns a copy of the reasons, keyed by stub ID.
+func (q *quarantine) snapshot() map[uuid.UUID]string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return maps.Clone(q.reasons)
+}
+
+// quarantineFailed quarantines the stub if its matchers panicked, with the
+// panic value as the reason. It is deferred around the matching of a stub
+// and recovers the panic, the stub is then a non-match with a zero rank.
+func (s *searcher) quarantineFailed(stub *Stub, matched *bool, rank *float64) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	reason := fmt.Sprintf("matcher panicked: %v", r)
+
+	slog.Warn("stub quarantined",
+		"id", stub.ID,
+		"service", stub.Service,
+		"method", stub.Method,
+		"reason", reason)
+
+	s.quarantine.add(stub.ID, reason)
+
+	*matched, *rank = false, 0
+}
+
+// quarantined returns the stubs excluded from the searches because their
+// matchers panicked, e.g. on a fixture bug, with the reason of each.
+//
+// A quarantined stub is skipped by searches but can still be found by its
+// ID. Updating, enabling or deleting the stub lifts its quarantine, so a
+// fixed stub is matched again. Clear lifts all of them.
+//
+// Returns:
+// - []Quarantined: The quarantined stubs, sorted by service, method and ID.
+func (s *searcher) quarantined() []Quarantined {
+	results := make([]Quarantined, 0)
+
+	for id, reason := range s.quarantine.snapshot() {
+		if stub := s.findByID(id); stub != nil {
+			results = append(results, Quarantined{Stub: stub, Reason: reason})
+		}
+	}
+
+	slices.SortFunc(results, func(a, b Quarantined) int {
+		return compareStubs(a.Stub, b.Stub)
+	})
+
+	return results
+}
@@ -0,0 +1,78 @@
+package stuber_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Quarantine(t *testing.T) {
+	for name, opts := range map[string][]stuber.Option{
+		"inline":        nil,
+		"match timeout": {stuber.WithMatchTimeout(time.Second)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := stuber.NewBudgerigar(features.New(), opts...)
+
+			broken := &stuber.Stub{
+				ID:      uuid.New(),
+				Service: "Greeter1",
+				Method:  "SayHello1",
+				Headers: stuber.InputHeader{Equals: map[string]interface{}{"x-team": "core"}},
+				Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "broken"}},
+				Matcher: stuber.MatcherFunc(func(stuber.Query) bool {
+					panic("boom")
+				}),
+			}
+			fallback := &stuber.Stub{
+				ID:      uuid.New(),
+				Service: "Greeter1",
+				Method:  "SayHello1",
+				Input:   stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "fallback"}},
+			}
+
+			s.PutMany(broken, fallback)
+			require.Empty(t, s.Quarantined())
+
+			query := stuber.Query{
+				Service: "Greeter1",
+				Method:  "SayHello1",
+				Headers: map[string]interface{}{"x-team": "core"},
+				Data:    map[string]interface{}{"name": "Bob"},
+			}
+
+			// The panic is recovered and the other stubs are still matched.
+			for range 2 {
+				r, err := s.FindByQuery(query)
+				require.NoError(t, err)
+				require.Same(t, fallback, r.Found())
+			}
+
+			quarantined := s.Quarantined()
+			require.Len(t, quarantined, 1)
+			require.Same(t, broken, quarantined[0].Stub)
+			require.Contains(t, quarantined[0].Reason, "boom")
+
+			// A quarantined stub can still be found by its ID.
+			require.Same(t, broken, s.FindByID(broken.ID))
+
+			// Fixing the stub lifts its quarantine.
+			fixed := *broken
+			fixed.Matcher = stuber.MatcherFunc(func(stuber.Query) bool { return true })
+
+			s.UpdateMany(&fixed)
+			require.Empty(t, s.Quarantined())
+
+			r, err := s.FindByQuery(query)
+			require.NoError(t, err)
+			require.Same(t, &fixed, r.Found())
+		})
+	}
+}
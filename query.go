@@ -2,7 +2,10 @@ package stuber
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/bavix/features"
 	"github.com/google/uuid"
@@ -12,6 +15,56 @@ const (
 	RequestInternalFlag features.Flag = iota
 )
 
+// ErrInvalidFullName is returned when a full method name is not in the
+// "service/method" form.
+var ErrInvalidFullName = errors.New("invalid full method name")
+
+// ErrQueryServiceEmpty is returned when a query does not name a service.
+var ErrQueryServiceEmpty = errors.New("query has no service")
+
+// ErrQueryMethodEmpty is returned when a query does not name a method and the
+// method wildcard is disabled.
+var ErrQueryMethodEmpty = errors.New("query has no method")
+
+// ErrInvalidID is returned when a query carries the nil UUID as its ID.
+var ErrInvalidID = errors.New("invalid id")
+
+// ErrInvalidMatchMode is returned when a query carries an unknown match mode override.
+var ErrInvalidMatchMode = errors.New("invalid match mode")
+
+// ErrInvalidFieldMask is returned when a query carries a field mask path with an empty segment.
+var ErrInvalidFieldMask = errors.New("invalid field mask")
+
+// ErrInvalidScoring is returned when a query carries a negative scoring weight.
+var ErrInvalidScoring = errors.New("invalid scoring")
+
+// ErrConflictingFields is returned when a query carries fields that cannot be used together.
+var ErrConflictingFields = errors.New("conflicting fields")
+
+// MatchMode defines how the exact and partial input matchers of a stub are
+// compared against the query data.
+type MatchMode string
+
+const (
+	// MatchModeEquals requires the query data to be equal to the combined
+	// equals and contains matchers of a stub.
+	MatchModeEquals MatchMode = "equals"
+
+	// MatchModeContains requires the query data to contain the combined
+	// equals and contains matchers of a stub.
+	MatchModeContains MatchMode = "contains"
+)
+
+// Scoring weighs the parts of the rank of a stub against each other.
+//
+// The rank of a stub is the weighted sum of the rank of its body matchers,
+// including the message sequence, and the rank of its header and trailer
+// matchers. Without a scoring, both weigh 1.
+type Scoring struct {
+	Headers float64 `json:"headers"` // The weight of the rank of the headers and trailers.
+	Body    float64 `json:"body"`    // The weight of the rank of the body.
+}
+
 type Query struct {
 	ID      *uuid.UUID             `json:"id,omitempty"`
 	Service string                 `json:"service"`
@@ -19,6 +72,42 @@ type Query struct {
 	Headers map[string]interface{} `json:"headers"`
 	Data    map[string]interface{} `json:"data"`
 
+	// DataSequence holds the messages of a client stream in the order they
+	// were sent, matched by the stubs' sequence matchers.
+	DataSequence []map[string]interface{} `json:"dataSequence,omitempty"`
+
+	// Trailers are the trailer metadata of the request.
+	Trailers map[string]string `json:"trailers,omitempty"`
+
+	// Peer is the address of the caller, with or without a port.
+	Peer string `json:"peer,omitempty"`
+
+	// RawBody is the request body as received, checked by the stubs' body
+	// checksums. Without it, the checksums are computed over the data.
+	RawBody []byte `json:"rawBody,omitempty"`
+
+	// MatchModeOverride replaces the matching mode of every stub for this
+	// query. When empty, each stub is matched the way it was authored.
+	MatchModeOverride MatchMode `json:"matchModeOverride,omitempty"`
+
+	// FieldMask lists the dot-separated paths of the data fields to match,
+	// like a protobuf FieldMask. Fields outside the mask are ignored in both
+	// the query data and the stubs' input data. When empty, all fields match.
+	FieldMask []string `json:"fieldMask,omitempty"`
+
+	// IncludeSimilar asks for the runner-up to be reported by Result.Similar
+	// even when a stub matches, e.g. to spot unintended close competitors.
+	IncludeSimilar bool `json:"includeSimilar,omitempty"`
+
+	// SimilarCandidates asks for the most similar stubs to be collected when
+	// nothing matches, see Result.SimilarN.
+	SimilarCandidates bool `json:"similarCandidates,omitempty"`
+
+	// Scoring overrides the weights of the headers and the body in the ranks
+	// of the stubs for this query, e.g. to let headers decide between the
+	// stubs of an auth-sensitive call. Matching is unaffected.
+	Scoring *Scoring `json:"scoring,omitempty"`
+
 	toggles features.Toggles
 }
 
@@ -47,6 +136,83 @@ func NewQuery(r *http.Request) (Query, error) {
 	return q, nil
 }
 
+// Validate checks the query before it is used for a search.
+//
+// It reports every problem found: an empty service or method, the nil UUID
+// as ID, an unknown match mode override, a field mask path with an empty
+// segment, a negative scoring weight, and a match mode override combined
+// with an ID, since searching by ID does not match the input at all.
+//
+// An empty method is reported, use ValidateWith or Budgerigar.ValidateQuery
+// to accept it as the method wildcard.
+//
+// Returns:
+// - error: The joined validation errors, or nil if the query is valid.
+func (q Query) Validate() error {
+	return q.ValidateWith(false)
+}
+
+// ValidateWith checks the query like Validate, but accepts an empty method if
+// wildcard is set, as searchers created with WithMethodWildcard do.
+//
+// Parameters:
+// - wildcard: Whether an empty method matches the stubs of any method.
+//
+// Returns:
+// - error: The joined validation errors, or nil if the query is valid.
+func (q Query) ValidateWith(wildcard bool) error {
+	var errs []error
+
+	if q.Service == "" {
+		errs = append(errs, ErrQueryServiceEmpty)
+	}
+
+	if q.Method == "" && !wildcard {
+		errs = append(errs, ErrQueryMethodEmpty)
+	}
+
+	if q.ID != nil && *q.ID == uuid.Nil {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidID, q.ID))
+	}
+
+	switch q.MatchModeOverride {
+	case "", MatchModeEquals, MatchModeContains:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidMatchMode, q.MatchModeOverride))
+	}
+
+	if !validFieldMask(q.FieldMask) {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidFieldMask, q.FieldMask))
+	}
+
+	if q.Scoring != nil && (q.Scoring.Headers < 0 || q.Scoring.Body < 0) {
+		errs = append(errs, fmt.Errorf("%w: %+v", ErrInvalidScoring, *q.Scoring))
+	}
+
+	if q.ID != nil && q.MatchModeOverride != "" {
+		errs = append(errs, fmt.Errorf("%w: id and matchModeOverride", ErrConflictingFields))
+	}
+
+	return errors.Join(errs...)
+}
+
 func (q Query) RequestInternal() bool {
 	return q.toggles.Has(RequestInternalFlag)
 }
+
+// splitFullName splits a full method name into its service and method.
+//
+// Both "service/method" and the gRPC form "/service/method" are accepted.
+//
+// Returns:
+// - string: The service name.
+// - string: The method name.
+// - error: ErrInvalidFullName if either part is empty or the name has extra slashes.
+func splitFullName(fullName string) (string, string, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullName, "/"), "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return "", "", ErrInvalidFullName
+	}
+
+	return service, method, nil
+}
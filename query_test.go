@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bavix/features"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/gripmock/stuber"
@@ -40,3 +42,45 @@ func TestQuery_NewExternal(t *testing.T) {
 	require.Equal(t, "Mundo", q.Data["Hola"])
 	require.False(t, q.RequestInternal())
 }
+
+func TestQuery_Validate(t *testing.T) {
+	id := uuid.New()
+	nilID := uuid.Nil
+
+	require.NoError(t, stuber.Query{Service: "Testing", Method: "TestMethod"}.Validate())
+	require.NoError(t, stuber.Query{ID: &id, Service: "Testing", Method: "TestMethod"}.Validate())
+	require.NoError(t, stuber.Query{
+		Service:           "Testing",
+		Method:            "TestMethod",
+		MatchModeOverride: stuber.MatchModeContains,
+	}.Validate())
+
+	err := stuber.Query{}.Validate()
+	require.ErrorIs(t, err, stuber.ErrQueryServiceEmpty)
+	require.ErrorIs(t, err, stuber.ErrQueryMethodEmpty)
+	require.EqualError(t, err, "query has no service\nquery has no method")
+
+	// The method wildcard accepts an empty method.
+	require.NoError(t, stuber.Query{Service: "Testing"}.ValidateWith(true))
+	require.ErrorIs(t, stuber.Query{}.ValidateWith(true), stuber.ErrQueryServiceEmpty)
+	require.ErrorIs(t, stuber.NewBudgerigar(features.New()).ValidateQuery(stuber.Query{Service: "Testing"}), stuber.ErrQueryMethodEmpty)
+	require.NoError(t, stuber.NewBudgerigar(features.New(), stuber.WithMethodWildcard()).ValidateQuery(stuber.Query{Service: "Testing"}))
+
+	err = stuber.Query{ID: &nilID, Service: "Testing", Method: "TestMethod"}.Validate()
+	require.ErrorIs(t, err, stuber.ErrInvalidID)
+
+	err = stuber.Query{Service: "Testing", Method: "TestMethod", MatchModeOverride: "regex"}.Validate()
+	require.ErrorIs(t, err, stuber.ErrInvalidMatchMode)
+	require.ErrorContains(t, err, `"regex"`)
+
+	err = stuber.Query{Service: "Testing", Method: "TestMethod", FieldMask: []string{"user.", "name"}}.Validate()
+	require.ErrorIs(t, err, stuber.ErrInvalidFieldMask)
+
+	err = stuber.Query{
+		ID:                &id,
+		Service:           "Testing",
+		Method:            "TestMethod",
+		MatchModeOverride: stuber.MatchModeEquals,
+	}.Validate()
+	require.ErrorIs(t, err, stuber.ErrConflictingFields)
+}
@@ -0,0 +1,63 @@
+package stuber
+
+import (
+	"cmp"
+	"slices"
+)
+
+// RankedStub is a stub with its rank for a query.
+type RankedStub struct {
+	Stub    *Stub   `json:"stub"`    // The ranked stub.
+	Rank    float64 `json:"rank"`    // The rank of the stub for the query.
+	Matched bool    `json:"matched"` // Whether a search would consider the stub a match.
+}
+
+// rankAll ranks every stub of the query's service and method, e.g. to chart
+// why a stub wins over the others.
+//
+// The query is transformed and normalized like by a search, but nothing is
+// marked, counted, captured or logged. Disabled, quarantined and not yet
+// activated stubs are ranked as well but never matched. Match probabilities
+// are not drawn, a flaky stub is reported as matched if its matchers match.
+//
+// Parameters:
+// - query: The Query to rank the Stub values for.
+//
+// Returns:
+// - []RankedStub: The Stub values with their ranks, highest first, ties in storage order.
+// - error: ErrServiceNotFound or ErrMethodNotFound if there are no such Stub values.
+func (s *searcher) rankAll(query Query) ([]RankedStub, error) {
+	query = s.transform(query)
+
+	// Treat absent data and headers as empty, like a search does.
+	if query.Data == nil {
+		query.Data = map[string]any{}
+	}
+
+	if query.Headers == nil {
+		query.Headers = map[string]any{}
+	}
+
+	stubs, err := s.findBy(query.Service, query.Method)
+	if err != nil {
+		return nil, s.wrap(err)
+	}
+
+	normalized := s.normalization.query(query)
+	mode := s.serviceDefault(query.Service)
+
+	results := make([]RankedStub, 0, len(stubs))
+
+	for _, stub := range stubs {
+		matched, rank := s.matchStub(normalized, withMatchMode(s.normalization.stub(stub), mode))
+		matched = matched && !stub.Disabled && s.activated(stub) && !s.quarantine.has(stub.ID)
+
+		results = append(results, RankedStub{Stub: stub, Rank: rank, Matched: matched})
+	}
+
+	slices.SortStableFunc(results, func(a, b RankedStub) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+
+	return results, nil
+}
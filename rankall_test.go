@@ -0,0 +1,76 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_RankAll(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(name string, team interface{}) *stuber.Stub {
+		stub := &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Get",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"name": name}},
+			Output:  stuber.Output{Error: name},
+		}
+
+		if team != nil {
+			stub.Headers = stuber.InputHeader{Equals: map[string]interface{}{"x-team": team}}
+		}
+
+		return stub
+	}
+
+	bob, core, dave := newStub("Bob", nil), newStub("Bob", "core"), newStub("Dave", nil)
+	disabled := newStub("Bob", "core")
+	disabled.Disabled = true
+
+	s.PutMany(bob, dave, core, disabled)
+
+	query := stuber.Query{
+		Service: "Users",
+		Method:  "Get",
+		Headers: map[string]interface{}{"x-team": "core"},
+		Data:    map[string]interface{}{"name": "Bob"},
+	}
+
+	ranked, err := s.RankAll(query)
+	require.NoError(t, err)
+	require.Len(t, ranked, 4)
+
+	// The stub requiring the header ranks first, the disabled one ties with it but never matches.
+	require.Same(t, core, ranked[0].Stub)
+	require.True(t, ranked[0].Matched)
+	require.Same(t, disabled, ranked[1].Stub)
+	require.False(t, ranked[1].Matched)
+	require.Equal(t, ranked[0].Rank, ranked[1].Rank)
+	require.Same(t, bob, ranked[2].Stub)
+	require.True(t, ranked[2].Matched)
+	require.Greater(t, ranked[1].Rank, ranked[2].Rank)
+	require.Same(t, dave, ranked[3].Stub)
+	require.False(t, ranked[3].Matched)
+	require.GreaterOrEqual(t, ranked[2].Rank, ranked[3].Rank)
+
+	// The winner's rank is the one a search reports.
+	eval, err := s.Evaluate(query)
+	require.NoError(t, err)
+	require.Same(t, core, eval.Found)
+	require.InDelta(t, ranked[0].Rank, eval.Score, 0)
+
+	// Ranking marks nothing.
+	require.Empty(t, s.Used())
+
+	_, err = s.RankAll(stuber.Query{Service: "Users", Method: "List"})
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+
+	_, err = s.RankAll(stuber.Query{Service: "Teams", Method: "Get"})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+}
@@ -0,0 +1,31 @@
+package stuber
+
+import "github.com/google/uuid"
+
+// ReadOnlySearcher is the read-only part of a Budgerigar, see
+// Budgerigar.ReadOnly.
+//
+// It has no method changing the stored stubs, locking services or resetting
+// the searcher, so a holder cannot alter the stub set. Searches through it
+// still count as uses of the found stubs, like any other search.
+type ReadOnlySearcher interface {
+	FindByID(id uuid.UUID) *Stub
+	FindByIDPrefix(prefix string, unique bool) ([]*Stub, error)
+	FindBy(service, method string) ([]*Stub, error)
+	FindByQuery(query Query) (*Result, error)
+	FindByFullName(fullName string, query Query) (*Result, error)
+	Evaluate(query Query) (Evaluation, error)
+	All() []*Stub
+	AllOrdered() []*Stub
+	Used() []*Stub
+	Unused() []*Stub
+	PartitionByUsage() ([]*Stub, []*Stub)
+	ETag() string
+	Export() ([]byte, error)
+}
+
+// readOnly hides every method of the Budgerigar but the read-only ones, so
+// a type assertion cannot reach the mutating methods.
+type readOnly struct {
+	ReadOnlySearcher
+}
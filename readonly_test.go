@@ -0,0 +1,41 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ReadOnly(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+
+	s.PutMany(stub)
+
+	view := s.ReadOnly()
+
+	r, err := view.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+	require.Same(t, stub, view.FindByID(stub.ID))
+	require.Equal(t, []*stuber.Stub{stub}, view.Used())
+
+	// The handle cannot be turned back into a mutable searcher.
+	_, ok := view.(*stuber.Budgerigar)
+	require.False(t, ok)
+
+	_, ok = view.(interface{ Clear() })
+	require.False(t, ok)
+
+	_, ok = view.(interface{ DeleteByID(ids ...uuid.UUID) int })
+	require.False(t, ok)
+
+	// Changes made through the searcher are visible.
+	s.Clear()
+	require.Empty(t, view.All())
+}
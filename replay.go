@@ -0,0 +1,62 @@
+package stuber
+
+import "github.com/google/uuid"
+
+// ReplayResult is the outcome of a replayed query.
+type ReplayResult struct {
+	StubID uuid.UUID `json:"stubId"` // The ID of the matched stub, uuid.Nil if none matched.
+	Err    error     `json:"-"`      // The error of the search, ErrStubNotFound if no stub matched.
+}
+
+// replay runs the queries in order and reports the stub each one matches,
+// e.g. to keep golden files of captured queries and the stubs they route to.
+//
+// By default the queries are evaluated without side effects: no stub is
+// marked as used, counted, captured or logged, so every query sees the same
+// state. With mark set, each query is searched like by find, so later
+// queries see the effects of earlier ones, e.g. on occurrences. A search
+// finding only a similar stub is reported as ErrStubNotFound.
+//
+// Parameters:
+// - queries: The queries to replay.
+// - mark: Whether to search the queries with their side effects.
+//
+// Returns:
+// - []ReplayResult: The outcome of every query, in the order of the queries.
+func (s *searcher) replay(queries []Query, mark bool) []ReplayResult {
+	results := make([]ReplayResult, len(queries))
+
+	for i, query := range queries {
+		var found *Stub
+
+		if mark {
+			result, err := s.find(query)
+			if err != nil {
+				results[i].Err = err
+
+				continue
+			}
+
+			found = result.Found()
+		} else {
+			eval, err := s.evaluate(s.transform(query), false)
+			if err != nil {
+				results[i].Err = err
+
+				continue
+			}
+
+			found = eval.Found
+		}
+
+		if found == nil {
+			results[i].Err = ErrStubNotFound
+
+			continue
+		}
+
+		results[i].StubID = found.ID
+	}
+
+	return results
+}
@@ -0,0 +1,66 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Replay(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(name string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Get",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"name": name}},
+			Output:  stuber.Output{Error: name},
+		}
+	}
+
+	bob, carol := newStub("Bob"), newStub("Carol")
+
+	s.PutMany(bob, carol)
+
+	queries := []stuber.Query{
+		{Service: "Users", Method: "Get", Data: map[string]interface{}{"name": "Bob"}},
+		{Service: "Users", Method: "Get", Data: map[string]interface{}{"name": "Carol"}},
+		{Service: "Users", Method: "Get", Data: map[string]interface{}{"name": "Dave"}},
+		{Service: "Users", Method: "List"},
+		{Service: "Teams", Method: "Get"},
+	}
+
+	golden := s.Replay(queries, false)
+	require.Len(t, golden, 5)
+	require.Equal(t, bob.ID, golden[0].StubID)
+	require.NoError(t, golden[0].Err)
+	require.Equal(t, carol.ID, golden[1].StubID)
+	require.ErrorIs(t, golden[2].Err, stuber.ErrStubNotFound)
+	require.Equal(t, uuid.Nil, golden[2].StubID)
+	require.ErrorIs(t, golden[3].Err, stuber.ErrMethodNotFound)
+	require.ErrorIs(t, golden[4].Err, stuber.ErrServiceNotFound)
+
+	// Replaying without marking has no side effects.
+	require.Empty(t, s.Used())
+
+	// Editing a stub alters the routing of the replayed queries.
+	edited := *bob
+	edited.Input = stuber.InputData{Contains: map[string]interface{}{"name": "Dave"}}
+
+	s.UpdateMany(&edited)
+
+	replayed := s.Replay(queries, false)
+	require.ErrorIs(t, replayed[0].Err, stuber.ErrStubNotFound)
+	require.Equal(t, golden[1], replayed[1])
+	require.Equal(t, bob.ID, replayed[2].StubID)
+	require.Equal(t, golden[3:], replayed[3:])
+
+	// Replaying with marking searches the queries.
+	s.Replay(queries[1:2], true)
+	require.Equal(t, []*stuber.Stub{carol}, s.Used())
+}
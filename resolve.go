@@ -0,0 +1,94 @@
+package stuber
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// importResolve parses a JSON array of Stub values, drops the ones conflicting
+// with a Stub value of higher priority and loads the others into the
+// searcher, e.g. to merge the overlapping fixtures of several teams.
+//
+// Two Stub values conflict when they have the same service, method and
+// matchers, whatever their responses. Of conflicting Stub values, the one
+// with the highest Priority is kept, the first one in the payload on equal
+// priorities. Only the Stub values of the payload are compared with each
+// other, stored ones are replaced by ID as usual. Loading is transactional
+// like importJSON, nothing is dropped if it fails.
+//
+// Parameters:
+// - data: The JSON array of Stub values.
+//
+// Returns:
+// - []*Stub: The dropped Stub values, in the order of the payload.
+// - error: An error if the payload cannot be parsed or any kept Stub value is invalid.
+func (s *searcher) importResolve(data []byte) ([]*Stub, error) {
+	var stubs []*Stub
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&stubs); err != nil {
+		return nil, err
+	}
+
+	// Find the winner of every set of conflicting Stub values.
+	keys := make([]string, len(stubs))
+	winners := make(map[string]*Stub)
+
+	for i, stub := range stubs {
+		if stub == nil {
+			continue
+		}
+
+		keys[i] = conflictKey(stub)
+
+		if winner, ok := winners[keys[i]]; !ok || stub.Priority > winner.Priority {
+			winners[keys[i]] = stub
+		}
+	}
+
+	kept := make([]*Stub, 0, len(winners))
+	dropped := make([]*Stub, 0)
+
+	for i, stub := range stubs {
+		if stub == nil || winners[keys[i]] == stub {
+			kept = append(kept, stub)
+		} else {
+			dropped = append(dropped, stub)
+		}
+	}
+
+	if err := s.load(kept); err != nil {
+		return nil, err
+	}
+
+	return dropped, nil
+}
+
+// conflictKey returns the JSON of the stub without its ID, creation time,
+// priority and responses, so that stubs matching the same queries the same
+// way share it.
+func conflictKey(stub *Stub) string {
+	key := *stub
+	key.ID = uuid.Nil
+	key.CreatedAt = time.Time{}
+	key.Priority = 0
+	key.Output = Output{}
+	key.Occurrences = nil
+	key.Switch = nil
+	key.Captures = nil
+	key.HeaderCaptures = nil
+	key.Latency = nil
+
+	raw, err := json.Marshal(key)
+	if err != nil {
+		// A stub that cannot be encoded conflicts with no other stub.
+		return uuid.NewString()
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,30 @@
+package stuber
+
+// WithRoundRobin makes searches rotate through the matching stubs that tie
+// for the highest rank, instead of always returning the first one.
+//
+// The rotation is tracked per service and method of the query and advances
+// on every search that finds a stub, except internal requests. Clear resets it.
+// Concurrent searches take distinct turns, so they rotate as well.
+func WithRoundRobin() Option {
+	return func(s *searcher) {
+		s.roundRobin = true
+	}
+}
+
+// turn returns the rotation counter of the query's service and method, and
+// moves the rotation to the next stub in the same step if advance is set,
+// except for internal requests.
+func (s *searcher) turn(query Query, advance bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := group{service: query.Service, method: query.Method}
+	turn := s.turns[key]
+
+	if advance && !query.RequestInternal() {
+		s.turns[key]++
+	}
+
+	return turn
+}
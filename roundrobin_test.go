@@ -0,0 +1,86 @@
+package stuber_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_RoundRobin(t *testing.T) {
+	stubs := make([]*stuber.Stub, 0, 3)
+
+	for _, message := range []string{"first", "second", "third"} {
+		stubs = append(stubs, &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  "SayHello",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": message}},
+		})
+	}
+
+	query := stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "bob"}}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stubs...)
+
+	for range 3 {
+		r, err := s.FindByQuery(query)
+		require.NoError(t, err)
+		require.Same(t, stubs[0], r.Found())
+	}
+
+	s = stuber.NewBudgerigar(features.New(), stuber.WithRoundRobin())
+	s.PutMany(stubs...)
+
+	for i := range 7 {
+		r, err := s.FindByQuery(query)
+		require.NoError(t, err)
+		require.Same(t, stubs[i%3], r.Found())
+	}
+
+	s.Clear()
+	s.PutMany(stubs...)
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stubs[0], r.Found())
+
+	// Concurrent searches take distinct turns.
+	s.Clear()
+	s.PutMany(stubs...)
+
+	const workers = 30
+
+	seen := make(chan *stuber.Stub, workers)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			r, err := s.FindByQuery(query)
+			if err == nil {
+				seen <- r.Found()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(seen)
+
+	counts := make(map[*stuber.Stub]int, len(stubs))
+	for stub := range seen {
+		counts[stub]++
+	}
+
+	require.Equal(t, map[*stuber.Stub]int{stubs[0]: 10, stubs[1]: 10, stubs[2]: 10}, counts)
+}
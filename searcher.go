@@ -1,12 +1,22 @@
 package stuber
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"maps"
+	"math/rand/v2"
+	"reflect"
 	"slices"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 )
 
 // ErrServiceNotFound is returned when the service is not found.
@@ -18,28 +28,189 @@ var ErrMethodNotFound = errors.New("method not found")
 // ErrStubNotFound is returned when the stub is not found.
 var ErrStubNotFound = errors.New("stub not found")
 
+// ErrMaxDepthExceeded is returned together with ErrStubNotFound when the query
+// is nested deeper than the searcher allows.
+var ErrMaxDepthExceeded = errors.New("max depth exceeded")
+
+// MethodPrefixToken ends a stub method that matches every method starting
+// with the text before it, e.g. "Batch*".
+const MethodPrefixToken = "*"
+
+// defaultMaxDepth is the default maximum nesting depth of query data.
+const defaultMaxDepth = 64
+
 // searcher is a struct that manages the storage of search results.
 //
 // It contains a mutex for concurrent access, a map to store and retrieve
 // used stubs by their UUID, and a pointer to the storage struct.
 type searcher struct {
-	mu       sync.RWMutex // mutex for concurrent access
-	stubUsed map[uuid.UUID]struct{}
-	// map to store and retrieve used stubs by their UUID
+	mu       timedRWMutex            // mutex for concurrent access
+	stubUsed map[uuid.UUID]time.Time // used stubs by their UUID, with the time of their last use
 
 	storage *storage // pointer to the storage struct
+
+	maxDepth     int           // maximum nesting depth of query data, zero disables the guard
+	matchTimeout time.Duration // deadline of custom and regex stub matching, zero disables it
+	usedTTL      time.Duration // time the used marks last after the last use, zero keeps them
+
+	methodWildcard bool // whether an empty method matches the stubs of any method
+	similarN       int  // number of similar candidates collected for every query, zero disables it
+
+	minSimilarRank float64 // rank below which similar stubs are not reported
+
+	normalization Normalization // string normalizations applied before matching
+	roundRobin    bool          // whether searches rotate through stubs tying for the highest rank
+	tieSeed       string        // query field whose hash picks among stubs tying for the highest rank
+
+	outcomes outcomes // counters of search outcomes
+
+	captured map[string]any // values captured from the outputs of matched stubs
+	hits     map[group]int  // number of matches per service and method
+	turns    map[group]int  // round-robin counters per service and method
+
+	occurrences map[uuid.UUID]int // number of matches per stub
+	calls       map[uuid.UUID]int // number of uses per stub, kept in step with stubUsed
+
+	overrides map[uuid.UUID]outputOverride // temporary outputs per stub
+	pins      map[group][]pin              // stubs forced as responses per service and method
+
+	generation    uint64               // generation of the last change of the stub set
+	modGeneration map[uuid.UUID]uint64 // generation of the last change per stored stub
+	tombstones    map[uuid.UUID]uint64 // generation of the deletion per deleted stub
+
+	subscribers subscribers // subscribers to changes of the stub set
+	searchLog   *searchLog  // recent searches that found a stub, nil when disabled
+	quarantine  quarantine  // stubs excluded from searches because their matchers panicked
+	celPrograms celPrograms // compiled CEL programs of the stored stubs
+	exprNodes   exprNodes   // parsed matcher expressions of the stored stubs
+	lockTiming  *lockTiming // waits for the locks, nil when disabled
+
+	onMiss func(query Query, err error) // called when a search finds no stub, nil when disabled
+
+	lockedServices map[string]struct{}  // services whose stubs cannot be written
+	serviceModes   map[string]MatchMode // match modes of the stubs without one, keyed by service
+
+	contentIDs bool // whether stubs without an ID get an ID derived from their content
+
+	queryTransform func(query Query) Query // rewrites queries before matching, nil when disabled
+
+	normalizers map[string]func(any) any // field normalizers stubs reference by name
+
+	latencySource func() float64 // random numbers in [0, 1) sampling the latency profiles
+	matchSource   func() float64 // random numbers in [0, 1) drawn for the match probabilities
+}
+
+// Option configures a searcher.
+type Option func(*searcher)
+
+// WithMaxDepth sets the maximum nesting depth of query data and headers.
+//
+// Queries nested deeper than the limit do not match any stub. A non-positive
+// depth disables the guard.
+func WithMaxDepth(depth int) Option {
+	return func(s *searcher) {
+		s.maxDepth = max(depth, 0)
+	}
+}
+
+// WithMatchTimeout sets the deadline for matching a single stub that has
+// custom or regular expression matchers.
+//
+// A stub that does not finish in time is treated as a non-match. A
+// non-positive timeout disables the deadline, which is the default.
+func WithMatchTimeout(timeout time.Duration) Option {
+	return func(s *searcher) {
+		s.matchTimeout = max(timeout, 0)
+	}
+}
+
+// WithMethodWildcard makes a query with an empty method match the stubs of
+// every method of the service. Without it, an empty method is reported as
+// ErrMethodNotFound.
+func WithMethodWildcard() Option {
+	return func(s *searcher) {
+		s.methodWildcard = true
+	}
+}
+
+// WithSimilarCandidates makes every search that finds no match collect the
+// highest ranked near-misses, available through Result.SimilarN.
+//
+// A non-positive limit collects the default of three candidates.
+func WithSimilarCandidates(limit int) Option {
+	return func(s *searcher) {
+		s.similarN = cmp.Or(max(limit, 0), defaultSimilarLimit)
+	}
+}
+
+// WithCopyOnWrite makes the storage copy its maps on every write, so that
+// searches read an immutable snapshot without taking any lock.
+//
+// It suits read-heavy workloads where stubs are rarely changed after they
+// are loaded, since each write copies all the stored stubs.
+func WithCopyOnWrite() Option {
+	return func(s *searcher) {
+		s.storage = newCopyOnWriteStorage()
+	}
+}
+
+// WithOnMiss sets a function called with the query and the error of every
+// search that ends in ErrServiceNotFound, ErrMethodNotFound or
+// ErrStubNotFound, e.g. to record templates of the missing stubs.
+//
+// The function is called after the search, without holding any lock.
+func WithOnMiss(onMiss func(query Query, err error)) Option {
+	return func(s *searcher) {
+		s.onMiss = onMiss
+	}
+}
+
+// WithQueryTransform sets a function that rewrites every query before it is
+// matched, e.g. to normalize fields in one place instead of at every caller.
+//
+// The transformed query is used for matching, marking and logging alike.
+func WithQueryTransform(transform func(query Query) Query) Option {
+	return func(s *searcher) {
+		s.queryTransform = transform
+	}
 }
 
 // newSearcher creates a new instance of the searcher struct.
 //
-// It initializes the stubUsed map and the storage pointer.
+// It initializes the stubUsed map and the storage pointer and applies the
+// given options.
 //
 // Returns a pointer to the newly created searcher struct.
-func newSearcher() *searcher {
-	return &searcher{
+func newSearcher(opts ...Option) *searcher {
+	s := &searcher{
 		storage:  newStorage(),
-		stubUsed: make(map[uuid.UUID]struct{}),
+		stubUsed: make(map[uuid.UUID]time.Time),
+		maxDepth: defaultMaxDepth,
+		captured: make(map[string]any),
+		hits:     make(map[group]int),
+		turns:    make(map[group]int),
+
+		occurrences: make(map[uuid.UUID]int),
+		calls:       make(map[uuid.UUID]int),
+		overrides:   make(map[uuid.UUID]outputOverride),
+		normalizers: make(map[string]func(any) any),
+
+		modGeneration: make(map[uuid.UUID]uint64),
+		tombstones:    make(map[uuid.UUID]uint64),
+
+		latencySource: rand.Float64,
+		matchSource:   rand.Float64,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	// Time the locks of the storage chosen by the options.
+	s.mu.timing = s.lockTiming
+	s.storage.mu.timing = s.lockTiming
+
+	return s
 }
 
 // Result represents the result of a search operation.
@@ -48,8 +219,14 @@ func newSearcher() *searcher {
 // match found in the search, while similar represents the most similar match
 // found.
 type Result struct {
-	found   *Stub // The exact match found in the search
-	similar *Stub // The most similar match found
+	found    *Stub   // The exact match found in the search
+	similar  *Stub   // The most similar match found
+	similarN []*Stub // The most similar matches found, sorted by rank
+	output   Output  // The output of the found match resolved for the query
+
+	rank          float64         // The rank of the found match, zero for searches by ID
+	latency       *LatencyProfile // The latency profile of the found match, if any
+	latencySource func() float64  // The random numbers sampling the latency profile
 }
 
 // Found returns the exact match found in the search.
@@ -66,20 +243,294 @@ func (r *Result) Similar() *Stub {
 	return r.similar
 }
 
+// SimilarN returns the most similar matches found in the search, sorted by
+// rank in descending order.
+//
+// The candidates are only collected when the searcher is configured with
+// WithSimilarCandidates or the query sets SimilarCandidates. The first one
+// is the same stub as Similar.
+func (r *Result) SimilarN() []*Stub {
+	return r.similarN
+}
+
+// Output returns the output of the found match resolved for the query.
+//
+// Unlike Found().Output, it takes the stub's output switch into account.
+// Returns an empty Output if nothing was found.
+func (r *Result) Output() Output {
+	return r.output
+}
+
+// ResponseHeaders returns the headers the found stub responds with, resolved
+// for the query like Output.
+func (r *Result) ResponseHeaders() map[string]string {
+	return r.output.Headers
+}
+
+// ResponseTrailers returns the trailers the found stub responds with,
+// resolved for the query like Output.
+func (r *Result) ResponseTrailers() map[string]string {
+	return r.output.Trailers
+}
+
+// Status returns the gRPC status code and message carried by the found stub.
+//
+// The last return value is false when nothing was found or the found stub
+// responds with a payload instead of an error status.
+func (r *Result) Status() (codes.Code, string, bool) {
+	return r.output.Status()
+}
+
+// SampleDelay draws the delay to respond with from the latency profile of
+// the found stub. Every call draws a new delay, the caller sleeps.
+//
+// Returns zero if nothing was found or the found stub has no latency profile.
+func (r *Result) SampleDelay() time.Duration {
+	if r.latency == nil {
+		return 0
+	}
+
+	return r.latency.sample(r.latencySource())
+}
+
 // upsert inserts the given stub values into the searcher. If a stub value
 // already exists with the same key, it is updated.
 //
-// The function returns a slice of UUIDs representing the keys of the
-// inserted or updated values.
-func (s *searcher) upsert(values ...*Stub) []uuid.UUID {
-	return s.storage.upsert(s.castToValue(values)...)
+// The environment references of the matchers are resolved before storing.
+// Nothing is written if any stub value has a CEL or matcher expression or a
+// peer range that does not compile, references an unset environment variable
+// or an unregistered normalizer, or belongs, or is moved away from, a locked
+// service.
+//
+// Returns:
+// - []uuid.UUID: The keys of the inserted or updated values.
+// - error: An error wrapping ErrInvalidCEL, ErrInvalidExpression,
+// ErrInvalidPeer, ErrUnresolvedEnv, ErrUnknownNormalizer or ErrServiceLocked.
+func (s *searcher) upsert(values ...*Stub) ([]uuid.UUID, error) {
+	now := time.Now()
+
+	added := make([]uuid.UUID, 0, len(values))
+	updated := make([]uuid.UUID, 0)
+
+	// Resolve the environment references and compile the CEL expressions and
+	// peer ranges before touching any stub.
+	inputs := make([]InputData, len(values))
+	headers := make([]InputHeader, len(values))
+
+	for i, value := range values {
+		var err error
+
+		inputs[i], headers[i], err = prepare(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+
+	// Refuse to write the stubs of locked services, or referencing unknown
+	// normalizers, before touching any of them.
+	for _, value := range values {
+		services := []string{value.Service}
+		if prev := s.findByID(value.ID); prev != nil {
+			services = append(services, prev.Service)
+		}
+
+		if err := s.checkUnlocked(services...); err != nil {
+			s.mu.Unlock()
+
+			return nil, err
+		}
+
+		if err := s.checkNormalizers(value); err != nil {
+			s.mu.Unlock()
+
+			return nil, err
+		}
+	}
+
+	for i, value := range values {
+		value.Input, value.Headers = inputs[i], headers[i]
+
+		prev := s.findByID(value.ID)
+
+		if prev != nil {
+			updated = append(updated, value.ID)
+		} else {
+			added = append(added, value.ID)
+		}
+
+		// Stamp the creation time, keeping the one of the replaced stub.
+		if !value.CreatedAt.IsZero() {
+			continue
+		}
+
+		if prev != nil && !prev.CreatedAt.IsZero() {
+			value.CreatedAt = prev.CreatedAt
+		} else {
+			value.CreatedAt = now
+		}
+	}
+
+	ids := s.storage.upsert(s.castToValue(values)...)
+	s.touch(ids...)
+
+	s.mu.Unlock()
+
+	s.subscribers.emit(ChangeAdded, added)
+	s.subscribers.emit(ChangeUpdated, updated)
+
+	return ids, nil
+}
+
+// prepare resolves the environment references of the stub's matchers and
+// compiles its CEL and matcher expressions and peer ranges, to check a stub
+// before it is written.
+//
+// Returns:
+// - InputData: The input matchers with the environment references resolved.
+// - InputHeader: The header matchers with the environment references resolved.
+// - error: An error wrapping ErrUnresolvedEnv, ErrInvalidPeer, ErrInvalidCEL
+// or ErrInvalidExpression.
+func prepare(value *Stub) (InputData, InputHeader, error) {
+	input, headers, err := value.withEnv()
+	if err != nil {
+		return InputData{}, InputHeader{}, fmt.Errorf("stub %s: %w", value.ID, err)
+	}
+
+	if err := compilePeer(value.PeerMatch); err != nil {
+		return InputData{}, InputHeader{}, fmt.Errorf("stub %s: %w", value.ID, err)
+	}
+
+	if value.CEL != "" {
+		if _, err := compileCEL(value.CEL); err != nil {
+			return InputData{}, InputHeader{}, fmt.Errorf("stub %s: %w", value.ID, err)
+		}
+	}
+
+	if value.Expr != "" {
+		if _, err := compileExpr(value.Expr); err != nil {
+			return InputData{}, InputHeader{}, fmt.Errorf("stub %s: %w", value.ID, err)
+		}
+	}
+
+	return input, headers, nil
+}
+
+// merge applies the non-zero fields of the patch onto the stored stub with
+// the given ID.
+//
+// Nested structs are merged field by field, while maps and slices from the
+// patch replace the stored ones. The ID of the patch is ignored. The merged
+// stub is checked like by upsert, and replaces the stored one under the
+// storage write lock.
+//
+// Parameters:
+// - id: The UUID of the stub to patch.
+// - patch: The stub holding the fields to apply.
+//
+// Returns:
+// - error: ErrStubNotFound if there is no stub with the given ID, or an
+// error wrapping ErrInvalidCEL, ErrInvalidExpression, ErrInvalidPeer,
+// ErrUnresolvedEnv, ErrUnknownNormalizer or ErrServiceLocked if the merged
+// stub is refused.
+func (s *searcher) merge(id uuid.UUID, patch *Stub) error {
+	s.mu.Lock()
+
+	stored := s.findByID(id)
+	if stored == nil {
+		s.mu.Unlock()
+
+		return ErrStubNotFound
+	}
+
+	if err := s.checkUnlocked(stored.Service, patch.Service); err != nil {
+		s.mu.Unlock()
+
+		return err
+	}
+
+	// Copy the stored stub, so readers holding the previous pointer are not affected.
+	merged := *stored
+
+	mergeValue(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(patch).Elem())
+	merged.ID = id
+
+	input, headers, err := prepare(&merged)
+	if err == nil {
+		err = s.checkNormalizers(&merged)
+	}
+
+	if err != nil {
+		s.mu.Unlock()
+
+		return err
+	}
+
+	merged.Input, merged.Headers = input, headers
+
+	s.storage.update(id, func(Value) Value {
+		return &merged
+	})
+	s.touch(id)
+
+	s.mu.Unlock()
+
+	s.subscribers.emit(ChangeUpdated, []uuid.UUID{id})
+
+	return nil
+}
+
+// mergeValue copies the non-zero fields of src onto dst, recursing into
+// nested structs.
+func mergeValue(dst, src reflect.Value) {
+	for i := range src.NumField() {
+		field := src.Field(i)
+		if field.IsZero() || !dst.Field(i).CanSet() {
+			continue
+		}
+
+		if field.Kind() == reflect.Struct {
+			mergeValue(dst.Field(i), field)
+
+			continue
+		}
+
+		dst.Field(i).Set(field)
+	}
 }
 
 // del deletes the stub values with the given UUIDs from the searcher.
 //
-// Returns the number of stub values that were successfully deleted.
-func (s *searcher) del(ids ...uuid.UUID) int {
-	return s.storage.del(ids...)
+// Nothing is deleted if any stub value belongs to a locked service.
+//
+// Returns:
+// - int: The number of stub values that were successfully deleted.
+// - error: An error wrapping ErrServiceLocked if a locked service is affected.
+func (s *searcher) del(ids ...uuid.UUID) (int, error) {
+	s.mu.Lock()
+
+	// Collect the IDs that are stored, so only those are reported.
+	deleted := make([]uuid.UUID, 0, len(ids))
+
+	for _, v := range s.storage.findByIDs(ids...) {
+		if err := s.checkUnlocked(v.Left()); err != nil {
+			s.mu.Unlock()
+
+			return 0, err
+		}
+
+		deleted = append(deleted, v.Key())
+	}
+
+	n := s.storage.del(ids...)
+	s.bury(deleted...)
+
+	s.mu.Unlock()
+
+	s.subscribers.emit(ChangeDeleted, deleted)
+
+	return n, nil
 }
 
 // findByID retrieves the stub value associated with the given ID from the
@@ -102,34 +553,180 @@ func (s *searcher) findByID(id uuid.UUID) *Stub {
 // - service: The service field used to search for Stub values.
 // - method: The method field used to search for Stub values.
 //
+// The returned slice is cached and shared between concurrent readers until
+// the stubs of the given service and method change, so it must not be modified.
+// If the searcher has the method wildcard enabled, an empty method returns the
+// Stub values of all methods of the service. If no Stub value has the exact
+// method, the Stub values with a matching method prefix are returned.
+//
 // Returns:
 // - []*Stub: The Stub values that match the given service and method, or nil if not found.
 // - error: An error if the search fails.
 func (s *searcher) findBy(service, method string) ([]*Stub, error) {
+	// Treat an empty method as a wildcard if enabled.
+	if method == "" && s.methodWildcard {
+		all, err := s.storage.findByLeft(service)
+		if err != nil {
+			return nil, s.wrap(err)
+		}
+
+		return s.castToStub(all), nil
+	}
+
 	// Retrieve all Stub values that match the given service and method from the storage.
-	all, err := s.storage.findAll(service, method)
+	view, err := s.storage.view(service, method, func(values []Value) any {
+		// Cast the values to Stub pointers once per change.
+		return s.castToStub(values)
+	})
+
+	// The view is nil if the lookup failed.
+	stubs, _ := view.([]*Stub)
+	if err == nil && len(stubs) > 0 {
+		return stubs, nil
+	}
+
+	// Fall back to the Stub values with a method prefix if the exact lookup missed.
+	if !errors.Is(err, ErrLeftNotFound) {
+		if prefixed := s.findByPrefix(service, method); len(prefixed) > 0 {
+			return prefixed, nil
+		}
+	}
+
 	if err != nil {
 		return nil, s.wrap(err)
 	}
 
-	// Cast the values to Stub pointers and return.
-	return s.castToStub(all), nil
+	return stubs, nil
+}
+
+// findByPrefix retrieves the Stub values of the service whose method is a
+// prefix of the given method.
+//
+// A stub method ending with MethodPrefixToken is a prefix, e.g. "Batch*"
+// matches "BatchGetUser" and "BatchCreateUser". The method aliases of the
+// stub are considered as well.
+//
+// Parameters:
+// - service: The service field used to search for Stub values.
+// - method: The method field used to search for Stub values.
+//
+// Returns:
+// - []*Stub: The Stub values with a matching method prefix.
+func (s *searcher) findByPrefix(service, method string) []*Stub {
+	all, err := s.storage.findByLeft(service)
+	if err != nil {
+		return nil
+	}
+
+	results := make([]*Stub, 0)
+
+	for _, stub := range s.castToStub(all) {
+		for _, right := range rightsOf(stub) {
+			prefix, ok := strings.CutSuffix(right, MethodPrefixToken)
+			if ok && strings.HasPrefix(method, prefix) {
+				results = append(results, stub)
+
+				break
+			}
+		}
+	}
+
+	return results
 }
 
 // clear resets the searcher.
 //
 // It clears the stubUsed map and calls the storage clear method.
 func (s *searcher) clear() {
+	// Notify the subscribers once the searcher is unlocked.
+	defer s.subscribers.emit(ChangeCleared, nil)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Clear the stubUsed map.
-	s.stubUsed = make(map[uuid.UUID]struct{})
+	s.stubUsed = make(map[uuid.UUID]time.Time)
+
+	// Clear the captured values.
+	s.captured = make(map[string]any)
+
+	// Clear the hit and round-robin counters.
+	s.hits = make(map[group]int)
+	s.turns = make(map[group]int)
+	s.occurrences = make(map[uuid.UUID]int)
+	s.calls = make(map[uuid.UUID]int)
+
+	// Clear the output overrides and pins.
+	s.overrides = make(map[uuid.UUID]outputOverride)
+	s.pins = nil
+
+	// Clear the search log, the quarantine and the compiled CEL programs and matcher expressions.
+	s.searchLog.reset()
+	s.quarantine.reset()
+	s.celPrograms.reset()
+	s.exprNodes.reset()
+
+	// Record the deletion of every stub, so incremental syncs see it.
+	s.bury(slices.Collect(maps.Keys(s.modGeneration))...)
 
 	// Clear the storage.
 	s.storage.clear()
 }
 
+// compact rebuilds the searcher to release the memory retained by deleted stubs.
+//
+// The storage is reindexed and the stubUsed map is rebuilt with the used stubs
+// that still exist and whose marks have not expired. It runs under the write
+// lock and is meant to be called during idle periods, e.g. after bulk deletes.
+// All cached findBy results are invalidated.
+func (s *searcher) compact() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Rebuild the storage.
+	s.storage.compact()
+
+	// Keep only the used marks of the stubs that still exist.
+	stubUsed := make(map[uuid.UUID]time.Time, len(s.stubUsed))
+	now := time.Now()
+
+	for id, at := range s.stubUsed {
+		if s.storage.findByID(id) != nil && !s.expired(at, now) {
+			stubUsed[id] = at
+		}
+	}
+
+	s.stubUsed = stubUsed
+
+	// Keep only the match counts of the stubs that still exist.
+	maps.DeleteFunc(s.occurrences, func(id uuid.UUID, _ int) bool {
+		return s.storage.findByID(id) == nil
+	})
+
+	// Keep only the output overrides of the stubs that still exist and are not expired.
+	maps.DeleteFunc(s.overrides, func(id uuid.UUID, override outputOverride) bool {
+		return s.storage.findByID(id) == nil || !now.Before(override.until)
+	})
+
+	// Keep only the pins of the stubs that still exist.
+	for key, pins := range s.pins {
+		s.pins[key] = slices.DeleteFunc(pins, func(p pin) bool {
+			return s.storage.findByID(p.id) == nil
+		})
+
+		if len(s.pins[key]) == 0 {
+			delete(s.pins, key)
+		}
+	}
+
+	// Keep only the use counts of the stubs still marked as used.
+	maps.DeleteFunc(s.calls, func(id uuid.UUID, _ int) bool {
+		_, ok := stubUsed[id]
+
+		return !ok
+	})
+}
+
 // all returns all Stub values stored in the searcher.
 //
 // Returns:
@@ -139,16 +736,79 @@ func (s *searcher) all() []*Stub {
 	return s.castToStub(s.storage.values())
 }
 
+// allOrdered returns all Stub values in the order they were first inserted.
+//
+// Updated stubs keep the position of their first insertion.
+//
+// Returns:
+// - []*Stub: The Stub values in insertion order.
+func (s *searcher) allOrdered() []*Stub {
+	return s.castToStub(s.storage.ordered())
+}
+
+// findByOutput returns all Stub values whose output satisfies the predicate.
+//
+// The storage is scanned under its read lock, so the predicate must not
+// modify the searcher.
+//
+// Parameters:
+// - predicate: The function used to filter Stub values.
+//
+// Returns:
+// - []*Stub: The Stub values that satisfy the predicate.
+func (s *searcher) findByOutput(predicate func(*Stub) bool) []*Stub {
+	return s.castToStub(s.storage.filter(func(v Value) bool {
+		stub, ok := v.(*Stub)
+
+		return ok && predicate(stub)
+	}))
+}
+
+// findByTimeRange returns all Stub values created within the given time range.
+//
+// The range is inclusive on both ends, a zero from or to leaves that end open.
+//
+// Parameters:
+// - from: The earliest creation time.
+// - to: The latest creation time.
+//
+// Returns:
+// - []*Stub: The Stub values sorted by their creation time.
+func (s *searcher) findByTimeRange(from, to time.Time) []*Stub {
+	results := s.findByOutput(func(stub *Stub) bool {
+		return (from.IsZero() || !stub.CreatedAt.Before(from)) &&
+			(to.IsZero() || !stub.CreatedAt.After(to))
+	})
+
+	slices.SortStableFunc(results, func(a, b *Stub) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return results
+}
+
 // used returns all Stub values that have been used by the searcher.
 //
+// With a used TTL, only the Stub values used within it are returned.
+//
 // Returns:
 // - []*Stub: The Stub values that have been used by the searcher.
 func (s *searcher) used() []*Stub {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Retrieve all Stub values with keys in the stubUsed map.
-	return s.castToStub(s.storage.findByIDs(slices.Collect(maps.Keys(s.stubUsed))...))
+	now := time.Now()
+
+	// Retrieve all Stub values with unexpired keys in the stubUsed map.
+	ids := make([]uuid.UUID, 0, len(s.stubUsed))
+
+	for id := range s.stubUsed {
+		if s.isUsed(id, now) {
+			ids = append(ids, id)
+		}
+	}
+
+	return s.castToStub(s.storage.findByIDs(ids...))
 }
 
 // unused returns all Stub values that have not been used by the searcher.
@@ -159,13 +819,15 @@ func (s *searcher) unused() []*Stub {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now()
+
 	// Initialize an empty slice to store the results.
 	results := make([]*Stub, 0, len(s.all()))
 
 	// Iterate over all Stub values.
 	for _, stub := range s.all() {
-		// Check if the stub has not been used.
-		if _, ok := s.stubUsed[stub.ID]; !ok {
+		// Check if the stub has not been used, or its mark has expired.
+		if !s.isUsed(stub.ID, now) {
 			// Add the stub to the results.
 			results = append(results, stub)
 		}
@@ -175,6 +837,139 @@ func (s *searcher) unused() []*Stub {
 	return results
 }
 
+// partitionByUsage splits all Stub values into the used and unused ones.
+//
+// Both sides are computed in a single pass under the read lock, so no stub
+// is marked in between and every stub lands on exactly one side.
+//
+// Returns:
+// - []*Stub: The Stub values that have been used by the searcher.
+// - []*Stub: The Stub values that have not been used by the searcher.
+func (s *searcher) partitionByUsage() ([]*Stub, []*Stub) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := s.all()
+	now := time.Now()
+
+	used := make([]*Stub, 0, len(s.stubUsed))
+	unused := make([]*Stub, 0, len(all))
+
+	for _, stub := range all {
+		if s.isUsed(stub.ID, now) {
+			used = append(used, stub)
+		} else {
+			unused = append(unused, stub)
+		}
+	}
+
+	return used, unused
+}
+
+// recomputeUsed replaces the used stubs with the Stub values satisfying the
+// predicate, e.g. to import usage determined from access logs.
+//
+// The previous marks are discarded rather than merged, and the use counts of
+// the stubs no longer used are dropped. The values captured from the outputs
+// of matched stubs are reset too, since the matches they came from are no
+// longer reflected by the marks. The predicate runs under the write lock and
+// must not call back into the searcher.
+//
+// Parameters:
+// - pred: The predicate telling if a Stub value is used.
+func (s *searcher) recomputeUsed(pred func(*Stub) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stubUsed := make(map[uuid.UUID]time.Time)
+	now := time.Now()
+
+	for _, stub := range s.all() {
+		if pred(stub) {
+			stubUsed[stub.ID] = now
+		}
+	}
+
+	s.stubUsed = stubUsed
+	s.captured = make(map[string]any)
+
+	maps.DeleteFunc(s.calls, func(id uuid.UUID, _ int) bool {
+		_, ok := stubUsed[id]
+
+		return !ok
+	})
+}
+
+// etag returns a content-based hash of all Stub values stored in the searcher.
+//
+// Every stub is hashed on its own JSON representation without its creation
+// time, the per-stub hashes are sorted and hashed once more, so the result
+// does not depend on the insertion order and stays the same across restarts
+// for identical stubs.
+//
+// A stub that cannot be encoded, e.g. with a NaN in its output data, is hashed
+// on its ID and modification generation instead, so it still changes the
+// result whenever it is added, updated or deleted.
+//
+// Returns:
+// - string: The hex-encoded SHA-256 hash of the stub set.
+func (s *searcher) etag() string {
+	all := s.all()
+
+	// Hash each stub separately.
+	hashes := make([]string, 0, len(all))
+
+	for _, stub := range all {
+		// The creation time is not part of the content.
+		content := *stub
+		content.CreatedAt = time.Time{}
+
+		hash, ok := hashStub(content)
+		if !ok {
+			hash = s.fallbackHash(stub.ID)
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	// Sort the per-stub hashes to make the result order-independent.
+	slices.Sort(hashes)
+
+	h := sha256.New()
+	for _, hash := range hashes {
+		h.Write([]byte(hash))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fallbackHash returns the hex-encoded SHA-256 hash of the stub's ID and
+// modification generation, for a stub whose content cannot be hashed.
+func (s *searcher) fallbackHash(id uuid.UUID) string {
+	s.mu.RLock()
+	generation := s.modGeneration[id]
+	s.mu.RUnlock()
+
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s@%d", id, generation))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// hashStub returns the hex-encoded SHA-256 hash of the stub's JSON representation.
+//
+// Map keys are sorted by encoding/json, which keeps the output stable. The
+// second return value is false if the stub cannot be encoded.
+func hashStub(stub Stub) (string, bool) {
+	raw, err := json.Marshal(stub)
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(raw)
+
+	return hex.EncodeToString(sum[:]), true
+}
+
 // find retrieves the Stub value associated with the given Query from the searcher.
 //
 // Parameters:
@@ -184,14 +979,51 @@ func (s *searcher) unused() []*Stub {
 // - *Result: The Result containing the found Stub value (if any), or nil.
 // - error: An error if the search fails.
 func (s *searcher) find(query Query) (*Result, error) {
+	var (
+		result *Result
+		err    error
+	)
+
+	// Rewrite the query before anything else sees it.
+	query = s.transform(query)
+
 	// Check if the Query has an ID field.
 	if query.ID != nil {
 		// Search for the Stub value with the given ID.
-		return s.searchByID(query.Service, query.Method, query)
+		result, err = s.searchByID(query.Service, query.Method, query)
+	} else {
+		// Search for the Stub value with the given service and method.
+		result, err = s.search(query)
+	}
+
+	// Count the outcome of the search.
+	s.outcomes.record(result, err)
+
+	// Log the search if it found a stub.
+	if err == nil && result.Found() != nil && !query.RequestInternal() {
+		s.searchLog.record(SearchEvent{Time: time.Now(), Query: query, StubID: result.Found().ID, Rank: result.rank})
 	}
 
-	// Search for the Stub value with the given service and method.
-	return s.search(query)
+	// Report the search if no stub was found.
+	if s.onMiss != nil && missed(err) {
+		s.onMiss(query, err)
+	}
+
+	return result, err
+}
+
+// transform applies the searcher's query transform, if any, to the query.
+func (s *searcher) transform(query Query) Query {
+	if s.queryTransform == nil {
+		return query
+	}
+
+	return s.queryTransform(query)
+}
+
+// missed checks if the search error reports that no stub was found.
+func missed(err error) bool {
+	return errors.Is(err, ErrServiceNotFound) || errors.Is(err, ErrMethodNotFound) || errors.Is(err, ErrStubNotFound)
 }
 
 // searchByID retrieves the Stub value associated with the given ID from the searcher.
@@ -206,18 +1038,18 @@ func (s *searcher) find(query Query) (*Result, error) {
 // - error: An error if the search fails.
 func (s *searcher) searchByID(service, method string, query Query) (*Result, error) {
 	// Check if the given service and method are valid.
-	_, err := s.storage.posByN(service, method)
-	if err != nil {
-		return nil, s.wrap(err)
+	if _, err := s.findBy(service, method); err != nil {
+		return nil, err
 	}
 
 	// Search for the Stub value with the given ID.
 	if found := s.findByID(*query.ID); found != nil {
 		// Mark the Stub value as used.
 		s.mark(query, *query.ID)
+		s.hit(query, found)
 
 		// Return the found Stub value.
-		return &Result{found: found}, nil
+		return s.capture(query, s.resolve(query, found)), nil
 	}
 
 	// Return an error if the Stub value is not found.
@@ -233,10 +1065,96 @@ func (s *searcher) searchByID(service, method string, query Query) (*Result, err
 // - *Result: The Result containing the found Stub value (if any), or nil.
 // - error: An error if the search fails.
 func (s *searcher) search(query Query) (*Result, error) {
+	// Rank the Stub values without side effects, but for the draws of the flaky ones and the round-robin turn.
+	eval, err := s.evaluate(query, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// If a found Stub value is found, mark it as used and return it.
+	if eval.Found != nil {
+		s.mark(query, eval.Found.ID)
+		s.hit(query, eval.Found)
+
+		result := s.resolve(query, eval.Found)
+		result.rank = eval.Score
+
+		// Report the runner-up as well if requested.
+		if query.IncludeSimilar && eval.Similar != nil && eval.SimilarScore >= s.minSimilarRank {
+			result.similar = eval.Similar
+		}
+
+		return s.capture(query, result), nil
+	}
+
+	// If no found Stub value is found, return the similar Stub value unless it ranks too low.
+	if eval.Similar == nil || eval.SimilarScore < s.minSimilarRank {
+		return nil, ErrStubNotFound
+	}
+
+	return &Result{found: nil, similar: eval.Similar, similarN: eval.similarN}, nil
+}
+
+// Evaluation describes how the Stub values of a service and method rank
+// against a query.
+type Evaluation struct {
+	Found        *Stub   `json:"found,omitempty"`   // The stub the query would match.
+	Score        float64 `json:"score"`             // The rank of the found stub.
+	Similar      *Stub   `json:"similar,omitempty"` // The highest ranked stub other than the found one.
+	SimilarScore float64 `json:"similarScore"`      // The rank of the similar stub.
+	Exact        bool    `json:"exact"`             // Whether the query matches a stub.
+
+	similarN []*Stub // The most similar stubs, if requested.
+}
+
+// evaluate ranks the Stub values of the query's service and method without
+// marking the found one as used or capturing its output.
+//
+// It runs the same matching as search, so the found stub is the one search
+// would return. A query with an ID evaluates to the stub with that ID, a
+// pinned query to the pinned stub.
+//
+// Only a search draws the match probabilities of flaky stubs, as a draw is
+// random and consumes the match source. Without the draw, a flaky stub is
+// treated like one that always matches.
+//
+// Parameters:
+// - query: The Query used to search for a Stub value.
+// - draw: Whether flaky stubs draw if they match and the round-robin rotation advances, as in a search.
+//
+// Returns:
+// - Evaluation: The found and similar Stub values with their ranks.
+// - error: An error if the service or method is not found.
+func (s *searcher) evaluate(query Query, draw bool) (Evaluation, error) {
+	// Treat absent data and headers as empty, so matchers never see nil maps.
+	if query.Data == nil {
+		query.Data = map[string]any{}
+	}
+
+	if query.Headers == nil {
+		query.Headers = map[string]any{}
+	}
+
 	// Find all Stub values with the given service and method.
 	stubs, err := s.findBy(query.Service, query.Method)
 	if err != nil {
-		return nil, s.wrap(err)
+		return Evaluation{}, s.wrap(err)
+	}
+
+	if query.ID != nil {
+		found := s.findByID(*query.ID)
+
+		return Evaluation{Found: found, Exact: found != nil}, nil
+	}
+
+	// Refuse to match pathologically nested queries.
+	if s.maxDepth > 0 && (exceedsDepth(query.Data, s.maxDepth) || exceedsDepth(query.Headers, s.maxDepth)) {
+		return Evaluation{}, fmt.Errorf("%w: %w", ErrStubNotFound, ErrMaxDepthExceeded)
+	}
+
+	// A pinned stub takes precedence over the ranking.
+	if found := s.pinned(query); found != nil {
+		return Evaluation{Found: found, Exact: true}, nil
 	}
 
 	// Initialize variables to store the found and similar Stub values.
@@ -245,39 +1163,190 @@ func (s *searcher) search(query Query) (*Result, error) {
 		foundRank   float64
 		similar     *Stub
 		similarRank float64
+		candidates  []candidate
+		ties        []*Stub
 	)
 
+	// offer updates the similar Stub value if the given rank is higher.
+	offer := func(stub *Stub, rank float64) {
+		if rank > similarRank {
+			similar = stub
+			similarRank = rank
+		}
+	}
+
+	limit := s.similarLimit(query)
+
+	// Normalize the query data once, the stubs are normalized one by one.
+	normalized := s.normalization.query(query)
+
+	// Stubs without a match mode of their own inherit the one of the service.
+	mode := s.serviceDefault(query.Service)
+
 	// Iterate over the found Stub values.
 	for _, stub := range stubs {
-		// Calculate the rank of the current Stub value.
-		current := rankMatch(query, stub)
+		// Skip the Stub values that are disabled, quarantined or not activated yet.
+		if stub.Disabled || !s.activated(stub) || s.quarantine.has(stub.ID) {
+			continue
+		}
 
-		// Update the similar Stub value if the current rank is higher.
-		if current > similarRank {
-			similar = stub
-			similarRank = current
+		// Calculate the rank of the current Stub value and check if it matches the query.
+		matched, current := s.matchStub(normalized, withMatchMode(s.normalization.stub(stub), mode))
+
+		// A flaky stub is treated as a non-match when its draw fails.
+		matched = matched && (!draw || s.drawMatch(stub))
+
+		// Collect the near-misses if requested.
+		if limit > 0 && !matched && current > 0 {
+			candidates = append(candidates, candidate{stub: stub, rank: current})
 		}
 
 		// Update the found Stub value if the current Stub value matches the query and has a higher rank.
-		if match(query, stub) && current > foundRank {
+		// A match without any rank, e.g. a stub without input requirements, is still found.
+		// On equal ranks, the Stub value activated later wins, so it takes over once activated.
+		if matched && (found == nil || current > foundRank || current == foundRank && stub.ActivateAfter > found.ActivateAfter) {
+			// The replaced Stub value becomes a similar candidate.
+			if found != nil {
+				offer(found, foundRank)
+			}
+
 			found = stub
 			foundRank = current
+			ties = []*Stub{stub}
+
+			continue
+		}
+
+		// Collect the Stub values tying with the found one for round-robin.
+		if matched && found != nil && current == foundRank && stub.ActivateAfter == found.ActivateAfter {
+			ties = append(ties, stub)
 		}
+
+		offer(stub, current)
 	}
 
-	// If a found Stub value is found, mark it as used and return it.
-	if found != nil {
-		s.mark(query, found.ID)
+	// A search takes its turn of the rotation and advances it in one step.
+	var turn int
+	if s.roundRobin && found != nil {
+		turn = s.turn(query, draw)
+	}
 
-		return &Result{found: found}, nil
+	// Pick among the tying Stub values by the query's seed, or rotate through them, if requested.
+	if seeded, ok := s.seededTie(query, ties); ok && len(ties) > 1 {
+		found = seeded
+	} else if s.roundRobin && len(ties) > 1 {
+		found = ties[turn%len(ties)]
 	}
 
-	// If no found Stub value is found, return the similar Stub value.
-	if similar == nil {
-		return nil, ErrStubNotFound
+	eval := Evaluation{
+		Found:        found,
+		Score:        foundRank,
+		Similar:      similar,
+		SimilarScore: similarRank,
+		Exact:        found != nil,
+	}
+
+	if limit > 0 && found == nil {
+		eval.similarN = topSimilar(candidates, limit)
+	}
+
+	return eval, nil
+}
+
+// matchStub checks if the Stub value matches the query and ranks it.
+//
+// Besides the stub's own matchers, the query must carry the values the stub
+// expects to be captured from previous matches.
+//
+// Parameters:
+// - query: The Query used to search for a Stub value.
+// - stub: The Stub value to evaluate.
+//
+// Returns:
+// - bool: Whether the Stub value matches the query.
+// - float64: The rank of the Stub value.
+func (s *searcher) matchStub(query Query, stub *Stub) (bool, float64) {
+	matched, rank := s.runMatch(query, stub)
+
+	return matched && s.matchCaptured(query, stub), rank
+}
+
+// runMatch checks if the Stub value matches the query and ranks it.
+//
+// The normalizers the stub references are applied first. Stubs with custom,
+// normalizer or regular expression matchers are evaluated with the
+// searcher's match timeout, if configured. A stub that does not finish in
+// time is logged and treated as a non-match with a zero rank. A stub whose
+// matchers panic is quarantined and treated as a non-match as well.
+func (s *searcher) runMatch(query Query, stub *Stub) (bool, float64) {
+	run := func() (matched bool, rank float64) {
+		defer s.quarantineFailed(stub, &matched, &rank)
+
+		query, stub := s.withNormalizers(query, stub)
+
+		return match(query, stub, &s.celPrograms, &s.exprNodes), rankMatch(query, stub, &s.exprNodes)
+	}
+
+	if s.matchTimeout <= 0 || !needsDeadline(stub) {
+		return run()
+	}
+
+	type outcome struct {
+		matched bool
+		rank    float64
+	}
+
+	// The channel is buffered, so the goroutine finishes even after a timeout.
+	done := make(chan outcome, 1)
+
+	go func() {
+		matched, rank := run()
+		done <- outcome{matched: matched, rank: rank}
+	}()
+
+	timer := time.NewTimer(s.matchTimeout)
+	defer timer.Stop()
+
+	select {
+	case o := <-done:
+		return o.matched, o.rank
+	case <-timer.C:
+		slog.Warn("stub match timed out",
+			"id", stub.ID,
+			"service", stub.Service,
+			"method", stub.Method,
+			"timeout", s.matchTimeout)
+
+		return false, 0
+	}
+}
+
+// resolve builds the Result for the Stub value found by the given Query.
+//
+// The output takes the stub's occurrences into account, so resolving counts
+// the match of the stub. An active output override takes precedence.
+//
+// Parameters:
+// - query: The Query used to find the Stub value.
+// - found: The found Stub value.
+//
+// Returns:
+// - *Result: The Result with the output resolved for the query.
+func (s *searcher) resolve(query Query, found *Stub) *Result {
+	var output Output
+
+	// Skip the counting for stubs without occurrences.
+	if len(found.Occurrences) == 0 {
+		output = found.OutputFor(query.Data)
+	} else {
+		output = found.OutputAt(s.occurrence(query, found), query.Data)
+	}
+
+	if override, ok := s.overridden(found.ID); ok {
+		output = override
 	}
 
-	return &Result{found: nil, similar: similar}, nil
+	return &Result{found: found, output: output, latency: found.Latency, latencySource: s.latencySource}
 }
 
 // mark marks the given Stub value as used in the searcher.
@@ -298,7 +1367,7 @@ func (s *searcher) mark(query Query, id uuid.UUID) {
 	defer s.mu.Unlock()
 
 	// Mark the Stub value as used by adding it to the stubUsed map.
-	s.stubUsed[id] = struct{}{}
+	s.markUsed(id)
 }
 
 // castToValue converts a slice of *Stub values to a slice of Value interface{}.
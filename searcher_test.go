@@ -0,0 +1,148 @@
+package stuber //nolint:testpackage
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindByCache(t *testing.T) {
+	s := newSearcher()
+
+	id := uuid.New()
+
+	s.upsert(&Stub{ID: id, Service: "Greeter", Method: "SayHello"})
+
+	first, err := s.findBy("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	second, err := s.findBy("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Same(t, &first[0], &second[0])
+
+	s.upsert(&Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello"})
+
+	third, err := s.findBy("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Len(t, third, 2)
+
+	s.del(id)
+
+	fourth, err := s.findBy("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Len(t, fourth, 1)
+
+	s.clear()
+
+	_, err = s.findBy("Greeter", "SayHello")
+	require.ErrorIs(t, err, ErrServiceNotFound)
+}
+
+func BenchmarkFindBy(b *testing.B) {
+	s := newSearcher()
+
+	for range 100 {
+		s.upsert(&Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello"})
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				all, err := s.storage.findAll("Greeter", "SayHello")
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				_ = s.castToStub(all)
+			}
+		})
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := s.findBy("Greeter", "SayHello"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkSearch(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "locked"},
+		{name: "copy-on-write", opts: []Option{WithCopyOnWrite()}},
+	} {
+		s := newSearcher(bench.opts...)
+
+		for i := range 100 {
+			s.upsert(&Stub{
+				ID:      uuid.New(),
+				Service: "Greeter",
+				Method:  "SayHello",
+				Input:   InputData{Equals: map[string]any{"name": strconv.Itoa(i)}},
+				Output:  Output{Data: map[string]any{"message": "Hello"}},
+			})
+		}
+
+		query := Query{Service: "Greeter", Method: "SayHello", Data: map[string]any{"name": "99"}}
+
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := s.find(query); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := newSearcher()
+
+	keep := &Stub{ID: uuid.New(), Service: "Greeter1", Method: "SayHello1"}
+	s.upsert(keep)
+
+	deleted := make([]uuid.UUID, 0, 100)
+
+	for i := range 100 {
+		id := uuid.New()
+		s.upsert(&Stub{ID: id, Service: "Greeter" + strconv.Itoa(i+2), Method: "SayHello"})
+		deleted = append(deleted, id)
+	}
+
+	s.mark(Query{}, keep.ID)
+	s.mark(Query{}, deleted[0])
+
+	n, err := s.del(deleted...)
+	require.NoError(t, err)
+	require.Equal(t, 100, n)
+
+	s.compact()
+
+	require.Len(t, s.storage.load().lefts, 1)
+	require.Len(t, s.storage.load().rights, 1)
+	require.Len(t, s.stubUsed, 1)
+
+	all, err := s.findBy("Greeter1", "SayHello1")
+	require.NoError(t, err)
+	require.Equal(t, []*Stub{keep}, all)
+
+	_, err = s.findBy("Greeter2", "SayHello")
+	require.ErrorIs(t, err, ErrServiceNotFound)
+
+	require.Equal(t, []*Stub{keep}, s.used())
+	require.Empty(t, s.unused())
+}
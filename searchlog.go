@@ -0,0 +1,150 @@
+package stuber
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SearchEvent records a search that found a stub.
+type SearchEvent struct {
+	Time   time.Time `json:"time"`   // The time of the search.
+	Query  Query     `json:"query"`  // The query of the search.
+	StubID uuid.UUID `json:"stubId"` // The ID of the found stub.
+	Rank   float64   `json:"rank"`   // The rank of the found stub, zero for searches by ID.
+}
+
+// WithSearchLog makes the searcher keep the given number of the most recent
+// searches that found a stub, see Budgerigar.StubHistory.
+//
+// The log is disabled by default. A non-positive size disables it.
+func WithSearchLog(size int) Option {
+	return func(s *searcher) {
+		s.searchLog = newSearchLog(size)
+	}
+}
+
+// searchLog is a fixed-size ring buffer of search events.
+//
+// The zero value keeps no events.
+type searchLog struct {
+	mu     sync.RWMutex
+	events []SearchEvent // The ring buffer, allocated up front.
+	next   int           // The index the next event is written to.
+	full   bool          // Whether the buffer has wrapped around.
+}
+
+// newSearchLog creates a search log keeping the given number of events.
+func newSearchLog(size int) *searchLog {
+	return &searchLog{events: make([]SearchEvent, max(size, 0))}
+}
+
+// record appends the event, overwriting the oldest one when full.
+func (l *searchLog) record(event SearchEvent) {
+	if l == nil || len(l.events) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events[l.next] = event
+	l.next = (l.next + 1) % len(l.events)
+	l.full = l.full || l.next == 0
+}
+
+// reset drops all the events.
+func (l *searchLog) reset() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.events)
+	l.next = 0
+	l.full = false
+}
+
+// recent returns up to limit of the most recent events satisfying the
+// predicate, newest first. A non-positive limit returns all of them.
+func (l *searchLog) recent(limit int, predicate func(SearchEvent) bool) []SearchEvent {
+	results := make([]SearchEvent, 0)
+
+	if l == nil || len(l.events) == 0 {
+		return results
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.events)
+	}
+
+	for i := 1; i <= n && (limit <= 0 || len(results) < limit); i++ {
+		event := l.events[(l.next-i+len(l.events))%len(l.events)]
+		if predicate(event) {
+			results = append(results, event)
+		}
+	}
+
+	return results
+}
+
+// stubHistory returns the most recent searches that found the stub with the
+// given ID, newest first.
+//
+// The history is only kept when the searcher is configured with
+// WithSearchLog, and is empty for unknown IDs.
+//
+// Parameters:
+// - id: The UUID of the stub.
+// - limit: The maximum number of events to return, a non-positive limit returns all of them.
+//
+// Returns:
+// - []SearchEvent: The search events of the stub.
+func (s *searcher) stubHistory(id uuid.UUID, limit int) []SearchEvent {
+	return s.searchLog.recent(limit, func(event SearchEvent) bool {
+		return event.StubID == id
+	})
+}
+
+// rankHistogram counts the recent searches that found a stub by the rank of
+// the found stub, e.g. to spot stubs winning with loose matching.
+//
+// The buckets are lower bounds in any order. A search is counted in the bucket
+// with the highest bound not above its rank, searches ranking below every
+// bound are not counted. Searches by ID are skipped, since they are not
+// ranked. The searches are only kept when the searcher is configured with
+// WithSearchLog.
+//
+// Parameters:
+// - buckets: The lower bounds of the buckets.
+//
+// Returns:
+// - map[float64]int: The number of searches keyed by the bucket bound.
+func (s *searcher) rankHistogram(buckets []float64) map[float64]int {
+	bounds := slices.Sorted(slices.Values(buckets))
+	histogram := make(map[float64]int, len(bounds))
+
+	for _, bound := range bounds {
+		histogram[bound] = 0
+	}
+
+	for _, event := range s.searchLog.recent(0, func(event SearchEvent) bool { return event.Query.ID == nil }) {
+		// Without an equal bound, the rank falls in the bucket before the first higher bound.
+		i, found := slices.BinarySearch(bounds, event.Rank)
+		if found {
+			histogram[bounds[i]]++
+		} else if i > 0 {
+			histogram[bounds[i-1]]++
+		}
+	}
+
+	return histogram
+}
@@ -0,0 +1,117 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_RankHistogram(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New(), stuber.WithSearchLog(10))
+
+	loose := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+	tight := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{"x-user": "bob"}},
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello bob"}},
+	}
+
+	s.PutMany(loose, tight)
+
+	looseQuery := stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "alice"}}
+	tightQuery := stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Headers: map[string]interface{}{"x-user": "bob"},
+		Data:    map[string]interface{}{"name": "bob"},
+	}
+
+	low, err := s.Evaluate(looseQuery)
+	require.NoError(t, err)
+	require.Same(t, loose, low.Found)
+
+	high, err := s.Evaluate(tightQuery)
+	require.NoError(t, err)
+	require.Same(t, tight, high.Found)
+	require.Greater(t, high.Score, low.Score)
+
+	for _, query := range []stuber.Query{looseQuery, looseQuery, tightQuery, looseQuery} {
+		_, err := s.FindByQuery(query)
+		require.NoError(t, err)
+	}
+
+	// Searches by ID are not ranked.
+	_, err = s.FindByQuery(stuber.Query{ID: &loose.ID, Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+
+	middle := (low.Score + high.Score) / 2
+
+	require.Equal(t, map[float64]int{low.Score: 3, middle: 1}, s.RankHistogram([]float64{middle, low.Score}))
+	require.Equal(t, map[float64]int{high.Score: 1}, s.RankHistogram([]float64{high.Score}))
+	require.Empty(t, stuber.NewBudgerigar(features.New()).RankHistogram(nil))
+}
+
+func TestBudgerigar_StubHistory(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New(), stuber.WithSearchLog(4))
+
+	bob := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello bob"}},
+	}
+	alice := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "alice"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello alice"}},
+	}
+
+	s.PutMany(bob, alice)
+
+	search := func(name string, n int) {
+		_, err := s.FindByQuery(stuber.Query{
+			Service: "Greeter",
+			Method:  "SayHello",
+			Data:    map[string]interface{}{"name": name, "n": n},
+		})
+		require.NoError(t, err)
+	}
+
+	search("bob", 1)
+	search("alice", 2)
+	search("bob", 3)
+
+	history := s.StubHistory(bob.ID, 0)
+	require.Len(t, history, 2)
+	require.Equal(t, 3, history[0].Query.Data["n"])
+	require.Equal(t, 1, history[1].Query.Data["n"])
+	require.Equal(t, bob.ID, history[0].StubID)
+
+	require.Len(t, s.StubHistory(bob.ID, 1), 1)
+	require.Empty(t, s.StubHistory(uuid.New(), 0))
+
+	// The oldest searches are dropped once the log is full.
+	search("alice", 4)
+	search("alice", 5)
+	search("alice", 6)
+
+	history = s.StubHistory(bob.ID, 0)
+	require.Len(t, history, 1)
+	require.Equal(t, 3, history[0].Query.Data["n"])
+	require.Len(t, s.StubHistory(alice.ID, 10), 3)
+
+	s.Clear()
+	require.Empty(t, s.StubHistory(alice.ID, 0))
+
+	require.Empty(t, stuber.NewBudgerigar(features.New()).StubHistory(bob.ID, 0))
+}
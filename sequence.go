@@ -0,0 +1,82 @@
+package stuber
+
+// SequenceMatch matches the messages of a client stream, see Query.DataSequence.
+//
+// The anchors are checked against the positions they name: First against
+// the first message, Last against the last one, and Any against every
+// message until one matches. Unset anchors are not checked, so any number
+// of messages may come between the first and the last.
+type SequenceMatch struct {
+	First       *Condition `json:"first,omitempty"`       // The condition of the first message.
+	Last        *Condition `json:"last,omitempty"`        // The condition of the last message.
+	Any         *Condition `json:"any,omitempty"`         // The condition at least one message must satisfy.
+	MinMessages int        `json:"minMessages,omitempty"` // The minimum number of messages, if set.
+}
+
+// matchSequence checks if the query's message sequence satisfies the stub's
+// sequence matcher.
+//
+// A stub without a sequence matcher accepts any query, while a stub with one
+// requires at least one message.
+func matchSequence(input InputData, sequence []map[string]any) bool {
+	if input.Sequence == nil {
+		return true
+	}
+
+	satisfied, total := countSequence(input, sequence)
+
+	return len(sequence) > 0 && len(sequence) >= input.Sequence.MinMessages && satisfied == total
+}
+
+// rankSequence returns the share of the anchors of the stub's sequence
+// matcher the query's message sequence satisfies.
+func rankSequence(input InputData, sequence []map[string]any) float64 {
+	satisfied, total := countSequence(input, sequence)
+	if total == 0 {
+		return 0
+	}
+
+	return float64(satisfied) / float64(total)
+}
+
+// countSequence returns the number of satisfied anchors and the number of
+// anchors of the stub's sequence matcher.
+func countSequence(input InputData, sequence []map[string]any) (int, int) {
+	seq := input.Sequence
+	if seq == nil {
+		return 0, 0
+	}
+
+	orderIgnore := input.IgnoreArrayOrder
+	satisfied, total := 0, 0
+
+	if seq.First != nil {
+		total++
+
+		if len(sequence) > 0 && seq.First.match(sequence[0], orderIgnore) {
+			satisfied++
+		}
+	}
+
+	if seq.Last != nil {
+		total++
+
+		if len(sequence) > 0 && seq.Last.match(sequence[len(sequence)-1], orderIgnore) {
+			satisfied++
+		}
+	}
+
+	if seq.Any != nil {
+		total++
+
+		for _, message := range sequence {
+			if seq.Any.match(message, orderIgnore) {
+				satisfied++
+
+				break
+			}
+		}
+	}
+
+	return satisfied, total
+}
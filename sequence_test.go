@@ -0,0 +1,64 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Sequence(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Uploader",
+		Method:  "Upload",
+		Input: stuber.InputData{Sequence: &stuber.SequenceMatch{
+			First:       &stuber.Condition{Equals: map[string]interface{}{"type": "init"}},
+			Last:        &stuber.Condition{Contains: map[string]interface{}{"type": "commit"}},
+			Any:         &stuber.Condition{Contains: map[string]interface{}{"checksum": "abc"}},
+			MinMessages: 2,
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "uploaded"}},
+	}
+
+	s.PutMany(stub)
+
+	message := func(kind string) map[string]interface{} {
+		return map[string]interface{}{"type": kind}
+	}
+
+	chunk := map[string]interface{}{"type": "chunk", "checksum": "abc"}
+	commit := map[string]interface{}{"type": "commit", "checksum": "abc"}
+
+	tests := []struct {
+		sequence []map[string]interface{}
+		found    bool
+	}{
+		{[]map[string]interface{}{message("init"), chunk, message("chunk"), message("commit")}, true},
+		{[]map[string]interface{}{message("init"), commit}, true},
+		{[]map[string]interface{}{message("init"), message("chunk"), message("commit")}, false},
+		{[]map[string]interface{}{message("init"), chunk}, false},
+		{[]map[string]interface{}{chunk, message("commit")}, false},
+		{[]map[string]interface{}{commit}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(stuber.Query{Service: "Uploader", Method: "Upload", DataSequence: test.sequence})
+		if !test.found {
+			require.NoError(t, err)
+			require.Nil(t, r.Found())
+			require.Same(t, stub, r.Similar())
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+	}
+}
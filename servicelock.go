@@ -0,0 +1,55 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrServiceLocked is returned when a write affects the stubs of a locked service.
+var ErrServiceLocked = errors.New("service is locked")
+
+// lockService protects the stubs of the service against writes.
+//
+// Inserting, updating, patching or deleting a stub of the service fails with
+// ErrServiceLocked until the service is unlocked. Searches are unaffected.
+//
+// Parameters:
+// - service: The name of the service to lock.
+func (s *searcher) lockService(service string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lockedServices == nil {
+		s.lockedServices = make(map[string]struct{})
+	}
+
+	s.lockedServices[service] = struct{}{}
+}
+
+// unlockService allows writes to the stubs of the service again.
+//
+// Parameters:
+// - service: The name of the service to unlock.
+func (s *searcher) unlockService(service string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.lockedServices, service)
+}
+
+// checkUnlocked checks that none of the services is locked.
+//
+// The caller must hold the write lock, so no service is locked between the
+// check and the write.
+//
+// Returns:
+// - error: An error wrapping ErrServiceLocked naming the first locked service.
+func (s *searcher) checkUnlocked(services ...string) error {
+	for _, service := range services {
+		if _, ok := s.lockedServices[service]; ok {
+			return fmt.Errorf("%w: %s", ErrServiceLocked, service)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_LockService(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	locked := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+	other := &stuber.Stub{ID: uuid.New(), Service: "Other", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+
+	s.PutMany(locked, other)
+	s.LockService("Greeter")
+
+	// Writes affecting the locked service are refused as a whole.
+	ids, err := s.PutManyE(
+		&stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHi", Output: stuber.Output{Error: "boom"}},
+		&stuber.Stub{ID: uuid.New(), Service: "Other", Method: "SayHi", Output: stuber.Output{Error: "boom"}},
+	)
+	require.ErrorIs(t, err, stuber.ErrServiceLocked)
+	require.Nil(t, ids)
+
+	ids, err = s.UpdateManyE(&stuber.Stub{ID: locked.ID, Service: "Other", Method: "SayHello", Output: stuber.Output{Error: "moved"}})
+	require.ErrorIs(t, err, stuber.ErrServiceLocked)
+	require.Nil(t, ids)
+
+	n, err := s.DeleteByIDE(locked.ID, other.ID)
+	require.ErrorIs(t, err, stuber.ErrServiceLocked)
+	require.Zero(t, n)
+
+	require.ErrorIs(t, s.MergeByID(locked.ID, &stuber.Stub{Output: stuber.Output{Error: "patched"}}), stuber.ErrServiceLocked)
+	require.ErrorIs(t, s.MergeByID(other.ID, &stuber.Stub{Service: "Greeter"}), stuber.ErrServiceLocked)
+	require.ErrorIs(t, s.Import([]byte(`[{"service":"Greeter","method":"SayHey","output":{"data":{}}}]`)), stuber.ErrServiceLocked)
+
+	src := stuber.NewBudgerigar(features.New())
+	src.PutMany(&stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHey", Output: stuber.Output{Error: "boom"}})
+	require.ErrorIs(t, stuber.Merge(s, src, stuber.MergeKeepSrc), stuber.ErrServiceLocked)
+
+	require.Len(t, s.All(), 2)
+	require.Equal(t, "boom", s.FindByID(locked.ID).Output.Error)
+
+	// Reads and writes of other services are unaffected.
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.Same(t, locked, r.Found())
+
+	require.NoError(t, s.MergeByID(other.ID, &stuber.Stub{Output: stuber.Output{Error: "patched"}}))
+
+	n, err = s.DeleteByIDE(other.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	s.UnlockService("Greeter")
+
+	require.Equal(t, 1, s.DeleteByID(locked.ID))
+	require.Empty(t, s.All())
+}
@@ -0,0 +1,129 @@
+package stuber
+
+// shadowedBy returns the stored stubs whose searches the candidate would take.
+//
+// A stored stub is shadowed when a search for its smallest query, built from
+// its exact or partial inputs, may find the candidate instead: the candidate
+// matches the query and ranks at least as high for it. On equal ranks, which
+// stub wins depends on their order, so such stubs are reported too. A stub
+// ranking higher for its own query, e.g. one requiring more fields than the
+// candidate, keeps winning it and is not shadowed.
+//
+// Only the matchers declared comparable in shadowMatchers are taken into
+// account. Stubs of the same service and method using any other matcher are
+// skipped, as is a candidate using one.
+//
+// Parameters:
+// - candidate: The Stub value that is about to be added.
+//
+// Returns:
+// - []*Stub: The stored Stub values shadowed by the candidate.
+func (s *searcher) shadowedBy(candidate *Stub) []*Stub {
+	if !comparableInputs(candidate) {
+		return nil
+	}
+
+	stubs, err := s.findBy(candidate.Service, candidate.Method)
+	if err != nil {
+		return nil
+	}
+
+	results := make([]*Stub, 0)
+
+	for _, stub := range stubs {
+		if stub.ID == candidate.ID || !comparableInputs(stub) {
+			continue
+		}
+
+		query := smallestQuery(stub)
+
+		if match(query, candidate, nil, nil) && rankMatch(query, candidate, nil) >= rankMatch(query, stub, nil) {
+			results = append(results, stub)
+		}
+	}
+
+	return results
+}
+
+// shadowMatcher declares a matcher of a stub for the shadow check.
+type shadowMatcher struct {
+	field      string           // The path of the matcher field in Stub, e.g. "Input.Equals".
+	declared   func(*Stub) bool // Whether the stub uses the matcher.
+	comparable bool             // Whether the matcher can be compared between stubs.
+}
+
+// shadowMatchers declares every matcher of a stub. A new matcher must be
+// added here, the test of the shadow check fails otherwise. It is only
+// comparable if smallestQuery and match agree on it, i.e. a stub using it
+// still matches its own smallest query.
+//
+//nolint:gochecknoglobals
+var shadowMatchers = []shadowMatcher{
+	{"Input.Equals", func(s *Stub) bool { return len(s.Input.Equals) > 0 }, true},
+	{"Input.Contains", func(s *Stub) bool { return len(s.Input.Contains) > 0 }, true},
+	{"Input.IgnoreArrayOrder", func(s *Stub) bool { return s.Input.IgnoreArrayOrder }, true},
+	{"Input.Defaults", func(s *Stub) bool { return len(s.Input.Defaults) > 0 }, true},
+	{"Input.IgnoreCase", func(s *Stub) bool { return len(s.Input.IgnoreCase) > 0 }, true},
+	{"Input.Tolerance", func(s *Stub) bool { return len(s.Input.Tolerance) > 0 }, true},
+	{"Headers.Equals", func(s *Stub) bool { return len(s.Headers.Equals) > 0 }, true},
+	{"Headers.Contains", func(s *Stub) bool { return len(s.Headers.Contains) > 0 }, true},
+	{"HeadersExact", func(s *Stub) bool { return s.HeadersExact }, true},
+	{"EmptyBody", func(s *Stub) bool { return s.EmptyBody }, true},
+	{"MatchMode", func(s *Stub) bool { return s.MatchMode != "" }, true},
+	{"Input.Matches", func(s *Stub) bool { return len(s.Input.Matches) > 0 }, false},
+	{"Input.MinBytes", func(s *Stub) bool { return s.Input.MinBytes > 0 }, false},
+	{"Input.MaxBytes", func(s *Stub) bool { return s.Input.MaxBytes > 0 }, false},
+	{"Input.Captured", func(s *Stub) bool { return len(s.Input.Captured) > 0 }, false},
+	{"Input.Items", func(s *Stub) bool { return len(s.Input.Items) > 0 }, false},
+	{"Input.Times", func(s *Stub) bool { return len(s.Input.Times) > 0 }, false},
+	{"Input.Any", func(s *Stub) bool { return len(s.Input.Any) > 0 }, false},
+	{"Input.Elements", func(s *Stub) bool { return len(s.Input.Elements) > 0 }, false},
+	{"Input.OneOf", func(s *Stub) bool { return len(s.Input.OneOf) > 0 }, false},
+	{"Input.Sequence", func(s *Stub) bool { return s.Input.Sequence != nil }, false},
+	{"Input.Keys", func(s *Stub) bool { return len(s.Input.Keys) > 0 }, false},
+	{"Input.TypeOf", func(s *Stub) bool { return len(s.Input.TypeOf) > 0 }, false},
+	{"Input.Normalizers", func(s *Stub) bool { return len(s.Input.Normalizers) > 0 }, false},
+	{"Input.NotEquals", func(s *Stub) bool { return len(s.Input.NotEquals) > 0 }, false},
+	{"Headers.Matches", func(s *Stub) bool { return len(s.Headers.Matches) > 0 }, false},
+	{"Headers.Captured", func(s *Stub) bool { return len(s.Headers.Captured) > 0 }, false},
+	{"Matcher", func(s *Stub) bool { return s.Matcher != nil }, false},
+	{"CEL", func(s *Stub) bool { return s.CEL != "" }, false},
+	{"Expr", func(s *Stub) bool { return s.Expr != "" }, false},
+	{"Trailers", func(s *Stub) bool { return len(s.Trailers) > 0 }, false},
+	{"BodyChecksum", func(s *Stub) bool { return s.BodyChecksum != nil }, false},
+	{"PeerMatch", func(s *Stub) bool { return s.PeerMatch != "" }, false},
+}
+
+// comparableInputs checks if the stub only uses matchers whose inputs can be
+// compared with the inputs of another stub.
+func comparableInputs(stub *Stub) bool {
+	for _, m := range shadowMatchers {
+		if !m.comparable && m.declared(stub) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// smallestQuery builds the query with the fewest fields the stub matches.
+//
+// Exact inputs take precedence, since the query cannot carry any other fields.
+func smallestQuery(stub *Stub) Query {
+	return Query{
+		Service: stub.Service,
+		Method:  stub.Method,
+		Headers: smallestData(stub.Headers.Equals, stub.Headers.Contains),
+		Data:    smallestData(stub.Input.Equals, stub.Input.Contains),
+	}
+}
+
+// smallestData returns the exact fields if there are any, otherwise the
+// partial ones.
+func smallestData(equals, contains map[string]any) map[string]any {
+	if len(equals) > 0 {
+		return equals
+	}
+
+	return contains
+}
@@ -0,0 +1,94 @@
+package stuber //nolint:testpackage
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// notMatchers are the fields of a stub that do not decide whether a query
+// matches it, so the shadow check does not declare them.
+//
+//nolint:gochecknoglobals
+var notMatchers = []string{
+	"ID", "Service", "Method", "Methods", "Output", "Switch", "Captures", "HeaderCaptures", "CreatedAt",
+	"ActivateAfter", "Occurrences", "Latency", "Priority", "Disabled", "MatchProbability",
+}
+
+func TestShadowMatchers(t *testing.T) {
+	declared := make(map[string]shadowMatcher, len(shadowMatchers))
+	for _, m := range shadowMatchers {
+		declared[m.field] = m
+	}
+
+	fields := stubFields(reflect.TypeFor[Stub](), "")
+
+	// Every field is either a declared matcher or known not to be one.
+	for _, field := range fields {
+		_, ok := declared[field]
+		require.True(t, ok || slices.Contains(notMatchers, field), "stub field %s is not declared in shadowMatchers", field)
+	}
+
+	// Every declared matcher exists and detects its field.
+	for _, m := range shadowMatchers {
+		require.Contains(t, fields, m.field)
+
+		var stub Stub
+
+		require.False(t, m.declared(&stub), m.field)
+
+		setField(reflect.ValueOf(&stub).Elem(), m.field)
+		require.True(t, m.declared(&stub), m.field)
+		require.Equal(t, m.comparable, comparableInputs(&stub), m.field)
+	}
+}
+
+// stubFields returns the paths of the fields of the stub, descending into
+// the input and header matchers.
+func stubFields(typ reflect.Type, prefix string) []string {
+	var fields []string
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+
+		if field.Name == "Input" || field.Name == "Headers" {
+			fields = append(fields, stubFields(field.Type, field.Name+".")...)
+
+			continue
+		}
+
+		fields = append(fields, prefix+field.Name)
+	}
+
+	return fields
+}
+
+// setField sets the field at the path to a non-zero value.
+func setField(value reflect.Value, path string) {
+	for _, name := range strings.Split(path, ".") {
+		value = value.FieldByName(name)
+	}
+
+	switch value.Kind() { //nolint:exhaustive
+	case reflect.Map:
+		value.Set(reflect.MakeMap(value.Type()))
+		value.SetMapIndex(reflect.New(value.Type().Key()).Elem(), reflect.New(value.Type().Elem()).Elem())
+	case reflect.Slice:
+		value.Set(reflect.MakeSlice(value.Type(), 1, 1))
+	case reflect.Pointer:
+		value.Set(reflect.New(value.Type().Elem()))
+	case reflect.Interface:
+		value.Set(reflect.ValueOf(MatcherFunc(func(Query) bool { return true })))
+	case reflect.String:
+		value.SetString("x")
+	case reflect.Int:
+		value.SetInt(1)
+	case reflect.Bool:
+		value.SetBool(true)
+	default:
+		panic("unsupported field kind " + value.Kind().String())
+	}
+}
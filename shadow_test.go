@@ -0,0 +1,79 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ShadowedBy(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	narrow := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input: stuber.InputData{Contains: map[string]interface{}{
+			"name": "bob",
+			"lang": "en",
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "Hello bob"}},
+	}
+	exact := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+	same := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hey"}},
+	}
+	other := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "alice"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello alice"}},
+	}
+	regex := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Matches: map[string]interface{}{"name": "^b"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello b"}},
+	}
+	sibling := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayGoodbye",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Bye bob"}},
+	}
+
+	s.PutMany(narrow, exact, same, other, regex, sibling)
+
+	candidate := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hi"}},
+	}
+
+	// The narrow stub requires more fields, so it outranks the candidate on its own queries.
+	require.ElementsMatch(t, []*stuber.Stub{exact, same}, s.ShadowedBy(candidate))
+
+	candidate.Input.Matches = map[string]interface{}{"name": "^b"}
+	require.Empty(t, s.ShadowedBy(candidate))
+
+	require.Empty(t, s.ShadowedBy(&stuber.Stub{ID: uuid.New(), Service: "Unknown", Method: "SayHello"}))
+}
@@ -0,0 +1,120 @@
+package stuber
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// groupByMatcherSignature groups the stubs by the shape of their matchers,
+// e.g. to find all the stubs matching on the same fields.
+//
+// The signature of a stub lists what it constrains, ignoring the values, as
+// "matcher:path" terms sorted and joined by "; ". Paths are dot-separated and
+// end at the leaves of nested objects, arrays are leaves. For example, a stub
+// matching user.id exactly and status against a list of values has the
+// signature "input.equals:user.id; input.oneOf:status". Matchers without a
+// path, such as "cel" or "peer", appear as their name alone. Stubs without
+// any matcher share the empty signature. The service and method are not part
+// of the signature.
+//
+// Returns:
+// - map[string][]*Stub: The stubs keyed by their signature, in insertion order.
+func (s *searcher) groupByMatcherSignature() map[string][]*Stub {
+	groups := make(map[string][]*Stub)
+
+	for _, stub := range s.allOrdered() {
+		signature := matcherSignature(stub)
+		groups[signature] = append(groups[signature], stub)
+	}
+
+	return groups
+}
+
+// matcherSignature returns the canonical signature of the stub's matchers.
+func matcherSignature(stub *Stub) string {
+	return strings.Join(slices.Sorted(maps.Keys(matcherTerms(stub))), "; ")
+}
+
+// matcherTerms returns the set of the "matcher:path" terms of the stub's
+// matchers, and the names of the matchers without a path.
+func matcherTerms(stub *Stub) map[string]struct{} {
+	terms := make(map[string]struct{})
+	input := stub.Input
+
+	addPaths(terms, "input.equals", "", input.Equals)
+	addPaths(terms, "input.contains", "", input.Contains)
+	addPaths(terms, "input.matches", "", input.Matches)
+	addKeys(terms, "input.items", input.Items)
+	addKeys(terms, "input.times", input.Times)
+	addKeys(terms, "input.elements", input.Elements)
+	addKeys(terms, "input.oneOf", input.OneOf)
+	addKeys(terms, "input.keys", input.Keys)
+	addKeys(terms, "input.typeOf", input.TypeOf)
+	addKeys(terms, "input.notEquals", input.NotEquals)
+	addKeys(terms, "input.captured", input.Captured)
+	addConditions(terms, "input.any", input.Any)
+	addPaths(terms, "headers.equals", "", stub.Headers.Equals)
+	addPaths(terms, "headers.contains", "", stub.Headers.Contains)
+	addPaths(terms, "headers.matches", "", stub.Headers.Matches)
+	addKeys(terms, "headers.captured", stub.Headers.Captured)
+
+	for name := range stub.Trailers {
+		terms["trailers:"+strings.ToLower(name)] = struct{}{}
+	}
+
+	for name, set := range map[string]bool{
+		"input.size":     input.MinBytes > 0 || input.MaxBytes > 0,
+		"input.sequence": input.Sequence != nil,
+		"emptyBody":      stub.EmptyBody,
+		"headersExact":   stub.HeadersExact,
+		"peer":           stub.PeerMatch != "",
+		"bodyChecksum":   stub.BodyChecksum != nil,
+		"cel":            stub.CEL != "",
+		"expr":           stub.Expr != "",
+		"custom":         stub.Matcher != nil,
+	} {
+		if set {
+			terms[name] = struct{}{}
+		}
+	}
+
+	return terms
+}
+
+// addPaths adds a term for the path of every leaf of the value.
+func addPaths(terms map[string]struct{}, matcher, path string, value map[string]any) {
+	for key, item := range value {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+
+		if nested, ok := item.(map[string]any); ok && len(nested) > 0 {
+			addPaths(terms, matcher, field, nested)
+
+			continue
+		}
+
+		terms[matcher+":"+field] = struct{}{}
+	}
+}
+
+// addKeys adds a term for every path of a map keyed by paths.
+func addKeys[V any](terms map[string]struct{}, matcher string, entries map[string]V) {
+	for path := range entries {
+		terms[matcher+":"+path] = struct{}{}
+	}
+}
+
+// addConditions adds a term for the path of every leaf of the conditions,
+// however they are nested.
+func addConditions(terms map[string]struct{}, matcher string, conditions []Condition) {
+	for _, condition := range conditions {
+		addPaths(terms, matcher, "", condition.Equals)
+		addPaths(terms, matcher, "", condition.Contains)
+		addPaths(terms, matcher, "", condition.Matches)
+		addConditions(terms, matcher, condition.All)
+		addConditions(terms, matcher, condition.Any)
+	}
+}
@@ -0,0 +1,49 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_GroupByMatcherSignature(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(method string, id interface{}, status string) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  method,
+			Input: stuber.InputData{
+				Equals: map[string]interface{}{"user": map[string]interface{}{"id": id}},
+				OneOf:  map[string][]interface{}{"status": {status}},
+			},
+			Output: stuber.Output{Data: map[string]interface{}{}},
+		}
+	}
+
+	first := newStub("Get", 1, "active")
+	second := newStub("Update", "abc", "closed")
+	headers := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{"x-tenant": "acme"}},
+		Input:   stuber.InputData{Contains: map[string]interface{}{"tags": []interface{}{"a"}}},
+		CEL:     "data.size() > 0",
+		Output:  stuber.Output{Data: map[string]interface{}{}},
+	}
+	plain := &stuber.Stub{ID: uuid.New(), Service: "Users", Method: "List", Output: stuber.Output{Data: map[string]interface{}{}}}
+
+	s.PutMany(first, headers, second, plain)
+
+	require.Equal(t, map[string][]*stuber.Stub{
+		"input.equals:user.id; input.oneOf:status":          {first, second},
+		"cel; headers.equals:x-tenant; input.contains:tags": {headers},
+		"": {plain},
+	}, s.GroupByMatcherSignature())
+}
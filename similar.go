@@ -0,0 +1,104 @@
+package stuber
+
+import (
+	"cmp"
+	"slices"
+)
+
+// defaultSimilarLimit is the number of similar candidates collected when no
+// limit is configured.
+const defaultSimilarLimit = 3
+
+// candidate is a stub that did not match the query together with its rank.
+type candidate struct {
+	stub *Stub
+	rank float64
+}
+
+// WithMinSimilarRank suppresses similar stubs ranked below the given rank,
+// so a search that only finds an unrelated stub reports ErrStubNotFound
+// instead of suggesting it.
+//
+// The default of zero reports any similar stub.
+func WithMinSimilarRank(rank float64) Option {
+	return func(s *searcher) {
+		s.minSimilarRank = max(rank, 0)
+	}
+}
+
+// similarLimit returns the number of similar candidates to collect for the query.
+//
+// Candidates are collected when the searcher is configured to do so or the
+// query asks for them. Zero means they are not collected.
+func (s *searcher) similarLimit(query Query) int {
+	if s.similarN > 0 {
+		return s.similarN
+	}
+
+	if query.SimilarCandidates {
+		return defaultSimilarLimit
+	}
+
+	return 0
+}
+
+// topSimilar returns the stubs of the highest ranked candidates.
+//
+// Candidates with equal ranks keep their order, so the first stub is the one
+// reported by Result.Similar.
+//
+// Parameters:
+// - candidates: The candidates to choose from.
+// - limit: The maximum number of stubs to return.
+//
+// Returns:
+// - []*Stub: The stubs sorted by rank in descending order.
+func topSimilar(candidates []candidate, limit int) []*Stub {
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(b.rank, a.rank)
+	})
+
+	stubs := make([]*Stub, 0, min(limit, len(candidates)))
+
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		stubs = append(stubs, c.stub)
+	}
+
+	return stubs
+}
+
+// globalNearest returns the stubs whose input data ranks highest against the
+// request data, across all services and methods.
+//
+// Only the body is ranked, headers and trailers are ignored. Stubs with a
+// zero rank are left out, and stubs with equal ranks are ordered by service,
+// method and ID. It is a diagnostic aid and does not mark any stub as used.
+//
+// Parameters:
+// - data: The request data.
+// - n: The maximum number of stubs to return.
+//
+// Returns:
+// - []*Stub: The stubs sorted by rank in descending order.
+func (s *searcher) globalNearest(data map[string]any, n int) []*Stub {
+	if n <= 0 {
+		return nil
+	}
+
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	stubs := s.all()
+	slices.SortFunc(stubs, compareStubs)
+
+	candidates := make([]candidate, 0, len(stubs))
+
+	for _, stub := range stubs {
+		if rank := rankBody(data, stub); rank > 0 {
+			candidates = append(candidates, candidate{stub: stub, rank: rank})
+		}
+	}
+
+	return topSimilar(candidates, n)
+}
@@ -0,0 +1,169 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestResult_SimilarN(t *testing.T) {
+	stubs := make([]*stuber.Stub, 0, 4)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		stubs = append(stubs, &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  "SayHello",
+			Input: stuber.InputData{Equals: map[string]interface{}{
+				"name":  name,
+				"field": "value",
+			}},
+			Output: stuber.Output{Data: map[string]interface{}{"message": name}},
+		})
+	}
+
+	query := stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Data:    map[string]interface{}{"name": "e", "field": "value"},
+	}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stubs...)
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.NotNil(t, r.Similar())
+	require.Empty(t, r.SimilarN())
+
+	query.SimilarCandidates = true
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Len(t, r.SimilarN(), 3)
+	require.Same(t, r.Similar(), r.SimilarN()[0])
+
+	s = stuber.NewBudgerigar(features.New(), stuber.WithSimilarCandidates(10))
+	s.PutMany(stubs...)
+
+	query.SimilarCandidates = false
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Len(t, r.SimilarN(), 4)
+	require.Same(t, r.Similar(), r.SimilarN()[0])
+}
+
+func TestBudgerigar_IncludeSimilar(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	best := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"id": "1", "kind": "admin"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"name": "best"}},
+	}
+	runnerUp := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"id": "1"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"name": "runner-up"}},
+	}
+
+	s.PutMany(best, runnerUp)
+
+	query := stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{"id": "1", "kind": "admin"}}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, best, r.Found())
+	require.Nil(t, r.Similar())
+
+	query.IncludeSimilar = true
+
+	r, err = s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, best, r.Found())
+	require.Same(t, runnerUp, r.Similar())
+	require.Equal(t, best.Output, r.Output())
+}
+
+func TestBudgerigar_MinSimilarRank(t *testing.T) {
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob", "lang": "en"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+
+	query := stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "Bob", "lang": "fr"}}
+
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(stub)
+
+	eval, err := s.Evaluate(query)
+	require.NoError(t, err)
+	require.Same(t, stub, eval.Similar)
+	require.Positive(t, eval.SimilarScore)
+
+	s = stuber.NewBudgerigar(features.New(), stuber.WithMinSimilarRank(eval.SimilarScore))
+	s.PutMany(stub)
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Similar())
+
+	s = stuber.NewBudgerigar(features.New(), stuber.WithMinSimilarRank(eval.SimilarScore+0.1))
+	s.PutMany(stub)
+
+	r, err = s.FindByQuery(query)
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+}
+
+func TestBudgerigar_GlobalNearest(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	orders := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Orders",
+		Method:  "Get",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"orderId": "42", "region": "eu"}},
+		Output:  stuber.Output{Error: "boom"},
+	}
+
+	users := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Get",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{"authorization": "secret"}},
+		Input:   stuber.InputData{Contains: map[string]interface{}{"region": "eu"}},
+		Output:  stuber.Output{Error: "boom"},
+	}
+
+	billing := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Billing",
+		Method:  "Charge",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"amount": "10"}},
+		Output:  stuber.Output{Error: "boom"},
+	}
+
+	s.PutMany(orders, users, billing)
+
+	data := map[string]interface{}{"orderId": "42", "region": "eu"}
+
+	require.Equal(t, []*stuber.Stub{orders, users}, s.GlobalNearest(data, 2))
+	require.Equal(t, []*stuber.Stub{orders}, s.GlobalNearest(data, 1))
+	require.Empty(t, s.GlobalNearest(data, 0))
+	require.Empty(t, s.Used())
+}
@@ -0,0 +1,81 @@
+package stuber
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Snapshot is an in-process copy of the state of a searcher, taken by
+// Budgerigar.Snapshot and applied by Budgerigar.Restore.
+//
+// It holds the stored stubs, their usage and the match counters, but not the
+// options, locked services, subscribers, search log or generations.
+type Snapshot struct {
+	storage     storageSnapshot
+	stubUsed    map[uuid.UUID]time.Time
+	calls       map[uuid.UUID]int
+	occurrences map[uuid.UUID]int
+	hits        map[group]int
+	turns       map[group]int
+	captured    map[string]any
+	overrides   map[uuid.UUID]outputOverride
+}
+
+// snapshot copies the state of the searcher.
+//
+// The maps are copied, while the stubs are shared, since stored stubs are
+// replaced rather than modified. This is much cheaper than an export.
+//
+// Returns:
+// - *Snapshot: The copy of the state of the searcher.
+func (s *searcher) snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &Snapshot{
+		storage:     s.storage.snapshot(),
+		stubUsed:    maps.Clone(s.stubUsed),
+		calls:       maps.Clone(s.calls),
+		occurrences: maps.Clone(s.occurrences),
+		hits:        maps.Clone(s.hits),
+		turns:       maps.Clone(s.turns),
+		captured:    maps.Clone(s.captured),
+		overrides:   maps.Clone(s.overrides),
+	}
+}
+
+// restore replaces the state of the searcher with the snapshot under the
+// write lock.
+//
+// The snapshot is copied again, so it can be restored any number of times.
+// Subscribers and incremental syncs see it as if the stubs were cleared and
+// the ones of the snapshot added.
+//
+// Parameters:
+// - snapshot: The snapshot to restore.
+func (s *searcher) restore(snapshot *Snapshot) {
+	s.mu.Lock()
+
+	// Record the restore as the deletion of every stub followed by the
+	// addition of the ones of the snapshot, so incremental syncs see it.
+	restored := slices.Collect(maps.Keys(snapshot.storage.state.itemsByID))
+
+	s.bury(slices.Collect(maps.Keys(s.modGeneration))...)
+	s.storage.restore(snapshot.storage)
+	s.touch(restored...)
+	s.stubUsed = maps.Clone(snapshot.stubUsed)
+	s.calls = maps.Clone(snapshot.calls)
+	s.occurrences = maps.Clone(snapshot.occurrences)
+	s.hits = maps.Clone(snapshot.hits)
+	s.turns = maps.Clone(snapshot.turns)
+	s.captured = maps.Clone(snapshot.captured)
+	s.overrides = maps.Clone(snapshot.overrides)
+
+	s.mu.Unlock()
+
+	s.subscribers.emit(ChangeCleared, nil)
+	s.subscribers.emit(ChangeAdded, restored)
+}
@@ -0,0 +1,74 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_SnapshotRestore(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	first := &stuber.Stub{
+		ID:          uuid.New(),
+		Service:     "Greeter",
+		Method:      "SayHello",
+		Output:      stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+		Occurrences: []stuber.Occurrence{{Times: 1, Output: stuber.Output{Error: "warming up"}}},
+	}
+	second := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHi", Output: stuber.Output{Error: "boom"}}
+
+	s.PutMany(first, second)
+
+	query := stuber.Query{Service: "Greeter", Method: "SayHello"}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Equal(t, "warming up", r.Output().Error)
+
+	snapshot := s.Snapshot()
+
+	all := s.AllOrdered()
+	used, unused := s.PartitionByUsage()
+
+	for range 2 {
+		// Diverge from the snapshot in every way.
+		third := &stuber.Stub{ID: uuid.New(), Service: "Echo", Method: "Ping", Output: stuber.Output{Error: "boom"}}
+
+		s.PutMany(third)
+		s.DeleteByID(second.ID)
+
+		_, err := s.FindByQuery(query)
+		require.NoError(t, err)
+
+		_, err = s.IncrementAndGet(third.ID)
+		require.NoError(t, err)
+
+		s.Restore(snapshot)
+
+		require.Equal(t, all, s.AllOrdered())
+
+		restoredUsed, restoredUnused := s.PartitionByUsage()
+		require.ElementsMatch(t, used, restoredUsed)
+		require.ElementsMatch(t, unused, restoredUnused)
+		require.Nil(t, s.FindByID(third.ID))
+		require.Same(t, second, s.FindByID(second.ID))
+
+		n, err := s.IncrementAndGet(first.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		// The occurrences continue from the snapshot.
+		r, err := s.FindByQuery(query)
+		require.NoError(t, err)
+		require.Equal(t, first.Output, r.Output())
+	}
+
+	// Restoring a snapshot of the empty state removes all stubs.
+	s.Restore(stuber.NewBudgerigar(features.New()).Snapshot())
+	require.Empty(t, s.All())
+}
@@ -1,6 +1,7 @@
 package stuber
 
 import (
+	"cmp"
 	"errors"
 	"maps"
 	"slices"
@@ -28,30 +29,145 @@ type Value interface {
 
 // storage is a struct that manages the storage of search results.
 //
-// It contains a mutex for concurrent access, the total number of stored items,
-// maps to store and retrieve values by their left and right values, a map to
-// store values by their UUID, and a map to retrieve values by their UUID.
+// It contains a mutex for concurrent access, the total number of stored items
+// and a pointer to the current state, which holds the maps used to store and
+// retrieve values.
+//
+// By default, readers lock the mutex for reading and writers modify the state
+// in place under the write lock. In copy-on-write mode, readers load the state
+// without any locking, and writers build a modified copy of the state under
+// the write lock and then publish it atomically.
 type storage struct {
-	mu         sync.RWMutex          // Mutex for concurrent access.
-	leftTotal  atomic.Uint64         // Total number of stored left values.
-	rightTotal atomic.Uint64         // Total number of stored right values.
+	mu          timedRWMutex                 // Mutex for concurrent access.
+	leftTotal   atomic.Uint64                // Total number of stored left values.
+	rightTotal  atomic.Uint64                // Total number of stored right values.
+	current     atomic.Pointer[storageState] // The current state of the storage.
+	copyOnWrite bool                         // Whether writers replace the state instead of modifying it.
+}
+
+// storageState holds the maps of the storage.
+//
+// It contains maps to store and retrieve values by their left and right
+// values, a map to store values by their position, a map to retrieve values
+// by their UUID, and a cache of views built from the values of a position.
+type storageState struct {
 	lefts      map[string]uint64     // Map to store values by their left values.
 	rights     map[string]uint64     // Map to store values by their right values.
 	leftRights map[uint64][]uint64   // Map to store the right values associated with a left value.
 	items      map[uuid.UUID][]Value // Map to store values by their UUID.
 	itemsByID  map[uuid.UUID]Value   // Map to retrieve values by their UUID.
+	views      *sync.Map             // Map to cache views built from the values of a position.
+	order      map[uuid.UUID]uint64  // Map to retrieve the insertion sequence number of a value by its UUID.
+	nextOrder  uint64                // The sequence number of the next inserted value.
 }
 
 // newStorage creates a new storage instance.
 //
 // It creates a new instance of the storage struct with empty maps.
 func newStorage() *storage {
-	return &storage{
+	s := &storage{}
+	s.current.Store(newStorageState(0))
+
+	return s
+}
+
+// newCopyOnWriteStorage creates a new storage instance in copy-on-write mode.
+//
+// Reads never lock the storage, at the cost of copying the maps on every write.
+func newCopyOnWriteStorage() *storage {
+	s := newStorage()
+	s.copyOnWrite = true
+
+	return s
+}
+
+// newStorageState creates an empty state sized for the given number of values.
+func newStorageState(size int) *storageState {
+	return &storageState{
 		rights:     map[string]uint64{},
 		lefts:      map[string]uint64{},
 		leftRights: map[uint64][]uint64{},
 		items:      map[uuid.UUID][]Value{},
-		itemsByID:  map[uuid.UUID]Value{},
+		itemsByID:  make(map[uuid.UUID]Value, size),
+		views:      &sync.Map{},
+		order:      make(map[uuid.UUID]uint64, size),
+	}
+}
+
+// clone returns a copy of the state that can be modified without affecting
+// the readers of the original.
+//
+// The slices stored in the maps are shared, so they must be replaced rather
+// than modified in place.
+func (st *storageState) clone() *storageState {
+	views := &sync.Map{}
+
+	st.views.Range(func(key, value any) bool {
+		views.Store(key, value)
+
+		return true
+	})
+
+	return &storageState{
+		lefts:      maps.Clone(st.lefts),
+		rights:     maps.Clone(st.rights),
+		leftRights: maps.Clone(st.leftRights),
+		items:      maps.Clone(st.items),
+		itemsByID:  maps.Clone(st.itemsByID),
+		views:      views,
+		order:      maps.Clone(st.order),
+		nextOrder:  st.nextOrder,
+	}
+}
+
+// load returns the current state of the storage.
+func (s *storage) load() *storageState {
+	return s.current.Load()
+}
+
+// read returns the state for reading and a function that releases it.
+//
+// In copy-on-write mode the state is immutable and no lock is taken.
+func (s *storage) read() (*storageState, func()) {
+	if s.copyOnWrite {
+		return s.load(), func() {}
+	}
+
+	s.mu.RLock()
+
+	return s.load(), s.mu.RUnlock
+}
+
+// write returns the state for writing and a function that commits it.
+//
+// The storage is locked for writing until the commit function is called. In
+// copy-on-write mode the returned state is a copy, which is published by the
+// commit function.
+func (s *storage) write() (*storageState, func()) {
+	s.mu.Lock()
+
+	st, publish := s.edit()
+
+	return st, func() {
+		publish()
+		s.mu.Unlock()
+	}
+}
+
+// edit returns the state for writing and a function that publishes it.
+//
+// The caller must hold the write lock until the state is published. In
+// copy-on-write mode the returned state is a copy, and the current state is
+// left untouched if it is never published.
+func (s *storage) edit() (*storageState, func()) {
+	if !s.copyOnWrite {
+		return s.load(), func() {}
+	}
+
+	next := s.load().clone()
+
+	return next, func() {
+		s.current.Store(next)
 	}
 }
 
@@ -62,26 +178,84 @@ func (s *storage) clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Reset the total number of stored left values.
-	s.leftTotal = atomic.Uint64{}
+	s.current.Store(s.reset(0))
+}
 
-	// Reset the total number of stored right values.
-	s.rightTotal = atomic.Uint64{}
+// compact rebuilds the storage to release the memory retained by deleted values.
+//
+// The internal maps are recreated with the capacity of the remaining values,
+// and the left and right IDs are reassigned to the names that are still in
+// use. The order of the values within a position is preserved. All cached
+// views are dropped.
+func (s *storage) compact() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.load()
+	st := s.reset(len(prev.itemsByID))
+
+	// Reinsert the remaining values, once even if stored under several right values.
+	for _, values := range prev.items {
+		for _, v := range values {
+			if _, ok := st.itemsByID[v.Key()]; !ok {
+				s.put(st, v)
+			}
+		}
+	}
+
+	// Keep the insertion order of the remaining values.
+	st.order = maps.Clone(prev.order)
+	st.nextOrder = prev.nextOrder
+
+	// Publish the rebuilt state once it is complete.
+	s.current.Store(st)
+}
+
+// storageSnapshot is a copy of the state and counters of a storage.
+type storageSnapshot struct {
+	state      *storageState // The copied state, never published itself.
+	leftTotal  uint64        // The total number of stored left values.
+	rightTotal uint64        // The total number of stored right values.
+}
+
+// snapshot returns a copy of the current state of the storage.
+//
+// The maps are copied, while the stored values are shared.
+func (s *storage) snapshot() storageSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	// Reset the map that stores values by their left values.
-	s.lefts = map[string]uint64{}
+	return storageSnapshot{
+		state:      s.load().clone(),
+		leftTotal:  s.leftTotal.Load(),
+		rightTotal: s.rightTotal.Load(),
+	}
+}
 
-	// Reset the map that stores values by their right values.
-	s.rights = make(map[string]uint64)
+// restore replaces the state of the storage with a copy of the snapshot, so
+// the same snapshot can be restored again.
+func (s *storage) restore(snapshot storageSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Reset the map that stores the right values associated with a left value.
-	s.leftRights = map[uint64][]uint64{}
+	s.leftTotal.Store(snapshot.leftTotal)
+	s.rightTotal.Store(snapshot.rightTotal)
+	s.current.Store(snapshot.state.clone())
+}
 
-	// Reset the map that stores values by their UUID.
-	s.items = map[uuid.UUID][]Value{}
+// reset resets the counters and returns an empty state sized for the given
+// number of values.
+//
+// The caller must hold the write lock and publish the returned state.
+func (s *storage) reset(size int) *storageState {
+	// Reset the total number of stored left values.
+	s.leftTotal.Store(0)
 
-	// Reset the map that retrieves values by their UUID.
-	s.itemsByID = map[uuid.UUID]Value{}
+	// Reset the total number of stored right values.
+	s.rightTotal.Store(0)
+
+	// Reset the maps and drop all the cached views.
+	return newStorageState(size)
 }
 
 func (s *storage) values() []Value {
@@ -89,7 +263,53 @@ func (s *storage) values() []Value {
 	//
 	// This function returns a slice of Value objects containing all the values
 	// stored in the storage. The values are returned in an arbitrary order.
-	return slices.Collect(maps.Values(s.itemsByID))
+	st, done := s.read()
+	defer done()
+
+	return slices.Collect(maps.Values(st.itemsByID))
+}
+
+// ordered returns all the values stored in the storage in the order they
+// were first inserted.
+//
+// Updated values keep the position of their first insertion, deleted values
+// are dropped from the order.
+func (s *storage) ordered() []Value {
+	st, done := s.read()
+	defer done()
+
+	values := slices.Collect(maps.Values(st.itemsByID))
+
+	slices.SortFunc(values, func(a, b Value) int {
+		return cmp.Compare(st.order[a.Key()], st.order[b.Key()])
+	})
+
+	return values
+}
+
+// filter returns all the values that satisfy the given predicate.
+//
+// The predicate is called while the storage is locked for reading, so it
+// must not modify the storage.
+//
+// Parameters:
+// - predicate: The function used to filter the values.
+//
+// Returns:
+//   - []Value: A slice containing all the values that satisfy the predicate.
+func (s *storage) filter(predicate func(Value) bool) []Value {
+	st, done := s.read()
+	defer done()
+
+	results := make([]Value, 0)
+
+	for _, v := range st.itemsByID {
+		if predicate(v) {
+			results = append(results, v)
+		}
+	}
+
+	return results
 }
 
 // findAll retrieves all the values associated with a given left and right values.
@@ -108,18 +328,92 @@ func (s *storage) values() []Value {
 //   - error: A nil error if the values are found, otherwise an error indicating
 //     that the values were not found.
 func (s *storage) findAll(left, right string) ([]Value, error) {
+	// Lock the storage for reading.
+	st, done := s.read()
+	defer done()
+
+	// Find the position of the given left and right values.
+	pos, err := st.posByN(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the values associated with the given position.
+	return st.items[pos], nil
+}
+
+// view returns a view built from all the values associated with the given
+// left and right values.
+//
+// The view is built by fn on the first call and cached until a value of the
+// same position is inserted, updated or deleted, so concurrent readers share
+// a single instance. The view must be treated as immutable.
+//
+// Parameters:
+// - left: The left value to search for.
+// - right: The right value to search for.
+// - fn: The function that builds the view from the values.
+//
+// Returns:
+//   - any: The view returned by fn.
+//   - error: An error if the left or right value is not found.
+func (s *storage) view(left, right string, fn func([]Value) any) (any, error) {
+	// Lock the storage for reading, writers drop the views they affect
+	// under the write lock, so a view is never built from stale values.
+	st, done := s.read()
+	defer done()
+
 	// Find the position of the given left and right values.
-	pos, err := s.posByN(left, right)
+	pos, err := st.posByN(left, right)
 	if err != nil {
 		return nil, err
 	}
 
+	// Try the cached view first.
+	if v, ok := st.views.Load(pos); ok {
+		return v, nil
+	}
+
+	// Concurrent readers may build the same view, the first one wins.
+	v, _ := st.views.LoadOrStore(pos, fn(st.items[pos]))
+
+	return v, nil
+}
+
+// findByLeft retrieves all the values associated with the given left value,
+// regardless of their right value.
+//
+// Parameters:
+// - left: The left value to search for.
+//
+// Returns:
+//   - []Value: A slice containing all the values associated with the left value.
+//   - error: An error if the left value is not found.
+func (s *storage) findByLeft(left string) ([]Value, error) {
 	// Lock the storage for reading.
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	st, done := s.read()
+	defer done()
 
-	// Retrieve the values associated with the given position.
-	return s.items[pos], nil
+	leftID, ok := st.lefts[left]
+	if !ok {
+		return nil, ErrLeftNotFound
+	}
+
+	results := make([]Value, 0)
+	seen := make(map[uuid.UUID]struct{})
+
+	// Collect the values of every right value associated with the left value,
+	// values stored under several right values are collected once.
+	for _, rightID := range st.leftRights[leftID] {
+		for _, v := range st.items[s.pos(leftID, rightID)] {
+			if _, ok := seen[v.Key()]; !ok {
+				seen[v.Key()] = struct{}{}
+				results = append(results, v)
+			}
+		}
+	}
+
+	return results, nil
 }
 
 // findByID retrieves the value associated with the given ID.
@@ -134,11 +428,11 @@ func (s *storage) findAll(left, right string) ([]Value, error) {
 //   - Value: The value associated with the given ID, or nil if no value is
 //     found.
 func (s *storage) findByID(key uuid.UUID) Value { //nolint:ireturn
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	st, done := s.read()
+	defer done()
 
 	// Check if the value exists in the storage.
-	if v, ok := s.itemsByID[key]; ok {
+	if v, ok := st.itemsByID[key]; ok {
 		return v
 	}
 
@@ -158,8 +452,8 @@ func (s *storage) findByID(key uuid.UUID) Value { //nolint:ireturn
 //   - []Value: A slice of values associated with the given IDs.
 func (s *storage) findByIDs(keys ...uuid.UUID) []Value {
 	// Lock the storage for reading.
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	st, done := s.read()
+	defer done()
 
 	// Initialize a slice to store the results.
 	results := make([]Value, 0, len(keys))
@@ -167,7 +461,7 @@ func (s *storage) findByIDs(keys ...uuid.UUID) []Value {
 	// Iterate over each key.
 	for _, key := range keys {
 		// Check if the value exists in the storage.
-		if v, ok := s.itemsByID[key]; ok {
+		if v, ok := st.itemsByID[key]; ok {
 			// Append the value to the results if it exists.
 			results = append(results, v)
 		}
@@ -185,78 +479,168 @@ func (s *storage) upsert(values ...Value) []uuid.UUID {
 	// or updated values.
 	results := make([]uuid.UUID, len(values))
 
+	// Lock the storage for writing.
+	st, commit := s.write()
+	defer commit()
+
 	for i, v := range values {
-		// Get the ID of the left value. If it does not exist, create a new ID.
-		leftID := s.leftIDOrNew(v.Left())
+		// Store the key and value in the storage.
+		results[i] = v.Key()
 
-		// Get the ID of the right value. If it does not exist, create a new ID.
-		rightID := s.rightIDOrNew(v.Right())
+		s.put(st, v)
+	}
+
+	// Return the keys of the inserted or updated values.
+	return results
+}
+
+// update replaces the value stored under the given key with the result of fn.
+//
+// The whole read-modify-write cycle runs under the write lock, so concurrent
+// writers cannot interleave with it. The value returned by fn must keep the key.
+//
+// Parameters:
+// - key: The ID of the value to update.
+// - fn: The function that builds the new value from the stored one.
+//
+// Returns:
+//   - bool: True if the value was found and updated, otherwise false.
+func (s *storage) update(key uuid.UUID, fn func(Value) Value) bool {
+	// Lock the storage for writing.
+	st, commit := s.write()
+	defer commit()
+
+	prev, ok := st.itemsByID[key]
+	if !ok {
+		return false
+	}
+
+	s.put(st, fn(prev))
+
+	return true
+}
+
+// put stores the value in the state, replacing the previous value with the
+// same key.
+//
+// The caller must hold the write lock.
+func (s *storage) put(st *storageState, v Value) {
+	// Remove the previous version of the value, it may be stored under
+	// other left and right pairs.
+	if prev, ok := st.itemsByID[v.Key()]; ok {
+		for _, right := range rightsOf(prev) {
+			prevPos := s.pos(st.lefts[prev.Left()], st.rights[right])
+			st.items[prevPos] = slices.DeleteFunc(slices.Clone(st.items[prevPos]), func(value Value) bool {
+				return value.Key() == prev.Key()
+			})
+
+			st.views.Delete(prevPos)
+		}
+	}
+
+	// Get the ID of the left value, creating it if needed.
+	leftID := s.newLeftID(st, v.Left())
+
+	// Store the value under every right value it answers.
+	for _, right := range rightsOf(v) {
+		rightID := s.newRightID(st, right)
 
 		// Calculate the index of the value based on the left and right IDs.
 		ind := s.pos(leftID, rightID)
 
-		// Lock the storage for writing.
-		s.mu.Lock()
+		if !slices.Contains(st.leftRights[leftID], rightID) {
+			st.leftRights[leftID] = append(slices.Clip(st.leftRights[leftID]), rightID)
+		}
 
-		// Store the key and value in the storage.
-		results[i] = v.Key()
+		st.items[ind] = append(slices.Clip(st.items[ind]), v)
+
+		st.views.Delete(ind)
+	}
 
-		s.leftRights[leftID] = append(s.leftRights[leftID], rightID)
-		s.items[ind] = append(s.items[ind], v)
-		s.itemsByID[v.Key()] = v
+	st.itemsByID[v.Key()] = v
 
-		// Unlock the storage.
-		s.mu.Unlock()
+	// Number the value in insertion order, an update keeps its number.
+	if _, ok := st.order[v.Key()]; !ok {
+		st.order[v.Key()] = st.nextOrder
+		st.nextOrder++
 	}
+}
 
-	// Return the keys of the inserted or updated values.
-	return results
+// aliased is implemented by values stored under more than one right value.
+type aliased interface {
+	Aliases() []string // The right values the value is stored under besides Right.
+}
+
+// rightsOf returns the distinct right values the value is stored under.
+func rightsOf(v Value) []string {
+	rights := []string{v.Right()}
+
+	if a, ok := v.(aliased); ok {
+		for _, alias := range a.Aliases() {
+			if !slices.Contains(rights, alias) {
+				rights = append(rights, alias)
+			}
+		}
+	}
+
+	return rights
 }
 
 // del deletes the values with the given keys from the storage.
 //
 // The function returns the number of values that were successfully deleted.
 func (s *storage) del(keys ...uuid.UUID) int {
+	// Lock the storage for writing.
+	st, commit := s.write()
+	defer commit()
+
+	return s.remove(st, keys...)
+}
+
+// remove deletes the values with the given keys from the state.
+//
+// The caller must hold the write lock. The function returns the number of
+// values that were successfully deleted.
+func (s *storage) remove(st *storageState, keys ...uuid.UUID) int {
 	result := 0
+
 	// Map to store the keys to be deleted for each position.
 	deleteIDs := make(map[uuid.UUID][]uuid.UUID, len(keys))
 
 	// Iterate over the keys to be deleted.
 	for _, key := range keys {
 		// Get the value associated with the key.
-		v := s.findByID(key)
+		v, ok := st.itemsByID[key]
 		// Skip if the value doesn't exist.
-		if v == nil {
+		if !ok {
 			continue
 		}
 
-		// Get the position of the value in the storage.
-		pos, err := s.posByN(v.Left(), v.Right())
-		// Skip if the position couldn't be determined.
-		if err != nil {
-			continue
+		// Add the key to the list of keys to be deleted for every position of the value.
+		for _, right := range rightsOf(v) {
+			// Skip the positions that couldn't be determined.
+			if pos, err := st.posByN(v.Left(), right); err == nil {
+				deleteIDs[pos] = append(deleteIDs[pos], key)
+			}
 		}
 
-		// Add the key to the list of keys to be deleted for the position.
-		deleteIDs[pos] = append(deleteIDs[pos], key)
 		result++
 	}
 
-	// Lock the storage for writing.
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	// Delete the values with the keys from the storage.
 	for pos, v := range deleteIDs {
-		s.items[pos] = slices.DeleteFunc(s.items[pos], func(value Value) bool {
+		st.items[pos] = slices.DeleteFunc(slices.Clone(st.items[pos]), func(value Value) bool {
 			// Check if the key of the value is in the list of keys to be deleted.
 			return slices.Contains(v, value.Key())
 		})
+
+		st.views.Delete(pos)
 	}
 
-	// Delete the values from the itemsByID map.
+	// Delete the values from the itemsByID map and the insertion order.
 	for _, key := range keys {
-		delete(s.itemsByID, key)
+		delete(st.itemsByID, key)
+		delete(st.order, key)
 	}
 
 	// Return the number of values that were successfully deleted.
@@ -277,11 +661,11 @@ func (s *storage) leftID(name string) (uint64, error) {
 	//   - uint64: The ID associated with the given left name, or 0 if no ID is
 	//     found.
 	//   - error: An error if the left name is not found.
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	st, done := s.read()
+	defer done()
 
 	// Check if the ID exists in the lefts map.
-	if id, ok := s.lefts[name]; ok {
+	if id, ok := st.lefts[name]; ok {
 		// Return the ID if it exists.
 		return id, nil
 	}
@@ -309,14 +693,27 @@ func (s *storage) leftIDOrNew(name string) uint64 {
 	}
 
 	// Acquire a write lock to ensure atomicity.
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	st, commit := s.write()
+	defer commit()
+
+	return s.newLeftID(st, name)
+}
+
+// newLeftID returns the ID associated with the given left name, creating a
+// new ID if it does not exist yet.
+//
+// The caller must hold the write lock.
+func (s *storage) newLeftID(st *storageState, name string) uint64 {
+	// Another writer may have created the ID in the meantime.
+	if id, ok := st.lefts[name]; ok {
+		return id
+	}
 
 	// Create a new ID by incrementing the total count of lefts.
-	s.lefts[name] = s.leftTotal.Add(1)
+	st.lefts[name] = s.leftTotal.Add(1)
 
 	// Return the newly created ID.
-	return s.lefts[name]
+	return st.lefts[name]
 }
 
 // rightID returns the ID associated with the given right name.
@@ -332,11 +729,11 @@ func (s *storage) leftIDOrNew(name string) uint64 {
 //   - error: An error if the ID is not found.
 func (s *storage) rightID(name string) (uint64, error) {
 	// Acquire a read lock to ensure read consistency.
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	st, done := s.read()
+	defer done()
 
 	// Check if the ID exists in the rights map.
-	if id, ok := s.rights[name]; ok {
+	if id, ok := st.rights[name]; ok {
 		// Return the ID if it exists.
 		return id, nil
 	}
@@ -353,14 +750,27 @@ func (s *storage) rightIDOrNew(name string) uint64 {
 	}
 
 	// Acquire a write lock to ensure atomicity.
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	st, commit := s.write()
+	defer commit()
+
+	return s.newRightID(st, name)
+}
+
+// newRightID returns the ID associated with the given right name, creating a
+// new ID if it does not exist yet.
+//
+// The caller must hold the write lock.
+func (s *storage) newRightID(st *storageState, name string) uint64 {
+	// Another writer may have created the ID in the meantime.
+	if id, ok := st.rights[name]; ok {
+		return id
+	}
 
 	// Create a new ID by incrementing the total count of rights.
-	s.rights[name] = s.rightTotal.Add(1)
+	st.rights[name] = s.rightTotal.Add(1)
 
 	// Return the newly created ID.
-	return s.rights[name]
+	return st.rights[name]
 }
 
 // posByN retrieves the position associated with the given left and right values.
@@ -378,31 +788,56 @@ func (s *storage) rightIDOrNew(name string) uint64 {
 //   - uuid.UUID: A UUID representing the position of the given left and right values.
 //   - error: An error if the ID is not found or the left-right combination does not exist.
 func (s *storage) posByN(left, right string) (uuid.UUID, error) {
+	// Acquire a read lock to ensure read consistency.
+	st, done := s.read()
+	defer done()
+
+	return st.posByN(left, right)
+}
+
+// has checks if any value is stored under the given left and right values.
+//
+// Parameters:
+// - left: The left value to look up.
+// - right: The right value to look up.
+//
+// Returns:
+//   - bool: True if at least one value is stored, otherwise false.
+func (s *storage) has(left, right string) bool {
+	st, done := s.read()
+	defer done()
+
+	pos, err := st.posByN(left, right)
+
+	return err == nil && len(st.items[pos]) > 0
+}
+
+// posByN retrieves the position associated with the given left and right
+// values in the state.
+//
+// The caller must hold the lock or own the state.
+func (st *storageState) posByN(left, right string) (uuid.UUID, error) {
 	// Get the ID associated with the given left value.
 	// If the ID exists, continue.
-	leftID, err := s.leftID(left)
-	if err != nil {
-		return uuid.Nil, err
+	leftID, ok := st.lefts[left]
+	if !ok {
+		return uuid.Nil, ErrLeftNotFound
 	}
 
 	// Get the ID associated with the given right value.
 	// If the ID exists, continue.
-	rightID, err := s.rightID(right)
-	if err != nil {
-		return uuid.Nil, err
+	rightID, ok := st.rights[right]
+	if !ok {
+		return uuid.Nil, ErrRightNotFound
 	}
 
-	// Acquire a read lock to ensure read consistency.
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	// Check if the left-right combination exists in the leftRights map.
-	if !slices.Contains(s.leftRights[leftID], rightID) {
+	if !slices.Contains(st.leftRights[leftID], rightID) {
 		return uuid.Nil, ErrRightNotFound
 	}
 
 	// Calculate the position based on the left and right IDs.
-	return s.pos(leftID, rightID), nil
+	return pos(leftID, rightID), nil
 }
 
 // pos calculates the UUID based on the given left and right values.
@@ -413,9 +848,14 @@ func (s *storage) posByN(left, right string) (uuid.UUID, error) {
 //
 // Returns:
 //   - uuid.UUID: The calculated UUID.
+func (s *storage) pos(left, right uint64) uuid.UUID {
+	return pos(left, right)
+}
+
+// pos calculates the UUID based on the given left and right values.
 //
 //nolint:mnd
-func (s *storage) pos(left, right uint64) uuid.UUID {
+func pos(left, right uint64) uuid.UUID {
 	return uuid.UUID{
 		byte(left >> 56),
 		byte(left >> 48),
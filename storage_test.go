@@ -80,8 +80,8 @@ func TestAdd(t *testing.T) {
 
 	require.Equal(t, uint64(5), s.leftTotal.Load())
 	require.Equal(t, uint64(3), s.rightTotal.Load())
-	require.Len(t, s.items, 5)
-	require.Len(t, s.itemsByID, 6)
+	require.Len(t, s.load().items, 5)
+	require.Len(t, s.load().itemsByID, 6)
 }
 
 func TestUpdate(t *testing.T) {
@@ -92,8 +92,8 @@ func TestUpdate(t *testing.T) {
 
 	require.Equal(t, uint64(1), s.leftTotal.Load())
 	require.Equal(t, uint64(1), s.rightTotal.Load())
-	require.Len(t, s.items, 1)
-	require.Len(t, s.itemsByID, 1)
+	require.Len(t, s.load().items, 1)
+	require.Len(t, s.load().itemsByID, 1)
 
 	v := s.findByID(id)
 	require.NotNil(t, v)
@@ -106,8 +106,8 @@ func TestUpdate(t *testing.T) {
 
 	require.Equal(t, uint64(1), s.leftTotal.Load())
 	require.Equal(t, uint64(1), s.rightTotal.Load())
-	require.Len(t, s.items, 1)
-	require.Len(t, s.itemsByID, 1)
+	require.Len(t, s.load().items, 1)
+	require.Len(t, s.load().itemsByID, 1)
 
 	v = s.findByID(id)
 	require.NotNil(t, v)
@@ -117,6 +117,64 @@ func TestUpdate(t *testing.T) {
 	require.Equal(t, 42, val.value)
 }
 
+func TestUpdateMove(t *testing.T) {
+	id := uuid.New()
+
+	s := newStorage()
+	s.upsert(&testItem{id: id, left: "Greeter", right: "SayHello"})
+	s.upsert(&testItem{id: id, left: "Greeter", right: "SayHello"})
+
+	all, err := s.findAll("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	require.True(t, s.update(id, func(v Value) Value {
+		return &testItem{id: id, left: "Greeter", right: "SayHello2", value: v.(*testItem).value + 1} //nolint:forcetypeassert
+	}))
+	require.False(t, s.update(uuid.New(), func(v Value) Value { return v }))
+
+	all, err = s.findAll("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Empty(t, all)
+
+	all, err = s.findAll("Greeter", "SayHello2")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, 1, all[0].(*testItem).value) //nolint:forcetypeassert
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	id := uuid.New()
+
+	s := newCopyOnWriteStorage()
+	s.upsert(&testItem{id: id, left: "Greeter", right: "SayHello"})
+
+	snapshot := s.load()
+
+	s.upsert(
+		&testItem{id: id, left: "Greeter", right: "SayHello", value: 1},
+		&testItem{id: uuid.New(), left: "Greeter", right: "SayHello"},
+	)
+
+	require.NotSame(t, snapshot, s.load())
+	require.Len(t, snapshot.itemsByID, 1)
+	require.Equal(t, 0, snapshot.itemsByID[id].(*testItem).value) //nolint:forcetypeassert
+
+	all, err := s.findAll("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, 1, s.findByID(id).(*testItem).value) //nolint:forcetypeassert
+
+	require.Equal(t, 1, s.del(id))
+	require.Len(t, s.load().itemsByID, 1)
+
+	s.compact()
+
+	all, err = s.findAll("Greeter", "SayHello")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+}
+
 func TestFindByID(t *testing.T) {
 	id := uuid.MustParse("00000000-0000-0001-0000-000000000000")
 
@@ -135,8 +193,8 @@ func TestFindByID(t *testing.T) {
 
 	require.Equal(t, uint64(5), s.leftTotal.Load())
 	require.Equal(t, uint64(3), s.rightTotal.Load())
-	require.Len(t, s.items, 6)
-	require.Len(t, s.itemsByID, 7)
+	require.Len(t, s.load().items, 6)
+	require.Len(t, s.load().itemsByID, 7)
 
 	val := s.findByID(id)
 	require.NotNil(t, val)
@@ -157,8 +215,8 @@ func TestFindAll(t *testing.T) {
 
 	require.Equal(t, uint64(5), s.leftTotal.Load())
 	require.Equal(t, uint64(3), s.rightTotal.Load())
-	require.Len(t, s.items, 6)
-	require.Len(t, s.itemsByID, 7)
+	require.Len(t, s.load().items, 6)
+	require.Len(t, s.load().itemsByID, 7)
 
 	g1s1, err := s.findAll("Greeter1", "SayHello1")
 	require.NoError(t, err)
@@ -190,20 +248,20 @@ func TestDelete(t *testing.T) {
 	require.Equal(t, 0, s.del())
 	require.Equal(t, uint64(3), s.leftTotal.Load())
 	require.Equal(t, uint64(3), s.rightTotal.Load())
-	require.Len(t, s.items, 3)
-	require.Len(t, s.itemsByID, 3)
+	require.Len(t, s.load().items, 3)
+	require.Len(t, s.load().itemsByID, 3)
 
 	require.Equal(t, 1, s.del(id1))
 	require.Equal(t, uint64(3), s.leftTotal.Load())
 	require.Equal(t, uint64(3), s.rightTotal.Load())
-	require.Len(t, s.items, 3)
-	require.Len(t, s.itemsByID, 2)
+	require.Len(t, s.load().items, 3)
+	require.Len(t, s.load().itemsByID, 2)
 
 	require.Equal(t, 2, s.del(id2, id3))
 	require.Equal(t, uint64(3), s.leftTotal.Load())
 	require.Equal(t, uint64(3), s.rightTotal.Load())
-	require.Len(t, s.items, 3)
-	require.Empty(t, s.itemsByID)
+	require.Len(t, s.load().items, 3)
+	require.Empty(t, s.load().itemsByID)
 }
 
 func TestPos(t *testing.T) {
@@ -228,3 +286,96 @@ func TestPos(t *testing.T) {
 		require.Equal(t, test.guid.String(), newStorage().pos(test.left, test.right).String())
 	}
 }
+
+type aliasedItem struct {
+	testItem
+	aliases []string
+}
+
+func (t aliasedItem) Aliases() []string {
+	return t.aliases
+}
+
+func TestAliases(t *testing.T) {
+	id := uuid.New()
+
+	s := newStorage()
+	s.upsert(&aliasedItem{
+		testItem: testItem{id: id, left: "Greeter", right: "SayHello"},
+		aliases:  []string{"SayHi", "SayHello", "SayHey"},
+	})
+
+	for _, right := range []string{"SayHello", "SayHi", "SayHey"} {
+		all, err := s.findAll("Greeter", right)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+	}
+
+	all, err := s.findByLeft("Greeter")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	s.upsert(&aliasedItem{
+		testItem: testItem{id: id, left: "Greeter", right: "SayHello"},
+		aliases:  []string{"SayHi"},
+	})
+
+	all, err = s.findAll("Greeter", "SayHey")
+	require.NoError(t, err)
+	require.Empty(t, all)
+
+	s.compact()
+
+	all, err = s.findAll("Greeter", "SayHi")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	require.Equal(t, 1, s.del(id))
+
+	for _, right := range []string{"SayHello", "SayHi"} {
+		all, err := s.findAll("Greeter", right)
+		require.NoError(t, err)
+		require.Empty(t, all)
+	}
+
+	require.Empty(t, s.load().itemsByID)
+}
+
+func TestOrdered(t *testing.T) {
+	for _, s := range []*storage{newStorage(), newCopyOnWriteStorage()} {
+		ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+
+		s.upsert(
+			&testItem{id: ids[0], left: "Greeter2", right: "SayHello"},
+			&testItem{id: ids[1], left: "Greeter1", right: "SayHello"},
+		)
+		s.upsert(&testItem{id: ids[2], left: "Greeter1", right: "SayHi"})
+
+		// An update keeps the position, even when it moves the value.
+		s.upsert(&testItem{id: ids[0], left: "Greeter3", right: "SayHey", value: 1})
+
+		keys := func() []uuid.UUID {
+			values := s.ordered()
+			keys := make([]uuid.UUID, len(values))
+
+			for i, v := range values {
+				keys[i] = v.Key()
+			}
+
+			return keys
+		}
+
+		require.Equal(t, ids[:3], keys())
+		require.Equal(t, 1, s.ordered()[0].(*testItem).value) //nolint:forcetypeassert
+
+		require.Equal(t, 1, s.del(ids[1]))
+		s.upsert(&testItem{id: ids[3], left: "Greeter1", right: "SayHello"})
+		s.upsert(&testItem{id: ids[1], left: "Greeter1", right: "SayHello"})
+
+		require.Equal(t, []uuid.UUID{ids[0], ids[2], ids[3], ids[1]}, keys())
+
+		s.compact()
+
+		require.Equal(t, []uuid.UUID{ids[0], ids[2], ids[3], ids[1]}, keys())
+	}
+}
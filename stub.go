@@ -1,10 +1,24 @@
 package stuber
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 )
 
+// ErrServiceEmpty is returned when a stub has no service name.
+var ErrServiceEmpty = errors.New("service is empty")
+
+// ErrMethodEmpty is returned when a stub has no method name.
+var ErrMethodEmpty = errors.New("method is empty")
+
+// ErrOutputEmpty is returned when a stub has neither a response body nor an error status.
+var ErrOutputEmpty = errors.New("output has neither data nor error status")
+
 // Stub represents a gRPC service method and its associated data.
 type Stub struct {
 	ID      uuid.UUID   `json:"id"`      // The unique identifier of the stub.
@@ -13,6 +27,67 @@ type Stub struct {
 	Headers InputHeader `json:"headers"` // The headers of the request.
 	Input   InputData   `json:"input"`   // The input data of the request.
 	Output  Output      `json:"output"`  // The output data of the response.
+
+	Switch   *OutputSwitch     `json:"switch,omitempty"`   // The outputs selected by a request field.
+	Captures map[string]string `json:"captures,omitempty"` // The output fields to capture when matched, keyed by capture name.
+	Matcher  Matcher           `json:"-"`                  // The custom condition of the request, not serialized.
+	CEL      string            `json:"cel,omitempty"`      // The CEL expression the request must satisfy.
+	Expr     string            `json:"expr,omitempty"`     // The matcher expression the request must satisfy, see ParseMatcher.
+
+	// HeaderCaptures are the request headers to capture when matched, keyed
+	// by capture name, e.g. a trace ID to match the later steps of a flow.
+	HeaderCaptures map[string]string `json:"headerCaptures,omitempty"`
+
+	// CreatedAt is the time the stub was first stored, set when it is zero.
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+
+	// ActivateAfter is the number of prior matches of the stubs of the same
+	// service and method after which the stub starts matching.
+	ActivateAfter int `json:"activateAfter,omitempty"`
+	// Occurrences are the outputs returned for the first matches of the stub,
+	// before it falls back to Output.
+	Occurrences []Occurrence `json:"occurrences,omitempty"`
+
+	// Methods are additional method names the stub answers besides Method.
+	Methods []string `json:"methods,omitempty"`
+
+	// HeadersExact rejects queries carrying headers the stub does not declare.
+	HeadersExact bool `json:"headersExact,omitempty"`
+
+	// Trailers are the trailers the request must carry, names are compared
+	// case-insensitively.
+	Trailers map[string]string `json:"trailers,omitempty"`
+
+	// EmptyBody restricts the stub to queries without request data.
+	EmptyBody bool `json:"emptyBody,omitempty"`
+
+	// MatchMode is the mode the exact and partial inputs are compared in.
+	// When empty, the default of the service applies, if any, otherwise
+	// each input is compared the way it was authored.
+	MatchMode MatchMode `json:"matchMode,omitempty"`
+
+	// BodyChecksum is the checksum the request body must have.
+	BodyChecksum *Checksum `json:"bodyChecksum,omitempty"`
+
+	// Latency is the distribution of the delays of the responses, sampled
+	// by Result.SampleDelay.
+	Latency *LatencyProfile `json:"latency,omitempty"`
+
+	// Priority decides which of conflicting stubs ImportResolve keeps, the
+	// highest wins. It does not affect matching.
+	Priority int `json:"priority,omitempty"`
+
+	// Disabled excludes the stub from searches, it can still be found by ID.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// MatchProbability is the share of the searches the stub matches in,
+	// e.g. to model a flaky endpoint. Searches it does not match in fall
+	// through to the other stubs. Zero, like one, means every search.
+	MatchProbability float64 `json:"matchProbability,omitempty"`
+
+	// PeerMatch is the address of the peer the request must come from, or
+	// the CIDR range containing it.
+	PeerMatch string `json:"peerMatch,omitempty"`
 }
 
 // Key returns the unique identifier of the stub.
@@ -30,12 +105,195 @@ func (s Stub) Right() string {
 	return s.Method
 }
 
+// Aliases returns the additional method names the stub answers.
+func (s Stub) Aliases() []string {
+	return s.Methods
+}
+
+// OutputFor returns the output of the stub for the given request data.
+//
+// If the stub has an output switch and the request carries one of its cases
+// in the switch field, the output of that case is returned. Otherwise the
+// stub's default output is returned.
+//
+// Parameters:
+// - data: The request data.
+//
+// Returns:
+// - Output: The output of the stub for the request data.
+func (s Stub) OutputFor(data map[string]interface{}) Output {
+	if s.Switch == nil {
+		return s.Output
+	}
+
+	value, ok := data[s.Switch.Field]
+	if !ok {
+		return s.Output
+	}
+
+	if output, ok := s.Switch.Cases[fmt.Sprint(value)]; ok {
+		return output
+	}
+
+	return s.Output
+}
+
+// Validate checks that the stub can be matched and is able to produce a response.
+//
+// It reports every problem found: an empty service or method name, a regular
+// or CEL expression that does not compile, an unknown field type or match
+// mode, a negative tolerance, an invalid peer range, body checksum, latency
+// profile or match probability, and an output, including the outputs of the
+// occurrences, with neither a response body nor an error status.
+//
+// Returns:
+// - error: The joined validation errors, or nil if the stub is valid.
+func (s Stub) Validate() error {
+	var errs []error
+
+	if s.Service == "" {
+		errs = append(errs, ErrServiceEmpty)
+	}
+
+	if s.Method == "" || slices.Contains(s.Methods, "") {
+		errs = append(errs, ErrMethodEmpty)
+	}
+
+	errs = append(errs, s.matcherErrors()...)
+
+	if s.Latency != nil {
+		if err := s.Latency.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := validateProbability(s.MatchProbability); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.Output.empty() {
+		errs = append(errs, ErrOutputEmpty)
+	}
+
+	for i, occurrence := range s.Occurrences {
+		if occurrence.Output.empty() {
+			errs = append(errs, fmt.Errorf("occurrence %d: %w", i, ErrOutputEmpty))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// matcherErrors compiles the matchers of the stub without matching anything.
+//
+// It reports the regular and CEL expressions that do not compile, the
+// unknown field types and match modes, the negative tolerances and the
+// invalid peer ranges and body checksums.
+//
+// Returns:
+// - []error: The compilation errors, or nil if every matcher compiles.
+func (s Stub) matcherErrors() []error {
+	var errs []error
+
+	if err := compileMatches(s.Input.Matches); err != nil {
+		errs = append(errs, fmt.Errorf("input: %w", err))
+	}
+
+	if err := compileConditions(s.Input.Any); err != nil {
+		errs = append(errs, fmt.Errorf("input: %w", err))
+	}
+
+	if seq := s.Input.Sequence; seq != nil {
+		anchors := make([]Condition, 0, 3)
+
+		for _, anchor := range []*Condition{seq.First, seq.Last, seq.Any} {
+			if anchor != nil {
+				anchors = append(anchors, *anchor)
+			}
+		}
+
+		if err := compileConditions(anchors); err != nil {
+			errs = append(errs, fmt.Errorf("input sequence: %w", err))
+		}
+	}
+
+	if err := compileKeys(s.Input.Keys); err != nil {
+		errs = append(errs, fmt.Errorf("input: %w", err))
+	}
+
+	if err := compileTypeOf(s.Input.TypeOf); err != nil {
+		errs = append(errs, fmt.Errorf("input: %w", err))
+	}
+
+	if err := compileTolerance(s.Input.Tolerance); err != nil {
+		errs = append(errs, fmt.Errorf("input: %w", err))
+	}
+
+	if s.CEL != "" {
+		if _, err := compileCEL(s.CEL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.Expr != "" {
+		if _, err := compileExpr(s.Expr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := compilePeer(s.PeerMatch); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.BodyChecksum != nil {
+		if err := s.BodyChecksum.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := compileMatches(s.Headers.Matches); err != nil {
+		errs = append(errs, fmt.Errorf("headers: %w", err))
+	}
+
+	switch s.MatchMode {
+	case "", MatchModeEquals, MatchModeContains:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidMatchMode, s.MatchMode))
+	}
+
+	return errs
+}
+
 // InputData represents the input data of a gRPC request.
 type InputData struct {
 	IgnoreArrayOrder bool                   `json:"ignoreArrayOrder,omitempty"` // Whether to ignore the order of arrays in the input data.
 	Equals           map[string]interface{} `json:"equals"`                     // The data to match exactly.
 	Contains         map[string]interface{} `json:"contains"`                   // The data to match partially.
 	Matches          map[string]interface{} `json:"matches"`                    // The data to match using regular expressions.
+	MinBytes         int                    `json:"minBytes,omitempty"`         // The minimum size of the serialized data, if set.
+	MaxBytes         int                    `json:"maxBytes,omitempty"`         // The maximum size of the serialized data, if set.
+	Captured         map[string]string      `json:"captured,omitempty"`         // The fields to match against captured values, keyed by field.
+	Defaults         map[string]interface{} `json:"defaults,omitempty"`         // The values of absent top-level fields.
+	Items            map[string]ItemBounds  `json:"items,omitempty"`            // The item count bounds of collection fields, keyed by path.
+	Times            map[string]TimeMatch   `json:"times,omitempty"`            // The time windows of time fields, keyed by path.
+	Any              []Condition            `json:"any,omitempty"`              // The alternative conditions, one of which must match.
+	Elements         map[string][]any       `json:"elements,omitempty"`         // The elements array fields must contain, keyed by path.
+	OneOf            map[string][]any       `json:"oneOf,omitempty"`            // The allowed values of fields, keyed by path.
+	Sequence         *SequenceMatch         `json:"sequence,omitempty"`         // The matcher of the client stream messages.
+	Keys             map[string]KeyMatch    `json:"keys,omitempty"`             // The matchers of objects with dynamic keys, keyed by path.
+	TypeOf           map[string]string      `json:"typeOf,omitempty"`           // The JSON types of fields, keyed by path.
+	IgnoreCase       map[string]bool        `json:"ignoreCase,omitempty"`       // The case-insensitive string fields, keyed by path.
+	Normalizers      map[string]string      `json:"normalizers,omitempty"`      // The names of the field normalizers, keyed by path.
+	NotEquals        map[string]NotEqual    `json:"notEquals,omitempty"`        // The values fields must not hold, keyed by path.
+	Tolerance        map[string]float64     `json:"tolerance,omitempty"`        // The tolerances of float fields, keyed by path.
+}
+
+// ItemBounds is the range of the number of items of an array or object field.
+//
+// Both bounds are inclusive, a zero bound is not checked.
+type ItemBounds struct {
+	MinItems int `json:"minItems,omitempty"` // The minimum number of items, if set.
+	MaxItems int `json:"maxItems,omitempty"` // The maximum number of items, if set.
 }
 
 // GetEquals returns the data to match exactly.
@@ -55,9 +313,10 @@ func (i InputData) GetMatches() map[string]interface{} {
 
 // InputHeader represents the headers of a gRPC request.
 type InputHeader struct {
-	Equals   map[string]interface{} `json:"equals"`   // The headers to match exactly.
-	Contains map[string]interface{} `json:"contains"` // The headers to match partially.
-	Matches  map[string]interface{} `json:"matches"`  // The headers to match using regular expressions.
+	Equals   map[string]interface{} `json:"equals"`             // The headers to match exactly.
+	Contains map[string]interface{} `json:"contains"`           // The headers to match partially.
+	Matches  map[string]interface{} `json:"matches"`            // The headers to match using regular expressions.
+	Captured map[string]string      `json:"captured,omitempty"` // The headers to match against captured values, keyed by header.
 }
 
 // GetEquals returns the headers to match exactly.
@@ -80,10 +339,45 @@ func (i InputHeader) Len() int {
 	return len(i.Equals) + len(i.Matches) + len(i.Contains)
 }
 
+// OutputSwitch selects the output of a stub by the value of a request field.
+type OutputSwitch struct {
+	Field string            `json:"field"` // The name of the top-level request field.
+	Cases map[string]Output `json:"cases"` // The outputs keyed by the string form of the field value.
+}
+
 // Output represents the output data of a gRPC response.
+//
+// A response is either the data or an error status. The status is given by
+// Code, or codes.Unknown if only Error is set, and Error is its message.
 type Output struct {
-	Headers map[string]string      `json:"headers"`        // The headers of the response.
-	Data    interface{}            `json:"data"`           // The data of the response.
-	Error   string                 `json:"error"`          // The error message of the response.
-	Code    *codes.Code            `json:"code,omitempty"` // The status code of the response.
+	Headers  map[string]string `json:"headers"`            // The headers of the response.
+	Trailers map[string]string `json:"trailers,omitempty"` // The trailers of the response.
+	Data     interface{}       `json:"data"`               // The data of the response.
+	Error    string            `json:"error"`              // The error message of the response.
+	Code     *codes.Code       `json:"code,omitempty"`     // The status code of the response.
+}
+
+// Status returns the gRPC status code and message of the response.
+//
+// An error message without an explicit code is reported as codes.Unknown.
+//
+// Returns:
+// - codes.Code: The status code of the response.
+// - string: The error message of the response.
+// - bool: Whether the response is an error status rather than a payload.
+func (o Output) Status() (codes.Code, string, bool) {
+	if o.Code != nil && *o.Code != codes.OK {
+		return *o.Code, o.Error, true
+	}
+
+	if o.Error != "" {
+		return codes.Unknown, o.Error, true
+	}
+
+	return codes.OK, "", false
+}
+
+// empty checks if the output has neither a response body nor an error status.
+func (o Output) empty() bool {
+	return o.Data == nil && o.Error == "" && o.Code == nil
 }
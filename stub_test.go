@@ -0,0 +1,72 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestStub_Validate(t *testing.T) {
+	code := codes.Internal
+
+	require.ErrorIs(t, stuber.Stub{Service: "Greeter1", Method: "SayHello1"}.Validate(), stuber.ErrOutputEmpty)
+	require.NoError(t, stuber.Stub{Service: "Greeter1", Method: "SayHello1", Output: stuber.Output{Data: map[string]interface{}{}}}.Validate())
+	require.NoError(t, stuber.Stub{Service: "Greeter1", Method: "SayHello1", Output: stuber.Output{Error: "boom"}}.Validate())
+	require.NoError(t, stuber.Stub{Service: "Greeter1", Method: "SayHello1", Output: stuber.Output{Code: &code}}.Validate())
+
+	err := stuber.Stub{
+		Input:  stuber.InputData{Matches: map[string]interface{}{"name": "("}},
+		Output: stuber.Output{Error: "boom"},
+	}.Validate()
+	require.ErrorIs(t, err, stuber.ErrServiceEmpty)
+	require.ErrorIs(t, err, stuber.ErrMethodEmpty)
+	require.ErrorContains(t, err, "input: error parsing regexp")
+
+	// Only imports validate the output, writes store a stub without one.
+	s := stuber.NewBudgerigar(features.New())
+	require.Len(t, s.PutMany(&stuber.Stub{ID: uuid.New(), Service: "Greeter1", Method: "SayHello1"}), 1)
+}
+
+func TestBudgerigar_OutputSwitch(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(&stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "default"}},
+		Switch: &stuber.OutputSwitch{
+			Field: "type",
+			Cases: map[string]stuber.Output{
+				"A": {Data: map[string]interface{}{"message": "respA"}},
+				"B": {Data: map[string]interface{}{"message": "respB"}},
+			},
+		},
+	})
+
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want interface{}
+	}{
+		{"case A", map[string]interface{}{"name": "Bob", "type": "A"}, map[string]interface{}{"message": "respA"}},
+		{"case B", map[string]interface{}{"name": "Bob", "type": "B"}, map[string]interface{}{"message": "respB"}},
+		{"default", map[string]interface{}{"name": "Bob", "type": "C"}, map[string]interface{}{"message": "default"}},
+		{"missing field", map[string]interface{}{"name": "Bob"}, map[string]interface{}{"message": "default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: tt.data})
+			require.NoError(t, err)
+			require.NotNil(t, r.Found())
+			require.Equal(t, tt.want, r.Output().Data)
+		})
+	}
+}
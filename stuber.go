@@ -1,6 +1,10 @@
 package stuber
 
 import (
+	"io"
+	"slices"
+	"time"
+
 	"github.com/bavix/features"
 	"github.com/google/uuid"
 	"golang.org/x/text/cases"
@@ -22,30 +26,54 @@ type Budgerigar struct {
 //
 // Parameters:
 // - toggles: The features.Toggles to use.
+// - opts: The Options used to configure the searcher.
 //
 // Returns:
 // - A new Budgerigar.
-func NewBudgerigar(toggles features.Toggles) *Budgerigar {
+func NewBudgerigar(toggles features.Toggles, opts ...Option) *Budgerigar {
 	return &Budgerigar{
-		searcher: newSearcher(),
+		searcher: newSearcher(opts...),
 		toggles:  toggles,
 	}
 }
 
 // PutMany inserts the given Stub values into the Budgerigar. If a Stub value
-// does not have a key, a new UUID is generated for its key.
+// does not have a key, a new UUID is generated for its key, derived from its
+// content if the Budgerigar was created WithContentIDs.
+//
+// Expected values of the input and header matchers may reference environment
+// variables, e.g. "${env:EXPECTED_TENANT}", which are resolved on insert.
+// Nothing is inserted if any Stub value has a CEL or matcher expression or a
+// peer range that does not compile, references an unset environment variable
+// or an unregistered normalizer, or belongs to a locked service.
 //
 // Parameters:
 // - values: The Stub values to insert.
 //
 // Returns:
-// - []uuid.UUID: The keys of the inserted Stub values.
+// - []uuid.UUID: The keys of the inserted Stub values, nil if nothing was inserted.
 func (b *Budgerigar) PutMany(values ...*Stub) []uuid.UUID {
+	ids, _ := b.PutManyE(values...)
+
+	return ids
+}
+
+// PutManyE inserts the given Stub values into the Budgerigar like PutMany,
+// and reports why nothing was inserted.
+//
+// Parameters:
+// - values: The Stub values to insert.
+//
+// Returns:
+// - []uuid.UUID: The keys of the inserted Stub values, nil if nothing was inserted.
+// - error: An error wrapping ErrInvalidCEL, ErrInvalidExpression, ErrInvalidPeer,
+// ErrUnresolvedEnv, ErrUnknownNormalizer or ErrServiceLocked if nothing was inserted.
+func (b *Budgerigar) PutManyE(values ...*Stub) ([]uuid.UUID, error) {
 	// Iterate over each Stub value.
 	for _, value := range values {
 		// If the Stub value does not have a key, generate a new UUID for its key.
 		if value.Key() == uuid.Nil {
-			value.ID = uuid.New()
+			value.ID = b.searcher.newID(value)
 		}
 	}
 
@@ -54,6 +82,21 @@ func (b *Budgerigar) PutMany(values ...*Stub) []uuid.UUID {
 }
 
 func (b *Budgerigar) UpdateMany(values ...*Stub) []uuid.UUID {
+	ids, _ := b.UpdateManyE(values...)
+
+	return ids
+}
+
+// UpdateManyE inserts or updates the given Stub values that have a key like
+// UpdateMany, and reports why nothing was written.
+//
+// Parameters:
+// - values: The Stub values to insert or update.
+//
+// Returns:
+// - []uuid.UUID: The keys of the inserted or updated Stub values, nil if nothing was written.
+// - error: An error wrapping the errors PutManyE reports if nothing was written.
+func (b *Budgerigar) UpdateManyE(values ...*Stub) ([]uuid.UUID, error) {
 	// Extract the values that have a non-nil key.
 	// These values will be updated in the searcher.
 	updates := make([]*Stub, 0, len(values))
@@ -76,14 +119,46 @@ func (b *Budgerigar) UpdateMany(values ...*Stub) []uuid.UUID {
 	return b.searcher.upsert(updates...)
 }
 
+// MergeByID applies the non-zero fields of the patch onto the Stub value with
+// the given ID. Zero-valued fields of the patch leave the stored value intact.
+//
+// Parameters:
+// - id: The UUID of the Stub value to patch.
+// - patch: The Stub value holding the fields to apply.
+//
+// Returns:
+// - error: ErrStubNotFound if there is no Stub value with the given ID, or
+// an error wrapping ErrServiceLocked if a locked service is affected, or one
+// of the errors PutMany refuses a Stub value for if the merged value has them.
+func (b *Budgerigar) MergeByID(id uuid.UUID, patch *Stub) error {
+	return b.searcher.merge(id, patch)
+}
+
 // DeleteByID deletes the Stub values with the given IDs from the Budgerigar's searcher.
 //
+// Nothing is deleted if any Stub value belongs to a locked service.
+//
 // Parameters:
 // - ids: The UUIDs of the Stub values to delete.
 //
 // Returns:
 // - int: The number of Stub values that were successfully deleted.
 func (b *Budgerigar) DeleteByID(ids ...uuid.UUID) int {
+	n, _ := b.DeleteByIDE(ids...)
+
+	return n
+}
+
+// DeleteByIDE deletes the Stub values with the given IDs like DeleteByID,
+// and reports why nothing was deleted.
+//
+// Parameters:
+// - ids: The UUIDs of the Stub values to delete.
+//
+// Returns:
+// - int: The number of Stub values that were successfully deleted.
+// - error: An error wrapping ErrServiceLocked if nothing was deleted.
+func (b *Budgerigar) DeleteByIDE(ids ...uuid.UUID) (int, error) {
 	// Delete the Stub values with the given IDs from the searcher.
 	// Returns the number of Stub values that were successfully deleted.
 	//
@@ -95,6 +170,66 @@ func (b *Budgerigar) DeleteByID(ids ...uuid.UUID) int {
 	return b.searcher.del(ids...)
 }
 
+// LockService protects the Stub values of the service in the Budgerigar's
+// searcher against writes until UnlockService is called.
+//
+// PutMany, UpdateMany and DeleteByID leave the Budgerigar unchanged when any
+// of their Stub values belongs to a locked service, while Import and
+// MergeByID report ErrServiceLocked. Searches are unaffected.
+//
+// Parameters:
+// - service: The name of the service to lock.
+func (b *Budgerigar) LockService(service string) {
+	b.searcher.lockService(service)
+}
+
+// UnlockService allows writes to the Stub values of the service again.
+//
+// Parameters:
+// - service: The name of the service to unlock.
+func (b *Budgerigar) UnlockService(service string) {
+	b.searcher.unlockService(service)
+}
+
+// Pin makes every query of the service and method that the matcher accepts
+// find the Stub value with the given ID, regardless of ranking, until Unpin
+// is called.
+//
+// Parameters:
+// - service: The service of the pinned queries.
+// - method: The method of the pinned queries.
+// - matcher: The pattern of the pinned queries, nil for every query.
+// - id: The UUID of the Stub value to respond with.
+//
+// Returns:
+// - error: ErrStubNotFound if there is no Stub value with the given ID.
+func (b *Budgerigar) Pin(service, method string, matcher Matcher, id uuid.UUID) error {
+	return b.searcher.pin(service, method, matcher, id)
+}
+
+// Unpin removes the pins of the service and method, restoring the normal
+// ranking of their queries.
+//
+// Parameters:
+// - service: The service of the pinned queries.
+// - method: The method of the pinned queries.
+func (b *Budgerigar) Unpin(service, method string) {
+	b.searcher.unpin(service, method)
+}
+
+// SetServiceDefault sets the match mode of the Stub values of the service
+// that do not set MatchMode themselves.
+//
+// A Stub value's own MatchMode always takes precedence, as does a query's
+// MatchModeOverride. An empty mode removes the default.
+//
+// Parameters:
+// - service: The name of the service.
+// - mode: The match mode of the Stub values of the service.
+func (b *Budgerigar) SetServiceDefault(service string, mode MatchMode) {
+	b.searcher.setServiceDefault(service, mode)
+}
+
 // FindByID retrieves the Stub value associated with the given ID from the Budgerigar's searcher.
 //
 // Parameters:
@@ -113,6 +248,33 @@ func (b *Budgerigar) FindByID(id uuid.UUID) *Stub {
 	return b.searcher.findByID(id)
 }
 
+// FindByIDPrefix returns the Stub values from the Budgerigar's searcher whose
+// ID starts with the given prefix, e.g. a truncated ID from a log.
+//
+// Parameters:
+// - prefix: The beginning of the ID, compared case-insensitively.
+// - unique: Whether the prefix must identify a single Stub value.
+//
+// Returns:
+// - []*Stub: The Stub values whose ID starts with the prefix, sorted by ID.
+// - error: ErrStubNotFound if there is no such Stub value, or an error
+// wrapping ErrAmbiguousPrefix if unique is set and there are several.
+func (b *Budgerigar) FindByIDPrefix(prefix string, unique bool) ([]*Stub, error) {
+	return b.searcher.findByIDPrefix(prefix, unique)
+}
+
+// ValidateQuery checks the query like Query.Validate, accepting an empty
+// method if the Budgerigar was created WithMethodWildcard.
+//
+// Parameters:
+// - query: The Query to check.
+//
+// Returns:
+// - error: The joined validation errors, or nil if the query is valid.
+func (b *Budgerigar) ValidateQuery(query Query) error {
+	return query.ValidateWith(b.searcher.methodWildcard)
+}
+
 // FindByQuery retrieves the Stub value associated with the given Query from the Budgerigar's searcher.
 //
 // Parameters:
@@ -140,6 +302,98 @@ func (b *Budgerigar) FindByQuery(query Query) (*Result, error) {
 	return b.searcher.find(query)
 }
 
+// Evaluate ranks the Stub values against the given Query without marking
+// the found one as used.
+//
+// Match probabilities are not drawn, a flaky stub is evaluated like one that
+// always matches.
+//
+// Parameters:
+// - query: The Query used to search for a Stub value.
+//
+// Returns:
+// - Evaluation: The found and similar Stub values with their ranks.
+// - error: An error if the service or method is not found.
+func (b *Budgerigar) Evaluate(query Query) (Evaluation, error) {
+	// Convert the method field the same way FindByQuery does.
+	if b.toggles.Has(MethodTitle) {
+		query.Method = cases.
+			Title(language.English, cases.NoLower).
+			String(query.Method)
+	}
+
+	return b.searcher.evaluate(b.searcher.transform(query), false)
+}
+
+// RankAll ranks every Stub value of the query's service and method without
+// marking anything, e.g. to chart why the found Stub value wins.
+//
+// Parameters:
+// - query: The Query to rank the Stub values for.
+//
+// Returns:
+// - []RankedStub: The Stub values with their ranks and match flags, highest rank first.
+// - error: ErrServiceNotFound or ErrMethodNotFound if there are no such Stub values.
+func (b *Budgerigar) RankAll(query Query) ([]RankedStub, error) {
+	// Convert the method field the same way FindByQuery does.
+	if b.toggles.Has(MethodTitle) {
+		query.Method = cases.
+			Title(language.English, cases.NoLower).
+			String(query.Method)
+	}
+
+	return b.searcher.rankAll(query)
+}
+
+// Replay runs the queries in order and reports the Stub value each one
+// matches, e.g. to detect when a change of the Stub values alters routing.
+//
+// Without mark, the queries are evaluated like by Evaluate and leave the
+// Budgerigar unchanged. With mark, they are searched like by FindByQuery.
+//
+// Parameters:
+// - queries: The queries to replay.
+// - mark: Whether to mark the matched Stub values as used.
+//
+// Returns:
+// - []ReplayResult: The outcome of every query, in the order of the queries.
+func (b *Budgerigar) Replay(queries []Query, mark bool) []ReplayResult {
+	// Convert the method fields the same way FindByQuery does.
+	if b.toggles.Has(MethodTitle) {
+		queries = slices.Clone(queries)
+
+		for i := range queries {
+			queries[i].Method = cases.
+				Title(language.English, cases.NoLower).
+				String(queries[i].Method)
+		}
+	}
+
+	return b.searcher.replay(queries, mark)
+}
+
+// FindByFullName retrieves the Stub value associated with the given Query,
+// taking the service and method from the full method name.
+//
+// Parameters:
+// - fullName: The full method name, either "service/method" or "/service/method".
+// - query: The Query used to search for a Stub value, its service and method are replaced.
+//
+// Returns:
+// - *Result: The Result containing the found Stub value (if any), or nil.
+// - error: ErrInvalidFullName if the name is malformed, or an error if the search fails.
+func (b *Budgerigar) FindByFullName(fullName string, query Query) (*Result, error) {
+	service, method, err := splitFullName(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Service = service
+	query.Method = method
+
+	return b.FindByQuery(query)
+}
+
 // FindBy retrieves all Stub values that match the given service and method
 // from the Budgerigar's searcher.
 //
@@ -151,7 +405,152 @@ func (b *Budgerigar) FindByQuery(query Query) (*Result, error) {
 // - []*Stub: The Stub values that match the given service and method, or nil if not found.
 // - error: An error if the search fails.
 func (b *Budgerigar) FindBy(service, method string) ([]*Stub, error) {
-	return b.searcher.findBy(service, method)
+	stubs, err := b.searcher.findBy(service, method)
+	if err != nil {
+		return nil, err
+	}
+
+	// The searcher shares the slice between readers, hand out a copy.
+	return slices.Clone(stubs), nil
+}
+
+// FindByOutput retrieves all Stub values whose output satisfies the given
+// predicate from the Budgerigar's searcher.
+//
+// Parameters:
+// - predicate: The function used to filter Stub values. It must not modify the Budgerigar.
+//
+// Returns:
+// - []*Stub: The Stub values that satisfy the predicate.
+func (b *Budgerigar) FindByOutput(predicate func(*Stub) bool) []*Stub {
+	return b.searcher.findByOutput(predicate)
+}
+
+// FindByTimeRange retrieves all Stub values created within the given time
+// range from the Budgerigar's searcher.
+//
+// The range is inclusive, a zero from or to leaves that end open.
+//
+// Parameters:
+// - from: The earliest creation time.
+// - to: The latest creation time.
+//
+// Returns:
+// - []*Stub: The Stub values sorted by their creation time.
+func (b *Budgerigar) FindByTimeRange(from, to time.Time) []*Stub {
+	return b.searcher.findByTimeRange(from, to)
+}
+
+// Subscribe returns a channel of the changes made to the Budgerigar's stubs
+// and a function that cancels the subscription.
+//
+// Writers never wait for subscribers. A subscriber that falls more than 64
+// events behind is dropped and its channel is closed.
+//
+// Returns:
+// - <-chan ChangeEvent: The channel of change events.
+// - func(): The function that cancels the subscription.
+func (b *Budgerigar) Subscribe() (<-chan ChangeEvent, func()) {
+	return b.searcher.subscribe()
+}
+
+// StubHistory returns the most recent searches that found the Stub value
+// with the given ID, newest first.
+//
+// The history is only kept when the Budgerigar is created with WithSearchLog.
+//
+// Parameters:
+// - id: The UUID of the Stub value.
+// - limit: The maximum number of events to return, a non-positive limit returns all of them.
+//
+// Returns:
+// - []SearchEvent: The search events of the Stub value.
+func (b *Budgerigar) StubHistory(id uuid.UUID, limit int) []SearchEvent {
+	return b.searcher.stubHistory(id, limit)
+}
+
+// FindDuplicates groups the Stub values of the same service and method whose
+// content is at least as similar as the threshold.
+//
+// The similarity is the Jaccard index of the flattened headers, input and
+// output of two stubs, between 0 and 1. The groups are meant for review,
+// nothing is merged.
+//
+// Parameters:
+// - threshold: The minimum similarity, between 0 and 1.
+//
+// Returns:
+// - [][]uuid.UUID: The groups of near-duplicate stub IDs.
+func (b *Budgerigar) FindDuplicates(threshold float64) [][]uuid.UUID {
+	return b.searcher.findDuplicates(threshold)
+}
+
+// GlobalNearest returns the Stub values of any service and method whose input
+// data ranks highest against the request data, e.g. to find out which
+// service a payload belongs to.
+//
+// Parameters:
+// - data: The request data.
+// - n: The maximum number of Stub values to return.
+//
+// Returns:
+// - []*Stub: The Stub values sorted by rank in descending order.
+func (b *Budgerigar) GlobalNearest(data map[string]interface{}, n int) []*Stub {
+	return b.searcher.globalNearest(data, n)
+}
+
+// Unsatisfiable returns the Stub values of the Budgerigar's searcher whose
+// matchers contradict each other, e.g. a field required to equal two
+// different values or a minimum size above the maximum, so that they can
+// never match.
+//
+// Returns:
+// - []*Stub: The Stub values that can never match.
+func (b *Budgerigar) Unsatisfiable() []*Stub {
+	return b.searcher.unsatisfiable()
+}
+
+// ShadowedBy returns the stored Stub values whose searches the candidate
+// would take: a search for the smallest query of a shadowed Stub value, built
+// from its exact or partial inputs, may find the candidate instead, since the
+// candidate matches it with at least the same rank.
+//
+// Only exact and partial inputs and the matchers refining them, e.g. ignored
+// case or tolerances, are compared. Stub values with other matchers, e.g.
+// regular expression, custom, size or capture ones, are skipped.
+//
+// Parameters:
+// - candidate: The Stub value that is about to be added.
+//
+// Returns:
+// - []*Stub: The stored Stub values shadowed by the candidate.
+func (b *Budgerigar) ShadowedBy(candidate *Stub) []*Stub {
+	return b.searcher.shadowedBy(candidate)
+}
+
+// AmbiguityReport returns the pairs of Stub values of the same service and
+// method that tie for some query, so that which one is found depends on
+// their order.
+//
+// Ties of stubs with only exact and partial inputs are all found, for other
+// matchers the report is best-effort.
+//
+// Returns:
+// - []AmbiguityPair: The tying pairs, each with a query proving the tie.
+func (b *Budgerigar) AmbiguityReport() []AmbiguityPair {
+	return b.searcher.ambiguityReport()
+}
+
+// AllOrdered returns all Stub values from the Budgerigar's searcher in the
+// order they were first inserted, which is how they were authored.
+//
+// Updated Stub values keep the position of their first insertion, deleted
+// ones are dropped.
+//
+// Returns:
+// - []*Stub: All Stub values in insertion order.
+func (b *Budgerigar) AllOrdered() []*Stub {
+	return b.searcher.allOrdered()
 }
 
 // All returns all Stub values from the Budgerigar's searcher.
@@ -178,6 +577,405 @@ func (b *Budgerigar) Unused() []*Stub {
 	return b.searcher.unused()
 }
 
+// PartitionByUsage returns the used and unused Stub values from the
+// Budgerigar's searcher as a consistent snapshot.
+//
+// Returns:
+// - []*Stub: The used Stub values.
+// - []*Stub: The unused Stub values.
+func (b *Budgerigar) PartitionByUsage() ([]*Stub, []*Stub) {
+	return b.searcher.partitionByUsage()
+}
+
+// SweepUsed drops the used marks of the Budgerigar's searcher that expired
+// under its used TTL, together with the use counts of their Stub values.
+// The values captured from matched Stub values are kept.
+//
+// Returns:
+// - int: The number of dropped marks.
+func (b *Budgerigar) SweepUsed() int {
+	return b.searcher.sweepUsed()
+}
+
+// RecomputeUsed replaces the used Stub values of the Budgerigar's searcher
+// with the ones satisfying the predicate, so Used and Unused reflect usage
+// determined elsewhere.
+//
+// The values captured from the outputs of matched Stub values are reset, like
+// by Clear.
+//
+// Parameters:
+// - pred: The predicate telling if a Stub value is used.
+func (b *Budgerigar) RecomputeUsed(pred func(*Stub) bool) {
+	b.searcher.recomputeUsed(pred)
+}
+
+// OverrideOutput makes the Stub value with the given ID in the Budgerigar's
+// searcher respond with the given output until the deadline passes, without
+// changing the stored Stub value.
+//
+// Parameters:
+// - id: The UUID of the Stub value to override.
+// - output: The output to respond with.
+// - until: The time the Stub value's own output resumes.
+//
+// Returns:
+// - error: ErrStubNotFound if there is no Stub value with the given ID.
+func (b *Budgerigar) OverrideOutput(id uuid.UUID, output Output, until time.Time) error {
+	return b.searcher.overrideOutput(id, output, until)
+}
+
+// GroupByMatcherSignature groups the Stub values of the Budgerigar's searcher
+// by the fields and headers their matchers constrain, ignoring the values.
+//
+// Returns:
+// - map[string][]*Stub: The Stub values keyed by their matcher signature.
+func (b *Budgerigar) GroupByMatcherSignature() map[string][]*Stub {
+	return b.searcher.groupByMatcherSignature()
+}
+
+// GroupByHeaderConstraints groups the Stub values of the service and method
+// by their header matchers, including the expected values, e.g. to list the
+// body variants gated by the same auth headers.
+//
+// Parameters:
+// - service: The service of the Stub values.
+// - method: The method of the Stub values.
+//
+// Returns:
+// - map[string][]*Stub: The Stub values keyed by the JSON of their header matchers.
+// - error: ErrServiceNotFound or ErrMethodNotFound if there are no such Stub values.
+func (b *Budgerigar) GroupByHeaderConstraints(service, method string) (map[string][]*Stub, error) {
+	return b.searcher.groupByHeaderConstraints(service, method)
+}
+
+// ValidateMatchers compiles the matchers of every Stub value in the
+// Budgerigar's searcher without matching anything, e.g. to fail CI fast on
+// a fixture with a regular expression that does not compile.
+//
+// Returns:
+// - []error: The compilation errors, each naming its Stub value, or nil if every matcher compiles.
+func (b *Budgerigar) ValidateMatchers() []error {
+	return b.searcher.validateMatchers()
+}
+
+// Quarantined returns the Stub values of the Budgerigar's searcher excluded
+// from the searches because their matchers panicked, with the reason of each.
+//
+// Updating, enabling or deleting a quarantined Stub value lifts its quarantine.
+//
+// Returns:
+// - []Quarantined: The quarantined Stub values, sorted by service, method and ID.
+func (b *Budgerigar) Quarantined() []Quarantined {
+	return b.searcher.quarantined()
+}
+
+// InvalidOutputs returns the Stub values of the Budgerigar's searcher with an
+// output that cannot be encoded as JSON, such as malformed json.RawMessage
+// data. Binary []byte outputs are not checked.
+//
+// Returns:
+// - []*Stub: The Stub values with an invalid output, in insertion order.
+func (b *Budgerigar) InvalidOutputs() []*Stub {
+	return b.searcher.invalidOutputs()
+}
+
+// RankHistogram counts the recent searches of the Budgerigar's searcher that
+// found a Stub value by the rank of the found Stub value. It requires the
+// searcher to be created WithSearchLog.
+//
+// Parameters:
+// - buckets: The lower bounds of the buckets.
+//
+// Returns:
+// - map[float64]int: The number of searches keyed by the bucket bound.
+func (b *Budgerigar) RankHistogram(buckets []float64) map[float64]int {
+	return b.searcher.rankHistogram(buckets)
+}
+
+// MissingMethods returns the expected methods that have no Stub value in the
+// Budgerigar's searcher.
+//
+// Parameters:
+// - expected: The expected methods keyed by service.
+//
+// Returns:
+// - map[string][]string: The methods without Stub values keyed by service,
+// empty if every expected method is stubbed.
+func (b *Budgerigar) MissingMethods(expected map[string][]string) map[string][]string {
+	return b.searcher.missingMethods(expected)
+}
+
+// UnconstrainedFields returns the fields of a sample query that no Stub value
+// of the service and method in the Budgerigar's searcher matches on.
+//
+// Parameters:
+// - service: The service of the Stub values.
+// - method: The method of the Stub values.
+// - fields: The dot-separated field paths of the sample query.
+//
+// Returns:
+// - []string: The unconstrained fields, in the order they were given.
+// - error: ErrServiceNotFound or ErrMethodNotFound if there are no Stub values.
+func (b *Budgerigar) UnconstrainedFields(service, method string, fields []string) ([]string, error) {
+	return b.searcher.unconstrainedFields(service, method, fields)
+}
+
+// RegisterNormalizer registers a function normalizing field values before
+// comparison, which Stub values reference by name in their input's
+// normalizers. Stub values referencing an unregistered name are rejected.
+//
+// Parameters:
+// - name: The name Stub values reference the normalizer by.
+// - fn: The function normalizing a field value.
+func (b *Budgerigar) RegisterNormalizer(name string, fn func(any) any) {
+	b.searcher.registerNormalizer(name, fn)
+}
+
+// SetEnabledWhere enables or disables the Stub values of the Budgerigar's
+// searcher satisfying the predicate. Disabled Stub values are skipped by
+// searches. Stub values of locked services are left as they are.
+//
+// Parameters:
+// - pred: The predicate selecting the Stub values to toggle.
+// - enabled: Whether to enable or disable the Stub values.
+//
+// Returns:
+// - int: The number of Stub values whose state changed.
+func (b *Budgerigar) SetEnabledWhere(pred func(*Stub) bool, enabled bool) int {
+	return b.searcher.setEnabledWhere(pred, enabled)
+}
+
+// Snapshot returns an in-process copy of the state of the Budgerigar's
+// searcher, which is much cheaper to take and restore than an export.
+//
+// Returns:
+// - *Snapshot: The copy of the state.
+func (b *Budgerigar) Snapshot() *Snapshot {
+	return b.searcher.snapshot()
+}
+
+// Restore replaces the state of the Budgerigar's searcher with the snapshot
+// taken by Snapshot. The same snapshot can be restored any number of times.
+//
+// Parameters:
+// - snapshot: The snapshot to restore.
+func (b *Budgerigar) Restore(snapshot *Snapshot) {
+	b.searcher.restore(snapshot)
+}
+
+// IncrementAndGet counts a use of the Stub value with the given ID in the
+// Budgerigar's searcher and returns its number of uses so far.
+//
+// Matches of the Stub value add to the same count, which allows enforcing
+// call quotas outside the searcher.
+//
+// Parameters:
+// - id: The UUID of the Stub value to count.
+//
+// Returns:
+// - int: The number of uses of the Stub value, including this one.
+// - error: ErrStubNotFound if there is no Stub value with the given ID.
+func (b *Budgerigar) IncrementAndGet(id uuid.UUID) (int, error) {
+	return b.searcher.incrementAndGet(id)
+}
+
+// ReadOnly returns a read-only handle to the Budgerigar, e.g. to pass it to
+// untrusted test helpers.
+//
+// The restriction is enforced by the type: the handle only has the methods
+// of ReadOnlySearcher, and cannot be converted back into the Budgerigar. It
+// observes the changes made through the Budgerigar itself.
+//
+// Returns:
+// - ReadOnlySearcher: The read-only handle.
+func (b *Budgerigar) ReadOnly() ReadOnlySearcher { //nolint:ireturn
+	return readOnly{ReadOnlySearcher: b}
+}
+
+// ETag returns a content-based hash of all Stub values from the Budgerigar's searcher.
+//
+// Returns:
+// - string: A hash that changes whenever any Stub value changes.
+func (b *Budgerigar) ETag() string {
+	return b.searcher.etag()
+}
+
+// Generation returns the generation of the last change of the Stub values,
+// to pass to ChangedSince later.
+//
+// Returns:
+// - uint64: The current generation, zero if nothing was written yet.
+func (b *Budgerigar) Generation() uint64 {
+	return b.searcher.currentGeneration()
+}
+
+// ChangedSince returns the changes of the Stub values after the given
+// generation, e.g. to sync remote mock nodes incrementally.
+//
+// A stub deleted and added again is reported as changed only.
+//
+// Parameters:
+// - gen: The generation the caller has already seen.
+//
+// Returns:
+// - []*Stub: The Stub values added or updated after the generation.
+// - []uuid.UUID: The IDs of the Stub values deleted after the generation.
+func (b *Budgerigar) ChangedSince(gen uint64) ([]*Stub, []uuid.UUID) {
+	return b.searcher.changedSince(gen)
+}
+
+// ApproxMemory estimates the number of bytes used by the Stub values in the
+// Budgerigar's searcher.
+//
+// The result is an estimate based on the serialized size of the Stub values
+// and the entries of the indexes, it grows linearly with their number and
+// size but does not account for every allocation.
+//
+// Returns:
+// - int64: The estimated number of bytes.
+func (b *Budgerigar) ApproxMemory() int64 {
+	return b.searcher.approxMemory()
+}
+
+// LockStats returns the time spent waiting for the locks of the
+// Budgerigar's searcher, which shows whether WithCopyOnWrite is worth
+// enabling.
+//
+// Returns:
+// - LockStats: The waits, all zero unless the Budgerigar was created WithLockStats.
+func (b *Budgerigar) LockStats() LockStats {
+	return b.searcher.lockStats()
+}
+
+// OutcomeCounts returns the number of searches per outcome made through the
+// Budgerigar's searcher.
+//
+// Returns:
+// - OutcomeCounts: The number of found, similar and not found searches.
+func (b *Budgerigar) OutcomeCounts() OutcomeCounts {
+	return b.searcher.outcomeCounts()
+}
+
+// Export serializes all Stub values from the Budgerigar's searcher into a
+// JSON array sorted by service, method and ID.
+//
+// Returns:
+// - []byte: The JSON array of Stub values.
+// - error: An error if a Stub value cannot be serialized.
+func (b *Budgerigar) Export() ([]byte, error) {
+	return b.searcher.export()
+}
+
+// ExportBinary serializes all Stub values from the Budgerigar's searcher into
+// a compact binary payload sorted by service, method and ID.
+//
+// The payload decodes much faster than JSON and is meant for trusted caches
+// written and read by the same version of the package. Use Export for
+// interchange.
+//
+// Returns:
+// - []byte: The binary payload.
+// - error: An error if a Stub value cannot be serialized.
+func (b *Budgerigar) ExportBinary() ([]byte, error) {
+	return b.searcher.exportBinary()
+}
+
+// ImportBinary loads a binary payload made by ExportBinary into the
+// Budgerigar's searcher.
+//
+// The Stub values are restored as they were stored, empty maps and slices
+// included. Unlike Import, they are not checked with Stub.Validate, but
+// nothing is loaded if PutMany would refuse any of them.
+//
+// Parameters:
+// - data: The binary payload.
+//
+// Returns:
+// - error: An error wrapping ErrUnsupportedVersion if the payload was written by another version of the
+// package, an error if it cannot be parsed, or one of the errors PutManyE reports.
+func (b *Budgerigar) ImportBinary(data []byte) error {
+	return b.searcher.importBinary(data)
+}
+
+// ExportTo writes the Stub values from the Budgerigar's searcher to the
+// writer as newline-delimited JSON, sorted by service, method and ID.
+//
+// Unlike Export, the Stub values are written one at a time, which keeps the
+// memory use flat for large stub sets.
+//
+// Parameters:
+// - w: The writer receiving the Stub values.
+//
+// Returns:
+// - error: An error if a Stub value cannot be serialized or written.
+func (b *Budgerigar) ExportTo(w io.Writer) error {
+	return b.searcher.exportTo(w)
+}
+
+// ImportFrom reads newline-delimited JSON Stub values from the reader and
+// inserts each of them into the Budgerigar's searcher as it is read.
+//
+// Unlike Import, loading stops at the first Stub value that cannot be
+// loaded, and the ones read before it are kept.
+//
+// Parameters:
+// - r: The reader providing the Stub values.
+//
+// Returns:
+// - error: An error naming the line of the first Stub value that could not be loaded.
+func (b *Budgerigar) ImportFrom(r io.Reader) error {
+	return b.searcher.importFrom(r)
+}
+
+// ExportByService serializes the Stub values from the Budgerigar's searcher
+// into one JSON array per service, each sorted by method and ID.
+//
+// Every payload can be loaded on its own with Import.
+//
+// Returns:
+// - map[string][]byte: The JSON arrays of Stub values, keyed by service.
+// - error: An error if a Stub value cannot be serialized.
+func (b *Budgerigar) ExportByService() (map[string][]byte, error) {
+	return b.searcher.exportByService()
+}
+
+// Import loads a JSON array of Stub values into the Budgerigar's searcher.
+//
+// All Stub values are validated before any of them is inserted. If any Stub
+// value is invalid, the Budgerigar is left unchanged and the returned error
+// lists every failure with its index.
+//
+// Parameters:
+// - data: The JSON array of Stub values.
+//
+// Returns:
+// - error: An error if the payload cannot be parsed or any Stub value is invalid.
+func (b *Budgerigar) Import(data []byte) error {
+	return b.searcher.importJSON(data)
+}
+
+// ImportResolve loads a JSON array of Stub values into the Budgerigar's
+// searcher like Import, after dropping the Stub values that conflict with a
+// Stub value of higher Priority, i.e. have the same service, method and
+// matchers but a lower Priority.
+//
+// Parameters:
+// - data: The JSON array of Stub values.
+//
+// Returns:
+// - []*Stub: The dropped Stub values.
+// - error: An error if the payload cannot be parsed or any kept Stub value is invalid.
+func (b *Budgerigar) ImportResolve(data []byte) ([]*Stub, error) {
+	return b.searcher.importResolve(data)
+}
+
+// Compact releases the memory retained by deleted Stub values in the
+// Budgerigar's searcher. It is meant to be called during idle periods.
+func (b *Budgerigar) Compact() {
+	b.searcher.compact()
+}
+
 // Clear clears all Stub values from the Budgerigar's searcher.
 func (b *Budgerigar) Clear() {
 	b.searcher.clear()
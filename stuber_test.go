@@ -2,13 +2,17 @@ package stuber_test
 
 import (
 	"bytes"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bavix/features"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 
 	"github.com/gripmock/stuber"
 )
@@ -248,6 +252,28 @@ func TestBudgerigar_SearchEmpty(t *testing.T) {
 	}, r.Found().Output.Data)
 }
 
+func TestBudgerigar_SearchZeroRank(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+
+	s.PutMany(stub)
+
+	// A stub without input requirements matches any query with a zero rank.
+	r, err := s.FindByQuery(stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Data:    map[string]interface{}{"name": "bob"},
+	})
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+}
+
 func TestBudgerigar_SearchWithHeaders_Similar(t *testing.T) {
 	s := stuber.NewBudgerigar(features.New(stuber.MethodTitle))
 
@@ -411,3 +437,759 @@ func TestBudgerigar_Clear(t *testing.T) {
 
 	require.Empty(t, s.All())
 }
+
+func TestBudgerigar_ETag(t *testing.T) {
+	id1, id2 := uuid.New(), uuid.New()
+
+	newStubs := func() []*stuber.Stub {
+		return []*stuber.Stub{
+			{
+				ID:      id1,
+				Service: "Greeter1",
+				Method:  "SayHello1",
+				Input:   stuber.InputData{Equals: map[string]interface{}{"name": "a", "age": 1}},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "a"}},
+			},
+			{
+				ID:      id2,
+				Service: "Greeter2",
+				Method:  "SayHello2",
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "b"}},
+			},
+		}
+	}
+
+	s1 := stuber.NewBudgerigar(features.New())
+	s2 := stuber.NewBudgerigar(features.New())
+
+	require.Equal(t, s1.ETag(), s2.ETag())
+
+	stubs := newStubs()
+	s1.PutMany(stubs...)
+
+	reversed := newStubs()
+	s2.PutMany(reversed[1], reversed[0])
+
+	require.Equal(t, s1.ETag(), s2.ETag())
+
+	before := s1.ETag()
+
+	s1.UpdateMany(&stuber.Stub{
+		ID:      id2,
+		Service: "Greeter2",
+		Method:  "SayHello2",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "c"}},
+	})
+
+	require.NotEqual(t, before, s1.ETag())
+
+	s1.DeleteByID(id2)
+	s2.DeleteByID(id2)
+
+	require.Equal(t, s1.ETag(), s2.ETag())
+
+	// A stub that cannot be encoded to JSON still changes the hash.
+	unencodable := &stuber.Stub{
+		ID:      id2,
+		Service: "Greeter2",
+		Method:  "SayHello2",
+		Output:  stuber.Output{Data: map[string]interface{}{"score": math.NaN()}},
+	}
+
+	before = s1.ETag()
+
+	s1.PutMany(unencodable)
+	require.NotEqual(t, before, s1.ETag())
+
+	added := s1.ETag()
+
+	s1.UpdateMany(unencodable)
+	require.NotEqual(t, added, s1.ETag())
+}
+
+func TestResult_Status(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	code := codes.NotFound
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "ok"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "missing"}},
+			Output:  stuber.Output{Code: &code, Error: "user not found"},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "broken"}},
+			Output:  stuber.Output{Error: "internal"},
+		},
+	)
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: map[string]interface{}{"name": "ok"}})
+	require.NoError(t, err)
+
+	_, _, isErr := r.Status()
+	require.False(t, isErr)
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: map[string]interface{}{"name": "missing"}})
+	require.NoError(t, err)
+
+	c, msg, isErr := r.Status()
+	require.True(t, isErr)
+	require.Equal(t, codes.NotFound, c)
+	require.Equal(t, "user not found", msg)
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: map[string]interface{}{"name": "broken"}})
+	require.NoError(t, err)
+
+	c, msg, isErr = r.Status()
+	require.True(t, isErr)
+	require.Equal(t, codes.Unknown, c)
+	require.Equal(t, "internal", msg)
+}
+
+func TestBudgerigar_FindByOutput(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	id1, id2 := uuid.New(), uuid.New()
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      id1,
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "hello", "legacy": true}},
+		},
+		&stuber.Stub{
+			ID:      id2,
+			Service: "Greeter2",
+			Method:  "SayHello2",
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "hello", "legacy": true}},
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter2",
+			Method:  "SayHello2",
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+		},
+	)
+
+	found := s.FindByOutput(func(stub *stuber.Stub) bool {
+		data, ok := stub.Output.Data.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		_, ok = data["legacy"]
+
+		return ok
+	})
+
+	require.Len(t, found, 2)
+	require.ElementsMatch(t, []uuid.UUID{id1, id2}, []uuid.UUID{found[0].ID, found[1].ID})
+
+	require.Empty(t, s.FindByOutput(func(stub *stuber.Stub) bool {
+		return stub.Output.Error != ""
+	}))
+}
+
+func TestBudgerigar_MaxDepth(t *testing.T) {
+	nested := func(depth int) map[string]interface{} {
+		data := map[string]interface{}{"name": "Bob"}
+		for range depth - 1 {
+			data = map[string]interface{}{"next": data}
+		}
+
+		return data
+	}
+
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(&stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+	})
+
+	_, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: nested(100_000)})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.ErrorIs(t, err, stuber.ErrMaxDepthExceeded)
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: nested(1)})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+
+	limited := stuber.NewBudgerigar(features.New(), stuber.WithMaxDepth(2))
+
+	limited.PutMany(&stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Input:   stuber.InputData{Contains: nested(2)},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+	})
+
+	_, err = limited.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: nested(2)})
+	require.NoError(t, err)
+
+	_, err = limited.FindByQuery(stuber.Query{Service: "Greeter1", Method: "SayHello1", Data: nested(3)})
+	require.ErrorIs(t, err, stuber.ErrMaxDepthExceeded)
+}
+
+func TestBudgerigar_MergeByID(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	id := uuid.New()
+
+	s.PutMany(&stuber.Stub{
+		ID:      id,
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}, Error: "old"},
+	})
+
+	require.ErrorIs(t, s.MergeByID(uuid.New(), &stuber.Stub{}), stuber.ErrStubNotFound)
+
+	require.NoError(t, s.MergeByID(id, &stuber.Stub{
+		Output: stuber.Output{Data: map[string]interface{}{"message": "patched"}},
+	}))
+
+	stub := s.FindByID(id)
+	require.NotNil(t, stub)
+	require.Equal(t, "Greeter1", stub.Service)
+	require.Equal(t, map[string]interface{}{"name": "Bob"}, stub.Input.Equals)
+	require.Equal(t, map[string]interface{}{"message": "patched"}, stub.Output.Data)
+	require.Equal(t, "old", stub.Output.Error)
+
+	// Moving the stub to another method reindexes it.
+	require.NoError(t, s.MergeByID(id, &stuber.Stub{Method: "SayHello2"}))
+
+	all, err := s.FindBy("Greeter1", "SayHello1")
+	require.NoError(t, err)
+	require.Empty(t, all)
+
+	all, err = s.FindBy("Greeter1", "SayHello2")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, id, all[0].ID)
+
+	// A patch is refused like PutMany refuses a stub, leaving the stub intact.
+	refused := []struct {
+		name  string
+		patch *stuber.Stub
+		err   error
+	}{
+		{"cel", &stuber.Stub{CEL: "((("}, stuber.ErrInvalidCEL},
+		{"peer", &stuber.Stub{PeerMatch: "10.0.0.0/99"}, stuber.ErrInvalidPeer},
+		{
+			"normalizer",
+			&stuber.Stub{Input: stuber.InputData{Normalizers: map[string]string{"name": "unknown"}}},
+			stuber.ErrUnknownNormalizer,
+		},
+		{
+			"env",
+			&stuber.Stub{Input: stuber.InputData{Equals: map[string]interface{}{"name": "${env:STUBER_TEST_UNSET}"}}},
+			stuber.ErrUnresolvedEnv,
+		},
+	}
+
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			before := s.FindByID(id)
+
+			require.ErrorIs(t, s.MergeByID(id, tt.patch), tt.err)
+			require.Same(t, before, s.FindByID(id))
+		})
+	}
+}
+
+func TestBudgerigar_MatchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	s := stuber.NewBudgerigar(features.New(), stuber.WithMatchTimeout(50*time.Millisecond))
+
+	s.PutMany(
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "slow"}},
+			Matcher: stuber.MatcherFunc(func(stuber.Query) bool {
+				<-release
+
+				return true
+			}),
+		},
+		&stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter1",
+			Method:  "SayHello1",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"message": "fast"}},
+			Matcher: stuber.MatcherFunc(func(q stuber.Query) bool {
+				return q.Headers["x-team"] == "core"
+			}),
+		},
+	)
+
+	start := time.Now()
+
+	r, err := s.FindByQuery(stuber.Query{
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Headers: map[string]interface{}{"x-team": "core"},
+		Data:    map[string]interface{}{"name": "Bob"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"message": "fast"}, r.Found().Output.Data)
+	require.Less(t, time.Since(start), time.Second)
+
+	r, err = s.FindByQuery(stuber.Query{
+		Service: "Greeter1",
+		Method:  "SayHello1",
+		Data:    map[string]interface{}{"name": "Bob"},
+	})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"message": "fast"}, r.Similar().Output.Data)
+}
+
+func TestBudgerigar_FindByFullName(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	s.PutMany(&stuber.Stub{
+		ID:      uuid.New(),
+		Service: "helloworld.Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+	})
+
+	query := stuber.Query{Data: map[string]interface{}{"name": "Bob"}}
+
+	for _, name := range []string{"helloworld.Greeter/SayHello", "/helloworld.Greeter/SayHello"} {
+		r, err := s.FindByFullName(name, query)
+		require.NoError(t, err)
+		require.NotNil(t, r.Found())
+	}
+
+	for _, name := range []string{"", "/", "helloworld.Greeter", "helloworld.Greeter/", "/SayHello", "a/b/c"} {
+		_, err := s.FindByFullName(name, query)
+		require.ErrorIs(t, err, stuber.ErrInvalidFullName, name)
+	}
+
+	_, err := s.FindByFullName("helloworld.Greeter/SayBye", query)
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+}
+
+func TestBudgerigar_MethodWildcard(t *testing.T) {
+	stubs := func() []*stuber.Stub {
+		return []*stuber.Stub{
+			{
+				ID:      uuid.New(),
+				Service: "Greeter1",
+				Method:  "SayHello1",
+				Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+			},
+			{
+				ID:      uuid.New(),
+				Service: "Greeter1",
+				Method:  "SayBye1",
+				Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Alice"}},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "bye"}},
+			},
+			{
+				ID:      uuid.New(),
+				Service: "Greeter2",
+				Method:  "SayHello1",
+				Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Alice"}},
+				Output:  stuber.Output{Data: map[string]interface{}{"message": "other service"}},
+			},
+		}
+	}
+
+	strict := stuber.NewBudgerigar(features.New())
+	strict.PutMany(stubs()...)
+
+	_, err := strict.FindByQuery(stuber.Query{Service: "Greeter1", Data: map[string]interface{}{"name": "Alice"}})
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+
+	loose := stuber.NewBudgerigar(features.New(), stuber.WithMethodWildcard())
+	loose.PutMany(stubs()...)
+
+	all, err := loose.FindBy("Greeter1", "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	r, err := loose.FindByQuery(stuber.Query{Service: "Greeter1", Data: map[string]interface{}{"name": "Alice"}})
+	require.NoError(t, err)
+	require.NotNil(t, r.Found())
+	require.Equal(t, map[string]interface{}{"message": "bye"}, r.Found().Output.Data)
+
+	_, err = loose.FindByQuery(stuber.Query{Service: "Greeter3", Data: map[string]interface{}{"name": "Alice"}})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+}
+
+func TestBudgerigar_FindByTimeRange(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := &stuber.Stub{Service: "Greeter", Method: "SayHello", CreatedAt: base}
+	second := &stuber.Stub{Service: "Greeter", Method: "SayHello", CreatedAt: base.Add(time.Hour)}
+	third := &stuber.Stub{Service: "Greeter", Method: "SayHello", CreatedAt: base.Add(2 * time.Hour)}
+
+	s.PutMany(third, first, second)
+
+	require.Equal(t, []*stuber.Stub{first, second, third}, s.FindByTimeRange(time.Time{}, time.Time{}))
+	require.Equal(t, []*stuber.Stub{second, third}, s.FindByTimeRange(base.Add(time.Hour), time.Time{}))
+	require.Equal(t, []*stuber.Stub{first, second}, s.FindByTimeRange(time.Time{}, base.Add(time.Hour)))
+	require.Equal(t, []*stuber.Stub{second}, s.FindByTimeRange(base.Add(time.Minute), base.Add(time.Hour)))
+	require.Empty(t, s.FindByTimeRange(base.Add(3*time.Hour), time.Time{}))
+
+	before := time.Now()
+	stamped := &stuber.Stub{Service: "Greeter", Method: "SayHello"}
+	s.PutMany(stamped)
+
+	require.False(t, stamped.CreatedAt.Before(before))
+	require.Equal(t, []*stuber.Stub{stamped}, s.FindByTimeRange(before, time.Time{}))
+
+	created := stamped.CreatedAt
+	s.PutMany(&stuber.Stub{ID: stamped.ID, Service: "Greeter", Method: "SayHello"})
+	require.Equal(t, created, s.FindByID(stamped.ID).CreatedAt)
+}
+
+func TestBudgerigar_Evaluate(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	bob := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello bob"}},
+	}
+	alice := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "alice"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello alice"}},
+	}
+
+	s.PutMany(bob, alice)
+
+	eval, err := s.Evaluate(stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Data:    map[string]interface{}{"name": "bob"},
+	})
+	require.NoError(t, err)
+	require.True(t, eval.Exact)
+	require.Same(t, bob, eval.Found)
+	require.Positive(t, eval.Score)
+	require.Empty(t, s.Used())
+
+	if eval.Similar != nil {
+		require.Same(t, alice, eval.Similar)
+		require.LessOrEqual(t, eval.SimilarScore, eval.Score)
+	}
+
+	eval, err = s.Evaluate(stuber.Query{ID: &alice.ID, Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.True(t, eval.Exact)
+	require.Same(t, alice, eval.Found)
+
+	_, err = s.Evaluate(stuber.Query{Service: "Unknown", Method: "SayHello"})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+	require.Empty(t, s.Used())
+}
+
+func TestBudgerigar_PartitionByUsage(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	used := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello bob"}},
+	}
+	unused := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayGoodbye",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Bye"}},
+	}
+
+	s.PutMany(used, unused)
+
+	_, err := s.FindByQuery(stuber.Query{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Data:    map[string]interface{}{"name": "bob"},
+	})
+	require.NoError(t, err)
+
+	usedStubs, unusedStubs := s.PartitionByUsage()
+	require.Equal(t, []*stuber.Stub{used}, usedStubs)
+	require.Equal(t, []*stuber.Stub{unused}, unusedStubs)
+}
+
+func TestBudgerigar_Methods(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Methods: []string{"SayHi", "SayHey"},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+
+	s.PutMany(stub)
+
+	for _, method := range []string{"SayHello", "SayHi", "SayHey"} {
+		r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: method})
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+	}
+
+	require.Len(t, s.All(), 1)
+	require.Equal(t, 1, s.DeleteByID(stub.ID))
+
+	for _, method := range []string{"SayHello", "SayHi", "SayHey"} {
+		_, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: method})
+		require.Error(t, err)
+	}
+
+	require.ErrorIs(t, (&stuber.Stub{
+		Service: "Greeter",
+		Method:  "SayHello",
+		Methods: []string{""},
+		Output:  stuber.Output{Error: "boom"},
+	}).Validate(), stuber.ErrMethodEmpty)
+}
+
+func TestBudgerigar_EmptyQuery(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	empty := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+	emptyMatchers := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHi",
+		Headers: stuber.InputHeader{Equals: map[string]interface{}{}},
+		Input: stuber.InputData{
+			Equals:   map[string]interface{}{},
+			Contains: map[string]interface{}{},
+			Matches:  map[string]interface{}{},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"message": "Hi"}},
+	}
+	demanding := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHey",
+		Headers: stuber.InputHeader{Contains: map[string]interface{}{"authorization": "token"}},
+		Input:   stuber.InputData{Contains: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hey"}},
+	}
+
+	s.PutMany(empty, emptyMatchers, demanding)
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.Same(t, empty, r.Found())
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHi"})
+	require.NoError(t, err)
+	require.Same(t, emptyMatchers, r.Found())
+
+	r, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHey"})
+	require.NoError(t, err)
+	require.Nil(t, r.Found())
+	require.Same(t, demanding, r.Similar())
+
+	eval, err := s.Evaluate(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.True(t, eval.Exact)
+}
+
+func TestBudgerigar_MethodPrefix(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	batch := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "Batch" + stuber.MethodPrefixToken,
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "batch"}},
+	}
+	exact := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Users",
+		Method:  "BatchDeleteUser",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "exact"}},
+	}
+
+	s.PutMany(batch, exact)
+
+	for _, method := range []string{"BatchGetUser", "BatchCreateUser", "Batch"} {
+		r, err := s.FindByQuery(stuber.Query{Service: "Users", Method: method})
+		require.NoError(t, err)
+		require.Same(t, batch, r.Found(), method)
+	}
+
+	r, err := s.FindByQuery(stuber.Query{Service: "Users", Method: "BatchDeleteUser"})
+	require.NoError(t, err)
+	require.Same(t, exact, r.Found())
+
+	_, err = s.FindByQuery(stuber.Query{Service: "Users", Method: "GetUser"})
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+
+	_, err = s.FindByQuery(stuber.Query{Service: "Accounts", Method: "BatchGetUser"})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+}
+
+func TestBudgerigar_OnMiss(t *testing.T) {
+	var misses []error
+
+	s := stuber.NewBudgerigar(features.New(), stuber.WithOnMiss(func(_ stuber.Query, err error) {
+		misses = append(misses, err)
+	}))
+
+	s.PutMany(&stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "Bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	})
+
+	_, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "Bob"}})
+	require.NoError(t, err)
+	require.Empty(t, misses)
+
+	_, err = s.FindByQuery(stuber.Query{Service: "Unknown", Method: "SayHello"})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+
+	_, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "Unknown"})
+	require.ErrorIs(t, err, stuber.ErrMethodNotFound)
+
+	id := uuid.New()
+
+	_, err = s.FindByQuery(stuber.Query{ID: &id, Service: "Greeter", Method: "SayHello"})
+	require.Error(t, err)
+
+	// A stub that is not activated yet is not even similar.
+	s.PutMany(&stuber.Stub{
+		ID:            uuid.New(),
+		Service:       "Greeter",
+		Method:        "SayHi",
+		Output:        stuber.Output{Data: map[string]interface{}{"message": "Hi"}},
+		ActivateAfter: 1,
+	})
+
+	_, err = s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHi"})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+
+	require.Len(t, misses, 4)
+	require.ErrorIs(t, misses[0], stuber.ErrServiceNotFound)
+	require.ErrorIs(t, misses[1], stuber.ErrMethodNotFound)
+	require.ErrorIs(t, misses[2], stuber.ErrServiceNotFound)
+	require.ErrorIs(t, misses[3], stuber.ErrStubNotFound)
+}
+
+func TestBudgerigar_RecomputeUsed(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	first := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHello", Output: stuber.Output{Error: "boom"}}
+	second := &stuber.Stub{ID: uuid.New(), Service: "Greeter", Method: "SayHi", Output: stuber.Output{Error: "boom"}}
+
+	s.PutMany(first, second)
+
+	_, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.Equal(t, []*stuber.Stub{first}, s.Used())
+
+	// The used set is replaced, the earlier mark of the first stub is dropped.
+	s.RecomputeUsed(func(stub *stuber.Stub) bool {
+		return stub.Method == "SayHi"
+	})
+
+	require.Equal(t, []*stuber.Stub{second}, s.Used())
+	require.Equal(t, []*stuber.Stub{first}, s.Unused())
+
+	s.RecomputeUsed(func(*stuber.Stub) bool { return false })
+	require.Empty(t, s.Used())
+	require.Len(t, s.Unused(), 2)
+}
+
+func TestBudgerigar_QueryTransform(t *testing.T) {
+	var seen []stuber.Query
+
+	s := stuber.NewBudgerigar(features.New(),
+		stuber.WithQueryTransform(func(query stuber.Query) stuber.Query {
+			if name, ok := query.Data["name"].(string); ok {
+				query.Data = map[string]interface{}{"name": strings.ToLower(strings.TrimPrefix(name, "user:"))}
+			}
+
+			return query
+		}),
+		stuber.WithOnMiss(func(query stuber.Query, _ error) {
+			seen = append(seen, query)
+		}),
+	)
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Input:   stuber.InputData{Equals: map[string]interface{}{"name": "bob"}},
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+
+	s.PutMany(stub)
+
+	query := stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "user:BOB"}}
+
+	r, err := s.FindByQuery(query)
+	require.NoError(t, err)
+	require.Same(t, stub, r.Found())
+	require.Equal(t, []*stuber.Stub{stub}, s.Used())
+
+	eval, err := s.Evaluate(query)
+	require.NoError(t, err)
+	require.Same(t, stub, eval.Found)
+
+	// The original query is left untouched.
+	require.Equal(t, "user:BOB", query.Data["name"])
+
+	_, err = s.FindByQuery(stuber.Query{Service: "Unknown", Method: "SayHello", Data: map[string]interface{}{"name": "Alice"}})
+	require.ErrorIs(t, err, stuber.ErrServiceNotFound)
+	require.Len(t, seen, 1)
+	require.Equal(t, "alice", seen[0].Data["name"])
+}
@@ -0,0 +1,107 @@
+package stuber
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is the number of events buffered for each subscriber.
+const subscriberBuffer = 64
+
+// ChangeKind is the kind of change made to the stub set.
+type ChangeKind string
+
+const (
+	// ChangeAdded is emitted for stubs that were inserted.
+	ChangeAdded ChangeKind = "added"
+	// ChangeUpdated is emitted for stubs that replaced or patched a stored stub.
+	ChangeUpdated ChangeKind = "updated"
+	// ChangeDeleted is emitted for stubs that were deleted.
+	ChangeDeleted ChangeKind = "deleted"
+	// ChangeCleared is emitted when all stubs were removed, it carries no IDs.
+	ChangeCleared ChangeKind = "cleared"
+)
+
+// ChangeEvent describes a change made to the stub set.
+type ChangeEvent struct {
+	Kind ChangeKind  `json:"kind"`          // The kind of change.
+	IDs  []uuid.UUID `json:"ids,omitempty"` // The IDs of the affected stubs.
+}
+
+// subscribers delivers change events to the subscribed channels.
+//
+// The zero value has no subscribers and is ready to use.
+type subscribers struct {
+	mu     sync.Mutex
+	nextID int
+	chans  map[int]chan ChangeEvent
+}
+
+// subscribe returns a channel of the changes made to the stub set and a
+// function that cancels the subscription.
+//
+// Events are sent after the change is applied. Each subscriber has a bounded
+// buffer, writers never wait for subscribers: a subscriber whose buffer is
+// full is dropped and its channel is closed, so it can tell that it missed
+// events and subscribe again. The channel is also closed by the cancel
+// function, which may be called more than once. Changes made concurrently
+// may be delivered in a different order than they were applied.
+//
+// Returns:
+// - <-chan ChangeEvent: The channel of change events.
+// - func(): The function that cancels the subscription.
+func (s *searcher) subscribe() (<-chan ChangeEvent, func()) {
+	return s.subscribers.add()
+}
+
+// add registers a new subscriber.
+func (s *subscribers) add() (<-chan ChangeEvent, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chans == nil {
+		s.chans = make(map[int]chan ChangeEvent)
+	}
+
+	id := s.nextID
+	s.nextID++
+
+	ch := make(chan ChangeEvent, subscriberBuffer)
+	s.chans[id] = ch
+
+	return ch, func() { s.remove(id) }
+}
+
+// remove unregisters the subscriber and closes its channel.
+func (s *subscribers) remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ch, ok := s.chans[id]; ok {
+		delete(s.chans, id)
+		close(ch)
+	}
+}
+
+// emit sends the event to every subscriber without blocking.
+//
+// Subscribers that cannot keep up are dropped.
+func (s *subscribers) emit(kind ChangeKind, ids []uuid.UUID) {
+	if kind != ChangeCleared && len(ids) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, ch := range s.chans {
+		// Every subscriber gets its own copy of the IDs.
+		select {
+		case ch <- ChangeEvent{Kind: kind, IDs: append([]uuid.UUID(nil), ids...)}:
+		default:
+			delete(s.chans, id)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Subscribe(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	id := uuid.New()
+	stub := &stuber.Stub{ID: id, Service: "Greeter", Method: "SayHello"}
+
+	s.PutMany(stub)
+	require.Equal(t, stuber.ChangeEvent{Kind: stuber.ChangeAdded, IDs: []uuid.UUID{id}}, <-events)
+
+	s.PutMany(stub)
+	require.Equal(t, stuber.ChangeEvent{Kind: stuber.ChangeUpdated, IDs: []uuid.UUID{id}}, <-events)
+
+	require.Equal(t, 1, s.DeleteByID(id, uuid.New()))
+	require.Equal(t, stuber.ChangeEvent{Kind: stuber.ChangeDeleted, IDs: []uuid.UUID{id}}, <-events)
+
+	s.Clear()
+	require.Equal(t, stuber.ChangeEvent{Kind: stuber.ChangeCleared}, <-events)
+
+	cancel()
+
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestBudgerigar_SubscribeSlow(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	for range 100 {
+		s.PutMany(&stuber.Stub{Service: "Greeter", Method: "SayHello"})
+	}
+
+	n := 0
+	for range events {
+		n++
+	}
+
+	require.Less(t, n, 100)
+}
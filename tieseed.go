@@ -0,0 +1,56 @@
+package stuber
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// WithTieSeed makes searches pick among the matching stubs that tie for the
+// highest rank by a hash of the given query field, e.g. a request ID, so the
+// same value always gets the same stub while different values spread across
+// the tying stubs.
+//
+// The field is a dot-separated path into the query data, or into the query
+// headers if it starts with "headers.". Queries without the field fall back
+// to round-robin, if enabled, or to the first tying stub. The pick depends on
+// the stubs tying, so adding or removing one of them may move a value to
+// another stub.
+func WithTieSeed(field string) Option {
+	return func(s *searcher) {
+		s.tieSeed = field
+	}
+}
+
+// seededTie picks one of the tying stubs by the hash of the query's seed
+// field.
+//
+// Returns:
+// - *Stub: The picked Stub value.
+// - bool: Whether the searcher has a seed field and the query carries it.
+func (s *searcher) seededTie(query Query, ties []*Stub) (*Stub, bool) {
+	if s.tieSeed == "" {
+		return nil, false
+	}
+
+	path := splitPath(s.tieSeed)
+
+	var (
+		seed any
+		ok   bool
+	)
+
+	if path[0] == headersPrefix && len(path) > 1 {
+		seed, ok = lookup(query.Headers, path[1:])
+	} else {
+		seed, ok = lookup(query.Data, path)
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	h := fnv.New64a()
+	_, _ = fmt.Fprint(h, seed)
+
+	return ties[h.Sum64()%uint64(len(ties))], true
+}
@@ -0,0 +1,65 @@
+package stuber_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_TieSeed(t *testing.T) {
+	stubs := make([]*stuber.Stub, 3)
+	for i := range stubs {
+		stubs[i] = &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  "SayHello",
+			Input:   stuber.InputData{Contains: map[string]interface{}{"name": "bob"}},
+			Output:  stuber.Output{Data: map[string]interface{}{"n": i}},
+		}
+	}
+
+	s := stuber.NewBudgerigar(features.New(), stuber.WithTieSeed("headers.x-request-id"))
+	s.PutMany(stubs...)
+
+	find := func(requestID string) *stuber.Stub {
+		r, err := s.FindByQuery(stuber.Query{
+			Service: "Greeter",
+			Method:  "SayHello",
+			Headers: map[string]interface{}{"x-request-id": requestID},
+			Data:    map[string]interface{}{"name": "bob"},
+		})
+		require.NoError(t, err)
+
+		return r.Found()
+	}
+
+	// The same seed always gets the same stub, different seeds spread across the ties.
+	picked := make(map[*stuber.Stub]int)
+
+	for i := range 100 {
+		requestID := "req-" + strconv.Itoa(i)
+		found := find(requestID)
+
+		for range 3 {
+			require.Same(t, found, find(requestID))
+		}
+
+		picked[found]++
+	}
+
+	require.Len(t, picked, 3)
+
+	for _, n := range picked {
+		require.Greater(t, n, 15)
+	}
+
+	// Queries without the seed get the first tying stub.
+	r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello", Data: map[string]interface{}{"name": "bob"}})
+	require.NoError(t, err)
+	require.Same(t, stubs[0], r.Found())
+}
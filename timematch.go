@@ -0,0 +1,85 @@
+package stuber
+
+import (
+	"math"
+	"time"
+)
+
+// TimeMatch matches a time field of the query data.
+//
+// The field holds an RFC 3339 string or a number of seconds since the Unix
+// epoch. A value that cannot be parsed does not match. When both the
+// absolute and the relative window are set, the time must be in both.
+type TimeMatch struct {
+	At        time.Time     `json:"at,omitzero"`         // The expected time.
+	Tolerance time.Duration `json:"tolerance,omitempty"` // The allowed distance from At in either direction.
+	Within    time.Duration `json:"within,omitempty"`    // The window before the current time the value must be in, if set.
+}
+
+// matchTimes checks if the time fields of the query data are within the
+// windows of the stub's input data.
+//
+// Fields are dot-separated paths, a field that is absent does not match.
+func matchTimes(input InputData, data map[string]any) bool {
+	return countTimes(input, data) == len(input.Times)
+}
+
+// rankTimes returns the share of the stub's time fields the query data matches.
+func rankTimes(input InputData, data map[string]any) float64 {
+	if len(input.Times) == 0 {
+		return 0
+	}
+
+	return float64(countTimes(input, data)) / float64(len(input.Times))
+}
+
+// countTimes returns the number of the stub's time fields the query data matches.
+func countTimes(input InputData, data map[string]any) int {
+	if len(input.Times) == 0 {
+		return 0
+	}
+
+	now := time.Now()
+	n := 0
+
+	for path, tm := range input.Times {
+		value, ok := lookup(data, splitPath(path))
+		if !ok {
+			continue
+		}
+
+		t, ok := parseTime(value)
+		if ok && tm.contains(t, now) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// contains checks if the time is within the windows of the match.
+func (tm TimeMatch) contains(t, now time.Time) bool {
+	if !tm.At.IsZero() && (t.Before(tm.At.Add(-tm.Tolerance)) || t.After(tm.At.Add(tm.Tolerance))) {
+		return false
+	}
+
+	return tm.Within <= 0 || !t.Before(now.Add(-tm.Within)) && !t.After(now)
+}
+
+// parseTime converts an RFC 3339 string or a number of Unix seconds to a time.
+func parseTime(value any) (time.Time, bool) {
+	if s, ok := value.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, s)
+
+		return t, err == nil
+	}
+
+	seconds, ok := number(value)
+	if !ok || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return time.Time{}, false
+	}
+
+	whole, frac := math.Modf(seconds)
+
+	return time.Unix(int64(whole), int64(frac*float64(time.Second))), true
+}
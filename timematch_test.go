@@ -0,0 +1,81 @@
+package stuber_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_TimeMatch(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s := stuber.NewBudgerigar(features.New())
+
+	absolute := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Events",
+		Method:  "Publish",
+		Input: stuber.InputData{Times: map[string]stuber.TimeMatch{
+			"event.time": {At: at, Tolerance: time.Minute},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"status": "absolute"}},
+	}
+	relative := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Events",
+		Method:  "Send",
+		Input: stuber.InputData{Times: map[string]stuber.TimeMatch{
+			"sentAt": {Within: 5 * time.Minute},
+		}},
+		Output: stuber.Output{Data: map[string]interface{}{"status": "relative"}},
+	}
+
+	s.PutMany(absolute, relative)
+
+	publish := func(value interface{}) stuber.Query {
+		return stuber.Query{
+			Service: "Events",
+			Method:  "Publish",
+			Data:    map[string]interface{}{"event": map[string]interface{}{"time": value}},
+		}
+	}
+
+	for _, value := range []interface{}{
+		"2024-05-01T12:00:30Z",
+		"2024-05-01T14:01:00+02:00",
+		float64(at.Add(-time.Minute).Unix()),
+	} {
+		r, err := s.FindByQuery(publish(value))
+		require.NoError(t, err)
+		require.Same(t, absolute, r.Found(), value)
+	}
+
+	for _, value := range []interface{}{"2024-05-01T12:01:01Z", "yesterday", true} {
+		r, err := s.FindByQuery(publish(value))
+		require.ErrorIs(t, err, stuber.ErrStubNotFound)
+		require.Nil(t, r)
+	}
+
+	send := func(at time.Time) stuber.Query {
+		return stuber.Query{
+			Service: "Events",
+			Method:  "Send",
+			Data:    map[string]interface{}{"sentAt": at.Format(time.RFC3339Nano)},
+		}
+	}
+
+	r, err := s.FindByQuery(send(time.Now().Add(-time.Minute)))
+	require.NoError(t, err)
+	require.Same(t, relative, r.Found())
+
+	for _, at := range []time.Time{time.Now().Add(-10 * time.Minute), time.Now().Add(time.Hour)} {
+		r, err = s.FindByQuery(send(at))
+		require.ErrorIs(t, err, stuber.ErrStubNotFound)
+		require.Nil(t, r)
+	}
+}
@@ -0,0 +1,77 @@
+package stuber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidTolerance is returned when a stub has a negative float tolerance.
+var ErrInvalidTolerance = errors.New("invalid tolerance")
+
+// toleranceSlack is the relative error allowed on top of a tolerance.
+const toleranceSlack = 1e-9
+
+// withTolerance replaces the numbers of the query data that are within the
+// tolerance of the stub's exact or partial inputs by the expected numbers, so
+// that they compare equal.
+//
+// Fields are dot-separated paths into objects. The tolerance is inclusive and
+// applies only if one of the numbers has a fraction, two whole numbers are
+// still compared exactly, by value, so that e.g. json.Number("3") from a
+// decoded request equals an expected 3. The query is copied rather than
+// modified, and returned as is if the stub has no tolerances.
+func withTolerance(query Query, stub *Stub) Query {
+	for path, tolerance := range stub.Input.Tolerance {
+		segments := splitPath(path)
+
+		actual, ok := lookup(query.Data, segments)
+		if !ok {
+			continue
+		}
+
+		expected, ok := lookup(stub.Input.Equals, segments)
+		if !ok {
+			expected, ok = lookup(stub.Input.Contains, segments)
+		}
+
+		if ok && withinTolerance(expected, actual, tolerance) {
+			query.Data = transformAt(query.Data, segments, func(any) any { return expected })
+		}
+	}
+
+	return query
+}
+
+// withinTolerance checks if both values are numbers that differ by at most
+// the tolerance, or that are equal if both are whole.
+func withinTolerance(expected, actual any, tolerance float64) bool {
+	e, ok := number(expected)
+	if !ok {
+		return false
+	}
+
+	a, ok := number(actual)
+	if !ok {
+		return false
+	}
+
+	if e == math.Trunc(e) && a == math.Trunc(a) {
+		return e == a
+	}
+
+	// Allow for the representation error of the difference, so that a
+	// difference of exactly the tolerance in decimal is within it.
+	return math.Abs(e-a) <= tolerance+toleranceSlack*math.Max(math.Abs(e), math.Abs(a))
+}
+
+// compileTolerance checks that every tolerance is a non-negative number.
+func compileTolerance(tolerances map[string]float64) error {
+	for path, tolerance := range tolerances {
+		if tolerance < 0 || math.IsNaN(tolerance) {
+			return fmt.Errorf("%w %v of %s", ErrInvalidTolerance, tolerance, path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package stuber_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Tolerance(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	payment := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Payments",
+		Method:  "Charge",
+		Input: stuber.InputData{
+			Equals:    map[string]interface{}{"amount": 100.25, "refund": map[string]interface{}{"amount": -20.5}, "items": 3},
+			Tolerance: map[string]float64{"amount": 0.01, "refund.amount": 0.01, "items": 1},
+		},
+		Output: stuber.Output{Data: map[string]interface{}{"ok": true}},
+	}
+
+	s.PutMany(payment)
+
+	tests := []struct {
+		amount any
+		refund any
+		items  any
+		found  bool
+	}{
+		{100.25, -20.5, 3, true},
+		{100.26, -20.49, 3, true},
+		{100.24, -20.51, 3, true},
+		{json.Number("100.259"), json.Number("-20.509"), json.Number("3"), true},
+		{100.2601, -20.5, 3, false},
+		{100.2399, -20.5, 3, false},
+		{100.25, -20.52, 3, false},
+		{100.25, -20.48, 3, false},
+		{"100.25", -20.5, 3, false},
+		// Whole numbers are still compared exactly, by value.
+		{100.25, -20.5, 4, false},
+		{100.25, -20.5, json.Number("4"), false},
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(stuber.Query{
+			Service: "Payments",
+			Method:  "Charge",
+			Data: map[string]interface{}{
+				"amount": test.amount,
+				"refund": map[string]interface{}{"amount": test.refund},
+				"items":  test.items,
+			},
+		})
+		if !test.found {
+			require.NoError(t, err)
+			require.Nil(t, r.Found())
+			require.Same(t, payment, r.Similar())
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Same(t, payment, r.Found())
+	}
+
+	invalid := &stuber.Stub{
+		Service: "Payments",
+		Method:  "Charge",
+		Input:   stuber.InputData{Tolerance: map[string]float64{"amount": -0.01}},
+		Output:  stuber.Output{Error: "boom"},
+	}
+	require.ErrorIs(t, invalid.Validate(), stuber.ErrInvalidTolerance)
+}
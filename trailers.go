@@ -0,0 +1,43 @@
+package stuber
+
+import "strings"
+
+// matchTrailers checks if the query carries every trailer of the stub.
+//
+// Trailer names are compared case-insensitively and values exactly. The
+// query may carry trailers the stub does not declare.
+func matchTrailers(expected, actual map[string]string) bool {
+	return countTrailers(expected, actual) == len(expected)
+}
+
+// rankTrailers returns the share of the stub's trailers the query carries.
+func rankTrailers(expected, actual map[string]string) float64 {
+	if len(expected) == 0 {
+		return 0
+	}
+
+	return float64(countTrailers(expected, actual)) / float64(len(expected))
+}
+
+// countTrailers returns the number of the stub's trailers the query carries.
+func countTrailers(expected, actual map[string]string) int {
+	if len(expected) == 0 {
+		return 0
+	}
+
+	// Index the query's trailers by their lowercased names.
+	lowered := make(map[string]string, len(actual))
+	for name, value := range actual {
+		lowered[strings.ToLower(name)] = value
+	}
+
+	n := 0
+
+	for name, value := range expected {
+		if v, ok := lowered[strings.ToLower(name)]; ok && v == value {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,57 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Trailers(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	stub := &stuber.Stub{
+		ID:       uuid.New(),
+		Service:  "Greeter",
+		Method:   "SayHello",
+		Trailers: map[string]string{"X-Checksum": "abc"},
+		Output:   stuber.Output{Data: map[string]interface{}{"message": "Hello"}},
+	}
+
+	s.PutMany(stub)
+
+	tests := []struct {
+		trailers map[string]string
+		found    bool
+	}{
+		{map[string]string{"x-checksum": "abc"}, true},
+		{map[string]string{"X-CHECKSUM": "abc", "x-extra": "1"}, true},
+		{map[string]string{"x-checksum": "ABC"}, false},
+		{map[string]string{"x-other": "abc"}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		r, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello", Trailers: test.trailers})
+		if !test.found {
+			require.NoError(t, err)
+			require.Nil(t, r.Found())
+			require.Same(t, stub, r.Similar())
+
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Same(t, stub, r.Found())
+	}
+
+	payload, err := s.Export()
+	require.NoError(t, err)
+
+	restored := stuber.NewBudgerigar(features.New())
+	require.NoError(t, restored.Import(payload))
+	require.Equal(t, stub.Trailers, restored.All()[0].Trailers)
+}
@@ -0,0 +1,79 @@
+package stuber
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownType is returned when a stub expects a field type that is not
+// one of string, number, boolean, object, array or null.
+var ErrUnknownType = errors.New("unknown type")
+
+// matchTypeOf checks if the fields of the query data have the JSON types the
+// stub's input data expects.
+//
+// Fields are dot-separated paths. A field that is absent does not match,
+// not even the null type, which requires an explicit null.
+func matchTypeOf(input InputData, data map[string]any) bool {
+	return countTypeOf(input, data) == len(input.TypeOf)
+}
+
+// rankTypeOf returns the share of the stub's typed fields the query data matches.
+func rankTypeOf(input InputData, data map[string]any) float64 {
+	if len(input.TypeOf) == 0 {
+		return 0
+	}
+
+	return float64(countTypeOf(input, data)) / float64(len(input.TypeOf))
+}
+
+// countTypeOf returns the number of the stub's typed fields holding a value
+// of the expected type.
+func countTypeOf(input InputData, data map[string]any) int {
+	n := 0
+
+	for path, expected := range input.TypeOf {
+		value, ok := lookup(data, splitPath(path))
+		if ok && typeOf(value) == expected {
+			n++
+		}
+	}
+
+	return n
+}
+
+// typeOf returns the JSON type name of an unmarshaled value.
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return ""
+	}
+}
+
+// compileTypeOf checks that every expected type is a known JSON type name.
+//
+// It returns the first unknown type, otherwise nil.
+func compileTypeOf(types map[string]string) error {
+	for path, name := range types {
+		switch name {
+		case "string", "number", "boolean", "object", "array", "null":
+		default:
+			return fmt.Errorf("%w %q of %s", ErrUnknownType, name, path)
+		}
+	}
+
+	return nil
+}
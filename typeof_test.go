@@ -0,0 +1,70 @@
+package stuber_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_TypeOf(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	types := []string{"string", "number", "boolean", "object", "array", "null"}
+	stubs := make(map[string]*stuber.Stub, len(types))
+
+	for _, name := range types {
+		stubs[name] = &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Users",
+			Method:  "Get",
+			Input:   stuber.InputData{TypeOf: map[string]string{"user.id": name}},
+			Output:  stuber.Output{Data: map[string]interface{}{"type": name}},
+		}
+
+		s.PutMany(stubs[name])
+	}
+
+	values := map[string]interface{}{
+		"string":  "42",
+		"number":  json.Number("42"),
+		"boolean": true,
+		"object":  map[string]interface{}{"value": 42},
+		"array":   []interface{}{42},
+		"null":    nil,
+	}
+
+	for name, value := range values {
+		r, err := s.FindByQuery(stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{
+			"user": map[string]interface{}{"id": value},
+		}})
+		require.NoError(t, err)
+		require.Same(t, stubs[name], r.Found(), name)
+	}
+
+	// Numbers built in Go match as well.
+	r, err := s.FindByQuery(stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{
+		"user": map[string]interface{}{"id": 42},
+	}})
+	require.NoError(t, err)
+	require.Same(t, stubs["number"], r.Found())
+
+	// An absent field matches no type, not even null.
+	r, err = s.FindByQuery(stuber.Query{Service: "Users", Method: "Get", Data: map[string]interface{}{
+		"user": map[string]interface{}{},
+	}})
+	require.ErrorIs(t, err, stuber.ErrStubNotFound)
+	require.Nil(t, r)
+
+	err = stuber.Stub{
+		Service: "Users",
+		Method:  "Get",
+		Input:   stuber.InputData{TypeOf: map[string]string{"id": "integer"}},
+		Output:  stuber.Output{Data: map[string]interface{}{}},
+	}.Validate()
+	require.ErrorIs(t, err, stuber.ErrUnknownType)
+}
@@ -0,0 +1,172 @@
+package stuber
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/gripmock/deeply"
+)
+
+// unsatisfiable returns the stored stubs whose matchers contradict each
+// other, so that no query can ever match them.
+//
+// The analysis is static and covers the common authoring mistakes:
+//   - a field whose exact and partial values differ,
+//   - a field whose exact or partial string does not match its regular expression,
+//   - a minimum size or item count above the maximum,
+//   - an array field whose exact value lacks a required element,
+//   - a field without any allowed value,
+//   - an empty body required together with input fields.
+//
+// Custom matchers are not analyzed. The stubs are sorted by service, method
+// and ID.
+//
+// Returns:
+// - []*Stub: The Stub values that can never match.
+func (s *searcher) unsatisfiable() []*Stub {
+	stubs := s.all()
+	slices.SortFunc(stubs, compareStubs)
+
+	return slices.DeleteFunc(stubs, func(stub *Stub) bool {
+		return !contradictory(stub)
+	})
+}
+
+// contradictory checks if the matchers of the stub contradict each other.
+func contradictory(stub *Stub) bool {
+	input := stub.Input
+
+	switch {
+	case input.MinBytes > 0 && input.MaxBytes > 0 && input.MinBytes > input.MaxBytes:
+		return true
+	case stub.EmptyBody && (len(input.Equals) > 0 || len(input.Contains) > 0 || len(input.Elements) > 0 ||
+		len(input.Items) > 0 || len(input.Keys) > 0 || len(input.OneOf) > 0 ||
+		len(input.TypeOf) > 0):
+		return true
+	case conflicting(input.Equals, input.Contains) || conflicting(stub.Headers.Equals, stub.Headers.Contains):
+		return true
+	case mismatching(input.Equals, input.Matches) || mismatching(input.Contains, input.Matches):
+		return true
+	case mismatching(stub.Headers.Equals, stub.Headers.Matches) || mismatching(stub.Headers.Contains, stub.Headers.Matches):
+		return true
+	}
+
+	for _, allowed := range input.OneOf {
+		if len(allowed) == 0 {
+			return true
+		}
+	}
+
+	// A stub requiring an empty body cannot require a field to be present.
+	for _, ne := range input.NotEquals {
+		if stub.EmptyBody && !ne.AllowAbsent {
+			return true
+		}
+	}
+
+	for _, bounds := range input.Items {
+		if bounds.MinItems > 0 && bounds.MaxItems > 0 && bounds.MinItems > bounds.MaxItems {
+			return true
+		}
+	}
+
+	return lacksElements(input)
+}
+
+// conflicting checks if a field is present in both maps with values that
+// cannot both hold.
+//
+// Nested maps are compared field by field. Arrays are skipped, since
+// partial arrays do not have to equal the exact ones.
+func conflicting(a, b map[string]any) bool {
+	for key, av := range a {
+		bv, ok := b[key]
+		if !ok {
+			continue
+		}
+
+		am, aIsMap := av.(map[string]any)
+		bm, bIsMap := bv.(map[string]any)
+
+		switch {
+		case aIsMap && bIsMap:
+			if conflicting(am, bm) {
+				return true
+			}
+		case aIsMap != bIsMap:
+			return true
+		case isArray(av) || isArray(bv):
+			continue
+		case !deeply.Equals(av, bv):
+			return true
+		}
+	}
+
+	return false
+}
+
+// mismatching checks if a string field of the values does not match the
+// regular expression of the same field.
+func mismatching(values, patterns map[string]any) bool {
+	for key, pattern := range patterns {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+
+		if nested, ok := pattern.(map[string]any); ok {
+			if vm, ok := value.(map[string]any); ok && mismatching(vm, nested) {
+				return true
+			}
+
+			continue
+		}
+
+		expr, ok := pattern.(string)
+		if !ok {
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		if re, err := regexp.Compile(expr); err == nil && !re.MatchString(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lacksElements checks if the exact value of an array field lacks one of the
+// elements the field must contain.
+func lacksElements(input InputData) bool {
+	for path, elements := range input.Elements {
+		value, ok := lookup(input.Equals, splitPath(path))
+		if !ok {
+			continue
+		}
+
+		items, ok := value.([]any)
+		if !ok {
+			return true
+		}
+
+		for _, element := range elements {
+			if !slices.ContainsFunc(items, func(item any) bool { return deeply.Equals(element, item) }) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isArray checks if the value is an array.
+func isArray(value any) bool {
+	_, ok := value.([]any)
+
+	return ok
+}
@@ -0,0 +1,79 @@
+package stuber_test
+
+import (
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_Unsatisfiable(t *testing.T) {
+	newStub := func(method string, input stuber.InputData) *stuber.Stub {
+		return &stuber.Stub{
+			ID:      uuid.New(),
+			Service: "Greeter",
+			Method:  method,
+			Input:   input,
+			Output:  stuber.Output{Error: "boom"},
+		}
+	}
+
+	tests := map[string]*stuber.Stub{
+		"equals and contains": newStub("A", stuber.InputData{
+			Equals:   map[string]interface{}{"user": map[string]interface{}{"name": "Bob"}},
+			Contains: map[string]interface{}{"user": map[string]interface{}{"name": "Alice"}},
+		}),
+		"equals and matches": newStub("B", stuber.InputData{
+			Equals:  map[string]interface{}{"code": "abc"},
+			Matches: map[string]interface{}{"code": "^[0-9]+$"},
+		}),
+		"size bounds": newStub("C", stuber.InputData{MinBytes: 100, MaxBytes: 10}),
+		"item bounds": newStub("D", stuber.InputData{
+			Items: map[string]stuber.ItemBounds{"tags": {MinItems: 3, MaxItems: 1}},
+		}),
+		"equals and elements": newStub("E", stuber.InputData{
+			Equals:   map[string]interface{}{"tags": []interface{}{"x", "y"}},
+			Elements: map[string][]interface{}{"tags": {"z"}},
+		}),
+	}
+
+	empty := newStub("F", stuber.InputData{Contains: map[string]interface{}{"name": "Bob"}})
+	empty.EmptyBody = true
+	tests["empty body"] = empty
+
+	headers := newStub("G", stuber.InputData{})
+	headers.Headers = stuber.InputHeader{
+		Equals:   map[string]interface{}{"x-tenant": "a"},
+		Contains: map[string]interface{}{"x-tenant": "b"},
+	}
+	tests["headers"] = headers
+
+	for name, stub := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := stuber.NewBudgerigar(features.New())
+			s.PutMany(stub)
+
+			require.Equal(t, []*stuber.Stub{stub}, s.Unsatisfiable())
+		})
+	}
+
+	// Consistent matchers are not reported.
+	s := stuber.NewBudgerigar(features.New())
+	s.PutMany(
+		newStub("A", stuber.InputData{
+			Equals:   map[string]interface{}{"code": "123", "tags": []interface{}{"x", "z"}},
+			Contains: map[string]interface{}{"code": "123"},
+			Matches:  map[string]interface{}{"code": "^[0-9]+$"},
+			Elements: map[string][]interface{}{"tags": {"z"}},
+			MinBytes: 10,
+			MaxBytes: 100,
+			Items:    map[string]stuber.ItemBounds{"tags": {MinItems: 1, MaxItems: 3}},
+		}),
+		newStub("B", stuber.InputData{}),
+	)
+
+	require.Empty(t, s.Unsatisfiable())
+}
@@ -0,0 +1,77 @@
+package stuber
+
+import (
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WithUsedTTL makes the used marks expire the given duration after the last
+// use of their stub, so Used and Unused reflect a rolling window of recent
+// activity instead of everything since the last clear.
+//
+// Expired marks are ignored on read and dropped by Budgerigar.SweepUsed,
+// together with the use counts of their stubs. A non-positive duration keeps
+// the marks until cleared, which is the default.
+func WithUsedTTL(ttl time.Duration) Option {
+	return func(s *searcher) {
+		s.usedTTL = max(ttl, 0)
+	}
+}
+
+// isUsed checks if the stub with the given ID has a used mark that has not
+// expired at the given time. The caller holds the lock.
+func (s *searcher) isUsed(id uuid.UUID, now time.Time) bool {
+	at, ok := s.stubUsed[id]
+
+	return ok && !s.expired(at, now)
+}
+
+// expired checks if a used mark set at the given time has expired.
+func (s *searcher) expired(at, now time.Time) bool {
+	return s.usedTTL > 0 && now.Sub(at) >= s.usedTTL
+}
+
+// markUsed marks the stub with the given ID as used now and counts the use.
+// A stub whose mark has expired starts counting afresh, so the use counts stay
+// in step with the marks. The caller holds the write lock.
+func (s *searcher) markUsed(id uuid.UUID) {
+	now := time.Now()
+
+	if at, ok := s.stubUsed[id]; ok && s.expired(at, now) {
+		delete(s.calls, id)
+	}
+
+	s.stubUsed[id] = now
+	s.calls[id]++
+}
+
+// sweepUsed drops the expired used marks and the use counts of their stubs
+// under the write lock, e.g. periodically to bound the memory of a
+// long-running searcher. Without a used TTL nothing expires.
+//
+// The captured values are kept, they are named rather than tied to a stub
+// and stay until the next capture under their name, Clear or RecomputeUsed.
+//
+// Returns:
+// - int: The number of dropped marks.
+func (s *searcher) sweepUsed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	before := len(s.stubUsed)
+
+	maps.DeleteFunc(s.stubUsed, func(id uuid.UUID, at time.Time) bool {
+		if !s.expired(at, now) {
+			return false
+		}
+
+		delete(s.calls, id)
+
+		return true
+	})
+
+	return before - len(s.stubUsed)
+}
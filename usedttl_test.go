@@ -0,0 +1,59 @@
+package stuber_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_UsedTTL(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New(), stuber.WithUsedTTL(50*time.Millisecond))
+
+	stub := &stuber.Stub{
+		ID:      uuid.New(),
+		Service: "Greeter",
+		Method:  "SayHello",
+		Output:  stuber.Output{Data: map[string]interface{}{"message": "hello"}},
+	}
+
+	s.PutMany(stub)
+
+	_, err := s.FindByQuery(stuber.Query{Service: "Greeter", Method: "SayHello"})
+	require.NoError(t, err)
+	require.Equal(t, []*stuber.Stub{stub}, s.Used())
+	require.Empty(t, s.Unused())
+
+	// The mark expires once the window passes, without any sweep.
+	require.Eventually(t, func() bool {
+		return len(s.Used()) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []*stuber.Stub{stub}, s.Unused())
+
+	used, unused := s.PartitionByUsage()
+	require.Empty(t, used)
+	require.Equal(t, []*stuber.Stub{stub}, unused)
+
+	// The sweep drops the expired mark and the use count with it.
+	require.Equal(t, 1, s.SweepUsed())
+	require.Zero(t, s.SweepUsed())
+
+	calls, err := s.IncrementAndGet(stub.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	require.Equal(t, []*stuber.Stub{stub}, s.Used())
+
+	// Without a TTL the marks never expire.
+	permanent := stuber.NewBudgerigar(features.New())
+	permanent.PutMany(stub)
+
+	_, err = permanent.IncrementAndGet(stub.ID)
+	require.NoError(t, err)
+	require.Zero(t, permanent.SweepUsed())
+	require.Equal(t, []*stuber.Stub{stub}, permanent.Used())
+}
@@ -0,0 +1,42 @@
+package stuber
+
+import (
+	"fmt"
+	"slices"
+)
+
+// validateMatchers compiles the matchers of every stored stub without
+// matching anything, e.g. to fail CI fast on a fixture that would only fail
+// at match time.
+//
+// It covers the regular, CEL and matcher expressions of the input, header,
+// condition and sequence matchers, the key matchers, field types, match modes,
+// tolerances, peer ranges and body checksums, and checks that the referenced
+// normalizers are registered. Custom matchers are already compiled, e.g. by
+// ParseMatcher, and are not checked.
+//
+// Returns:
+// - []error: The compilation errors, each naming its stub, sorted by service,
+// method and stub ID, or nil if every matcher compiles.
+func (s *searcher) validateMatchers() []error {
+	stubs := s.all()
+	slices.SortFunc(stubs, compareStubs)
+
+	var errs []error
+
+	for _, stub := range stubs {
+		for _, err := range stub.matcherErrors() {
+			errs = append(errs, fmt.Errorf("stub %s: %w", stub.ID, err))
+		}
+
+		s.mu.RLock()
+		err := s.checkNormalizers(stub)
+		s.mu.RUnlock()
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,57 @@
+package stuber_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bavix/features"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gripmock/stuber"
+)
+
+func TestBudgerigar_ValidateMatchers(t *testing.T) {
+	s := stuber.NewBudgerigar(features.New())
+
+	newStub := func(input stuber.InputData, headers stuber.InputHeader) *stuber.Stub {
+		return &stuber.Stub{ID: uuid.New(), Service: "Users", Method: "Get", Input: input, Headers: headers, Output: stuber.Output{Error: "boom"}}
+	}
+
+	valid := newStub(stuber.InputData{
+		Matches: map[string]interface{}{"name": "^[a-z]+$"},
+		TypeOf:  map[string]string{"age": "number"},
+	}, stuber.InputHeader{Matches: map[string]interface{}{"x-id": "^[0-9]+$"}})
+	badRegex := newStub(stuber.InputData{Matches: map[string]interface{}{"name": "([a-z"}}, stuber.InputHeader{})
+	badType := newStub(stuber.InputData{TypeOf: map[string]string{"age": "integer"}}, stuber.InputHeader{})
+	badBoth := newStub(
+		stuber.InputData{Any: []stuber.Condition{{Matches: map[string]interface{}{"id": "*"}}}},
+		stuber.InputHeader{Matches: map[string]interface{}{"x-id": "[0-9"}},
+	)
+
+	require.Len(t, s.PutMany(valid, badRegex, badType, badBoth), 4)
+
+	errs := s.ValidateMatchers()
+	require.Len(t, errs, 4)
+
+	counts := make(map[uuid.UUID]int)
+
+	for _, err := range errs {
+		for _, stub := range []*stuber.Stub{valid, badRegex, badType, badBoth} {
+			if strings.Contains(err.Error(), stub.ID.String()) {
+				counts[stub.ID]++
+			}
+		}
+	}
+
+	require.Equal(t, map[uuid.UUID]int{badRegex.ID: 1, badType.ID: 1, badBoth.ID: 2}, counts)
+	require.True(t, slices.ContainsFunc(errs, func(err error) bool { return errors.Is(err, stuber.ErrUnknownType) }))
+
+	// Nothing is matched or marked.
+	require.Empty(t, s.Used())
+
+	s.DeleteByID(badRegex.ID, badType.ID, badBoth.ID)
+	require.Empty(t, s.ValidateMatchers())
+}